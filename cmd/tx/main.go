@@ -6,6 +6,7 @@ import (
 	"log"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/fatih/color"
 	"github.com/manifoldco/promptui"
@@ -89,12 +90,12 @@ func Main() {
 			},
 			{
 				Name:  "merge",
-				Usage: "tx merge [options] [resource_id]",
+				Usage: "tx merge [options] [resource_id...]",
 				Flags: []cli.Flag{
 					&cli.StringFlag{
 						Name: "branch",
-						Usage: "Merge specific branch (omit " +
-							"if it can be determined)",
+						Usage: "Merge specific branch (omit to use the " +
+							"current branch, from TX_BRANCH, CI variables or git)",
 						Value: "",
 					},
 					&cli.StringFlag{
@@ -116,13 +117,59 @@ func Main() {
 						Name:  "silent",
 						Usage: "Whether to reduce verbosity of the output",
 					},
+					&cli.StringFlag{
+						Name:    "resources",
+						Aliases: []string{"r"},
+						Usage: "Resource ids to merge, comma separated; " +
+							"'*' can be used as a wildcard",
+					},
+					&cli.IntFlag{
+						Name:    "workers",
+						Usage:   "How many parallel workers to use (max 20)",
+						Aliases: []string{"w"},
+						Value:   5,
+					},
+					&cli.DurationFlag{
+						Name: "timeout",
+						Usage: "How long to wait for each merge to complete " +
+							"(0 to wait forever)",
+						Value: 10 * time.Minute,
+					},
+					&cli.BoolFlag{
+						Name: "preview",
+						Usage: "List the strings that differ between the " +
+							"branch and its base, without merging",
+					},
+					&cli.BoolFlag{
+						Name:    "interactive",
+						Aliases: []string{"i"},
+						Usage:   "Choose how to resolve each differing string",
+					},
+					&cli.StringFlag{
+						Name: "decisions",
+						Usage: "Resolve differing strings as saved in " +
+							"`FILE` with '--save-decisions'",
+					},
+					&cli.StringFlag{
+						Name: "save-decisions",
+						Usage: "Save the interactive resolutions to `FILE` " +
+							"instead of merging",
+					},
 				},
 				Action: func(c *cli.Context) error {
-					if c.Args().Len() != 1 {
-						return cli.Exit(errorColor("Please provide one resource"), 1)
+					resourceIds := c.Args().Slice()
+					if c.String("resources") != "" {
+						resourceIds = append(
+							resourceIds,
+							strings.Split(c.String("resources"), ",")...,
+						)
+					}
+
+					workers := c.Int("workers")
+					if workers > 20 {
+						workers = 20
 					}
 
-					resourceId := c.Args().First()
 					cfg, err := config.LoadFromPaths(
 						c.String("root-config"),
 						c.String("config"),
@@ -170,15 +217,24 @@ func Main() {
 					}
 
 					args := txlib.MergeCommandArguments{
-						ResourceId:         resourceId,
+						ResourceIds:        resourceIds,
 						Branch:             c.String("branch"),
 						ConflictResolution: c.String("conflict-resolution"),
 						Force:              c.Bool("force"),
 						Skip:               c.Bool("skip"),
 						Silent:             c.Bool("silent"),
+						Workers:            workers,
+						Timeout:            c.Duration("timeout"),
+						Preview:            c.Bool("preview"),
+						Interactive:        c.Bool("interactive"),
+						DecisionsFile:      c.String("decisions"),
+						SaveDecisionsFile:  c.String("save-decisions"),
 					}
 					err = txlib.MergeCommand(&cfg, api, args)
 					if err != nil {
+						if err == promptui.ErrInterrupt {
+							return cli.Exit("", 1)
+						}
 						return cli.Exit(err, 1)
 					}
 					return nil
@@ -211,6 +267,16 @@ func Main() {
 						Name:  "xliff",
 						Usage: "Whether to push XLIFF files",
 					},
+					&cli.BoolFlag{
+						Name:  "json",
+						Usage: "Whether to push JSON files",
+					},
+					&cli.StringFlag{
+						Name: "file-type",
+						Usage: "The format of the translation files to push. " +
+							"This can be one of the following:\n    " +
+							"'default', 'xliff', 'json'",
+					},
 					&cli.BoolFlag{
 						Name: "use-git-timestamps",
 						Usage: "Compare local files to their Transifex " +
@@ -239,14 +305,16 @@ func Main() {
 					&cli.StringFlag{
 						Name: "branch",
 						Usage: "Push to specific branch (use empty argument " +
-							"'' to use the current branch, if it can be " +
-							"determined)",
+							"'' to use the current branch, from TX_BRANCH, " +
+							"CI variables or git)",
 						Value: "-1",
 					},
 					&cli.StringFlag{
 						Name: "base",
 						Usage: "Push current branch with a specific base branch. " +
-							"If omitted the main resource will be used as base",
+							"If omitted the main resource will be used as " +
+							"base; 'auto' picks the nearest git branch this " +
+							"one was branched off that has been pushed",
 						Value: "-1",
 					},
 					&cli.IntFlag{
@@ -269,6 +337,30 @@ func Main() {
 						Usage: "Whether to not discard translations if a source string with a " +
 							"pre-existing key changes",
 					},
+					&cli.StringFlag{
+						Name: "since",
+						Usage: "Only push files that changed since the given git " +
+							"ref, eg 'origin/main'",
+					},
+					&cli.BoolFlag{
+						Name:  "changed",
+						Usage: "Only push files with uncommitted git changes",
+					},
+					&cli.StringFlag{
+						Name: "source-file",
+						Usage: "Push this file instead of the configured source " +
+							"file ('-' to read from the standard input)",
+					},
+					&cli.StringFlag{
+						Name: "translation-file",
+						Usage: "Push this file as the translation of the language " +
+							"given with '-l' ('-' to read from the standard input)",
+					},
+					&cli.BoolFlag{
+						Name: "validate-languages",
+						Usage: "Fail if the file filter matches files whose " +
+							"language codes are not known to Transifex",
+					},
 				},
 				Action: func(c *cli.Context) error {
 					cfg, err := config.LoadFromPaths(
@@ -341,7 +433,6 @@ func Main() {
 						Translation:          c.Bool("translation"),
 						Force:                c.Bool("force"),
 						Skip:                 c.Bool("skip"),
-						Xliff:                c.Bool("xliff"),
 						Languages:            languages,
 						ResourceIds:          resourceIds,
 						UseGitTimestamps:     c.Bool("use-git-timestamps"),
@@ -352,6 +443,18 @@ func Main() {
 						Silent:               c.Bool("silent"),
 						ReplaceEditedStrings: c.Bool("replace-edited-strings"),
 						KeepTranslations:     c.Bool("keep-translations"),
+						Since:                c.String("since"),
+						Changed:              c.Bool("changed"),
+						SourceFile:           c.String("source-file"),
+						TranslationFile:      c.String("translation-file"),
+						ValidateLanguages:    c.Bool("validate-languages"),
+					}
+
+					if args.Since != "" && args.Changed {
+						return cli.Exit(errorColor(
+							"You cannot use both flags '%s' and '%s'.",
+							"since", "changed",
+						), 1)
 					}
 
 					if args.All && len(args.Languages) > 0 {
@@ -361,6 +464,31 @@ func Main() {
 						), 1)
 					}
 
+					if args.SourceFile != "" && args.Translation && !args.Source {
+						return cli.Exit(errorColor(
+							"It doesn't make sense to use the '--source-file' "+
+								"flag without pushing the source file",
+						), 1)
+					}
+
+					if args.TranslationFile != "" &&
+						(!args.Translation || len(args.Languages) != 1) {
+						return cli.Exit(errorColor(
+							"The '--translation-file' flag must be used with "+
+								"the '--translation' flag and exactly one "+
+								"language in the '--languages' flag",
+						), 1)
+					}
+
+					if (args.SourceFile != "" || args.TranslationFile != "") &&
+						(args.Since != "" || args.Changed) {
+						return cli.Exit(errorColor(
+							"It doesn't make sense to use the '--since' or "+
+								"'--changed' flag with the '--source-file' or "+
+								"'--translation-file' flag",
+						), 1)
+					}
+
 					if !args.Translation &&
 						(args.All || len(args.Languages) > 0) {
 						return cli.Exit(errorColor(
@@ -377,10 +505,42 @@ func Main() {
 						), 1)
 					}
 
-					if args.Xliff && !args.Translation {
+					var fileTypeFlags []string
+					for _, name := range []string{"xliff", "json", "file-type"} {
+						if c.IsSet(name) {
+							fileTypeFlags = append(fileTypeFlags, name)
+						}
+					}
+					if len(fileTypeFlags) > 1 {
+						return cli.Exit(errorColor(
+							"You cannot use both flags '%s' and '%s'.",
+							fileTypeFlags[0], fileTypeFlags[1],
+						), 1)
+					} else if c.Bool("xliff") {
+						args.FileType = "xliff"
+					} else if c.Bool("json") {
+						args.FileType = "json"
+					} else if c.String("file-type") != "" {
+						args.FileType = c.String("file-type")
+					} else {
+						args.FileType = "default"
+					}
+
+					if args.FileType != "default" &&
+						args.FileType != "xliff" &&
+						args.FileType != "json" {
+						return cli.Exit(errorColor(
+							"Invalid file type '%s', it can be one of the "+
+								"following: 'default', 'xliff', 'json'",
+							args.FileType,
+						), 1)
+					}
+
+					if args.FileType != "default" && !args.Translation {
 						return cli.Exit(errorColor(
-							"--xliff only makes sense when used with "+
+							"--%s only makes sense when used with "+
 								"`-t/--translation`",
+							fileTypeFlags[0],
 						), 1)
 					}
 
@@ -468,8 +628,8 @@ func Main() {
 					&cli.StringFlag{
 						Name: "branch",
 						Usage: "Push to specific branch (use empty argument " +
-							"'' to use the current branch, if it can be " +
-							"determined)",
+							"'' to use the current branch, from TX_BRANCH, " +
+							"CI variables or git)",
 						Value: "-1",
 					},
 					&cli.BoolFlag{
@@ -489,6 +649,11 @@ func Main() {
 							"a translation mode in order to download it.",
 						Value: -1,
 					},
+					&cli.StringFlag{
+						Name: "minimum-perc-unit",
+						Usage: "Whether the minimum percentage is computed " +
+							"on 'strings' or 'words'",
+					},
 					&cli.IntFlag{
 						Name:    "workers",
 						Usage:   "How many parallel workers to use (max 20)",
@@ -504,6 +669,27 @@ func Main() {
 						Usage: "Generate mock string translations",
 						Value: false,
 					},
+					&cli.BoolFlag{
+						Name: "atomic",
+						Usage: "Only update local files if all downloads " +
+							"succeed, otherwise leave them untouched",
+					},
+					&cli.BoolFlag{
+						Name: "diff",
+						Usage: "Show the changes a pull would make to the local " +
+							"files without changing them; exits with 1 if " +
+							"there are changes",
+					},
+					&cli.BoolFlag{
+						Name:  "stat",
+						Usage: "Used with --diff to only show a summary per file",
+					},
+					&cli.StringFlag{
+						Name: "archive",
+						Usage: "Save the pulled files, along with a manifest, " +
+							"into a .zip, .tar or .tar.gz archive instead of " +
+							"the working tree",
+					},
 				},
 				Action: func(c *cli.Context) error {
 					cfg, err := config.LoadFromPaths(c.String("root-config"),
@@ -563,6 +749,53 @@ func Main() {
 						Workers:           workers,
 						Silent:            c.Bool("silent"),
 						Pseudo:            c.Bool("pseudo"),
+						Atomic:            c.Bool("atomic"),
+						Diff:              c.Bool("diff") || c.Bool("stat"),
+						DiffStat:          c.Bool("stat"),
+						Archive:           c.String("archive"),
+					}
+
+					// Without an explicit '--mode', the mode set in the
+					// configuration for each resource and language is used
+					if !c.IsSet("mode") {
+						arguments.Mode = ""
+					}
+
+					arguments.MinimumPercentageUnit = c.String("minimum-perc-unit")
+					if arguments.MinimumPercentageUnit != "" &&
+						arguments.MinimumPercentageUnit != "strings" &&
+						arguments.MinimumPercentageUnit != "words" {
+						return cli.Exit(errorColor(
+							"'--minimum-perc-unit' needs to be 'strings' or "+
+								"'words'",
+						), 1)
+					}
+
+					if arguments.Archive != "" {
+						for _, flag := range []string{
+							"diff", "stat", "atomic", "disable-overwrite",
+						} {
+							if c.Bool(flag) {
+								return cli.Exit(errorColor(
+									"You cannot use both flags '%s' and '%s'.",
+									"archive", flag,
+								), 1)
+							}
+						}
+					}
+
+					if arguments.Diff && arguments.Atomic {
+						return cli.Exit(errorColor(
+							"You cannot use both flags '%s' and '%s'.",
+							"diff", "atomic",
+						), 1)
+					}
+
+					if arguments.Atomic && arguments.Skip {
+						return cli.Exit(errorColor(
+							"You cannot use both flags '%s' and '%s'.",
+							"atomic", "skip",
+						), 1)
 					}
 
 					if c.Bool("xliff") && c.Bool("json") {
@@ -616,7 +849,13 @@ func Main() {
 					}
 
 					err = txlib.PullCommand(&cfg, &api, &arguments)
-					if err != nil {
+					if errors.Is(err, txlib.ErrPullDiffChanges) {
+						return cli.Exit("", 1)
+					} else if err != nil && arguments.Diff {
+						// Keep 1 for "changes present" so that CI can tell
+						// the two apart
+						return cli.Exit(err, 2)
+					} else if err != nil {
 						return cli.Exit(err, 1)
 					}
 					return nil
@@ -649,7 +888,6 @@ func Main() {
 						"project",
 						"resource",
 						"file-filter",
-						"type",
 					}
 					var missingFlags []string
 					for _, value := range requiredFlagList {
@@ -669,27 +907,37 @@ func Main() {
 						SourceFile:       sourceFile,
 						ResourceName:     c.String("resource-name"),
 					}
-					if missingFlagsCount == 0 {
-						return txlib.AddCommand(
-							&cfg,
-							&args,
-						)
-					}
-
-					if missingFlagsCount == len(requiredFlagList) {
+					// The API is only needed to detect the file format or
+					// for the interactive mode, and the credentials aren't
+					// asked for if the command is going to fail anyway
+					interactive := missingFlagsCount == len(requiredFlagList) &&
+						args.RType == ""
+					var api jsonapi.Connection
+					if args.RType == "" &&
+						(missingFlagsCount == 0 || interactive) {
 						hostname, token, err := txlib.GetHostAndToken(
 							&cfg, c.String("hostname"), c.String("token"),
 						)
 						if err != nil {
 							return cli.Exit(err, 1)
 						}
-						api := jsonapi.Connection{
+						api = jsonapi.Connection{
 							Host:  hostname,
 							Token: token,
 							Headers: map[string]string{
 								"Integration": "txclient",
 							},
 						}
+					}
+					if missingFlagsCount == 0 {
+						return txlib.AddCommand(
+							&cfg,
+							&api,
+							&args,
+						)
+					}
+
+					if interactive {
 						err = txlib.AddCommandInteractive(&cfg, api)
 						if err != nil {
 							if err == promptui.ErrInterrupt {
@@ -701,10 +949,10 @@ func Main() {
 							}
 
 						}
+						return nil
 					}
 
-					if missingFlagsCount >= 1 &&
-						missingFlagsCount < len(requiredFlagList) {
+					if missingFlagsCount >= 1 {
 						err := cli.ShowCommandHelp(c, "add")
 						if err != nil {
 							return cli.Exit(err, 1)
@@ -739,8 +987,9 @@ func Main() {
 							"the translation files",
 					},
 					&cli.StringFlag{
-						Name:  "type",
-						Usage: "The file format type of your resource",
+						Name: "type",
+						Usage: "The file format type of your resource; " +
+							"detected from the source file if not set",
 					},
 					&cli.StringFlag{
 						Name: "resource-name",
@@ -767,6 +1016,11 @@ func Main() {
 									"a translation mode in order to download it.",
 								Value: -1,
 							},
+							&cli.StringFlag{
+								Name: "preset",
+								Usage: "Use the file layout and language codes of a " +
+									"platform: android, ios or web",
+							},
 						},
 						Action: func(c *cli.Context) error {
 							cfg, err := config.LoadFromPaths(
@@ -822,12 +1076,31 @@ func Main() {
 							}
 
 							fileFilter := c.String("file-filter")
+							var preset *txlib.AddRemotePreset
+							if c.String("preset") != "" {
+								found, exists := txlib.AddRemotePresets[c.String("preset")]
+								if !exists {
+									return cli.Exit(
+										errorColor(
+											"Unknown preset '%s', use one of: "+
+												"android, ios, web",
+											c.String("preset"),
+										),
+										1,
+									)
+								}
+								preset = &found
+								if !c.IsSet("file-filter") {
+									fileFilter = preset.FileFilter
+								}
+							}
 							if !strings.Contains(fileFilter, "<resource_slug>") ||
-								!strings.Contains(fileFilter, "<lang>") {
+								!txlib.FileFilterHasLanguage(fileFilter) {
 								return cli.Exit(
 									errorColor(
 										"File filter should contain at least the "+
-											"<resource_slug> and <lang> parameters",
+											"<resource_slug> and <lang> (or another "+
+											"language placeholder) parameters",
 									),
 									1,
 								)
@@ -840,6 +1113,7 @@ func Main() {
 									projectUrl,
 									fileFilter,
 									c.Int("minimum-perc"),
+									preset,
 								)
 								if err != nil {
 									return cli.Exit(errorColor(err.Error()), 1)
@@ -857,9 +1131,48 @@ func Main() {
 			},
 			{
 				Name:  "init",
-				Usage: "tx init",
+				Usage: "tx init [--org <org> --project <project>]",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name: "org",
+						Usage: "Organization slug; with '--project', add the " +
+							"localization files found in the repository " +
+							"as resources of the project",
+					},
+					&cli.StringFlag{
+						Name:  "project",
+						Usage: "Project slug, see '--org'",
+					},
+					&cli.StringFlag{
+						Name: "source-lang",
+						Usage: "Language code of the source files, where " +
+							"the layout doesn't tell",
+						Value: "en",
+					},
+					&cli.BoolFlag{
+						Name:    "yes",
+						Aliases: []string{"y"},
+						Usage:   "Write the config without asking for confirmation",
+					},
+					&cli.BoolFlag{
+						Name: "force",
+						Usage: "Replace an existing config with the resources " +
+							"found in the repository",
+					},
+				},
 				Action: func(c *cli.Context) error {
-					err := txlib.InitCommand()
+					var err error
+					if c.String("org") != "" || c.String("project") != "" {
+						err = txlib.InitProjectCommand(&txlib.InitCommandArguments{
+							OrganizationSlug: c.String("org"),
+							ProjectSlug:      c.String("project"),
+							SourceLanguage:   c.String("source-lang"),
+							Yes:              c.Bool("yes"),
+							Force:            c.Bool("force"),
+						})
+					} else {
+						err = txlib.InitCommand()
+					}
 					if err != nil {
 						return cli.Exit(errorColor(fmt.Sprint(err)), 1)
 					}
@@ -889,10 +1202,24 @@ func Main() {
 					&cli.StringFlag{
 						Name: "branch",
 						Usage: "Delete specific branch (use empty argument " +
-							"'' to use the current branch, if it can be " +
-							"determined)",
+							"'' to use the current branch, from TX_BRANCH, " +
+							"CI variables or git)",
 						Value: "-1",
 					},
+					&cli.BoolFlag{
+						Name:  "dry-run",
+						Usage: "Only list the resources that would be deleted",
+					},
+					&cli.BoolFlag{
+						Name:    "yes",
+						Aliases: []string{"y"},
+						Usage:   "Delete without asking for confirmation",
+					},
+					&cli.BoolFlag{
+						Name: "orphans",
+						Usage: "Delete the resources of the configured " +
+							"projects that have no section in the config",
+					},
 				},
 				Action: func(c *cli.Context) error {
 					cfg, err := config.LoadFromPaths(c.String("root-config"),
@@ -938,6 +1265,9 @@ func Main() {
 						Force:       c.Bool("force"),
 						Skip:        c.Bool("skip"),
 						Branch:      c.String("branch"),
+						DryRun:      c.Bool("dry-run"),
+						Yes:         c.Bool("yes"),
+						Orphans:     c.Bool("orphans"),
 					}
 					// Proceed with deletion
 					err = txlib.DeleteCommand(&cfg, api, &arguments)
@@ -947,6 +1277,98 @@ func Main() {
 					return nil
 				},
 			},
+			{
+				Name: "pseudo",
+				Usage: "Generate pseudo-localized files from the local " +
+					"source files, without contacting Transifex",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:    "languages",
+						Aliases: []string{"l"},
+						Usage: "Languages to create '<lang>_pseudo' files for; " +
+							"defaults to the languages of the local " +
+							"translation files",
+					},
+					&cli.StringFlag{
+						Name:    "resources",
+						Aliases: []string{"r"},
+						Usage: "Resource ids to pseudo-localize that are " +
+							"included in your config file",
+					},
+					&cli.StringFlag{
+						Name: "strategy",
+						Usage: "Comma-separated pseudo-localization " +
+							"strategies: " +
+							strings.Join(txlib.PseudoStrategies, ", "),
+						Value: "accents,expand,brackets",
+					},
+					&cli.IntFlag{
+						Name: "expansion",
+						Usage: "Percentage to make strings longer by, with " +
+							"the 'expand' strategy",
+						Value: 30,
+					},
+					&cli.BoolFlag{
+						Name:  "silent",
+						Usage: "Whether to reduce verbosity of the output",
+					},
+				},
+				Action: func(c *cli.Context) error {
+					cfg, err := config.LoadFromPaths(c.String("root-config"),
+						c.String("config"))
+					if err != nil {
+						return err
+					}
+
+					resourceIds := c.Args().Slice()
+					if c.String("resources") != "" {
+						extraResourceIds := strings.Split(
+							c.String("resources"),
+							",",
+						)
+						resourceIds = append(resourceIds, extraResourceIds...)
+					}
+
+					var languages []string
+					if c.String("languages") != "" {
+						for _, language := range strings.Split(
+							c.String("languages"), ",",
+						) {
+							languages = append(
+								languages, strings.TrimSpace(language),
+							)
+						}
+					}
+
+					var strategies []string
+					for _, strategy := range strings.Split(
+						c.String("strategy"), ",",
+					) {
+						strategy = strings.TrimSpace(strategy)
+						if strategy != "" {
+							strategies = append(strategies, strategy)
+						}
+					}
+
+					if c.Int("expansion") < 0 {
+						return cli.Exit(errorColor(
+							"'--expansion' cannot be negative",
+						), 1)
+					}
+
+					err = txlib.PseudoCommand(&cfg, &txlib.PseudoCommandArguments{
+						ResourceIds: resourceIds,
+						Languages:   languages,
+						Strategies:  strategies,
+						Expansion:   c.Int("expansion"),
+						Silent:      c.Bool("silent"),
+					})
+					if err != nil {
+						return cli.Exit(errorColor("%s", err), 1)
+					}
+					return nil
+				},
+			},
 			{
 				Name:  "update",
 				Usage: "Update the `tx` application if there is a newer version",
@@ -997,6 +1419,16 @@ func Main() {
 						Usage: "Resource ids to get status for that are " +
 							"included in your config file",
 					},
+					&cli.IntFlag{
+						Name:    "workers",
+						Usage:   "How many parallel workers to use (max 20)",
+						Aliases: []string{"w"},
+						Value:   5,
+					},
+					&cli.BoolFlag{
+						Name:  "json",
+						Usage: "Print the status as JSON",
+					},
 				},
 				Action: func(c *cli.Context) error {
 					cfg, err := config.LoadFromPaths(c.String("root-config"),
@@ -1036,9 +1468,16 @@ func Main() {
 						resourceIds = append(resourceIds, extraResourceIds...)
 					}
 
+					workers := c.Int("workers")
+					if workers > 20 {
+						workers = 20
+					}
+
 					// Construct arguments
 					arguments := txlib.StatusCommandArguments{
 						ResourceIds: resourceIds,
+						Workers:     workers,
+						Json:        c.Bool("json"),
 					}
 					// Proceed with deletion
 					err = txlib.StatusCommand(&cfg, api, &arguments)
@@ -1048,6 +1487,200 @@ func Main() {
 					return nil
 				},
 			},
+			{
+				Name: "check",
+				Usage: "tx check [--min PERC] [--mode MODE] [-l LANGS] " +
+					"[resource_id...]",
+				Flags: []cli.Flag{
+					&cli.IntFlag{
+						Name: "min",
+						Usage: "The completion percentage every checked " +
+							"language needs to reach. Overrides the " +
+							"'required_perc' settings of the config file",
+						Value: -1,
+					},
+					&cli.StringFlag{
+						Name:    "mode",
+						Aliases: []string{"m"},
+						Usage: "What to measure completion on: 'translated', " +
+							"'reviewed' or 'proofread'. Defaults to each " +
+							"resource's pull mode",
+					},
+					&cli.StringFlag{
+						Name:    "languages",
+						Aliases: []string{"l"},
+						Usage: "Check specific languages, comma separated " +
+							"language codes",
+					},
+					&cli.StringFlag{
+						Name:    "resources",
+						Aliases: []string{"r"},
+						Usage: "Resource ids to check that are included in " +
+							"your config file",
+					},
+					&cli.StringFlag{
+						Name: "minimum-perc-unit",
+						Usage: "Whether the percentage is computed on " +
+							"'strings' or 'words'",
+					},
+				},
+				Action: func(c *cli.Context) error {
+					// Exit with 1 only when languages are below the
+					// required completion and with 2 for any other error, so
+					// that CI can tell the two apart
+					cfg, err := config.LoadFromPaths(c.String("root-config"),
+						c.String("config"))
+					if err != nil {
+						return cli.Exit(err, 2)
+					}
+
+					mode := c.String("mode")
+					if mode != "" && mode != "translated" &&
+						mode != "reviewed" && mode != "proofread" {
+						return cli.Exit(errorColor(
+							"'--mode' needs to be 'translated', 'reviewed' "+
+								"or 'proofread', got '%s'",
+							mode,
+						), 2)
+					}
+					minimumPerc := c.Int("min")
+					if minimumPerc != -1 &&
+						(minimumPerc < 1 || minimumPerc > 100) {
+						return cli.Exit(errorColor(
+							"'--min' needs to be between 1 and 100",
+						), 2)
+					}
+					unit := c.String("minimum-perc-unit")
+					if unit != "" && unit != "strings" && unit != "words" {
+						return cli.Exit(errorColor(
+							"'--minimum-perc-unit' needs to be 'strings' "+
+								"or 'words', got '%s'",
+							unit,
+						), 2)
+					}
+
+					hostname, token, err := txlib.GetHostAndToken(
+						&cfg, c.String("hostname"), c.String("token"),
+					)
+					if err != nil {
+						return cli.Exit(err, 2)
+					}
+
+					client, err := txlib.GetClient(c.String("cacert"))
+					if err != nil {
+						return cli.Exit(err, 2)
+					}
+
+					api := jsonapi.Connection{
+						Host:   hostname,
+						Token:  token,
+						Client: client,
+						Headers: map[string]string{
+							"Integration": "txclient",
+						},
+					}
+
+					resourceIds := c.Args().Slice()
+					if c.String("resources") != "" {
+						resourceIds = append(
+							resourceIds,
+							strings.Split(c.String("resources"), ",")...,
+						)
+					}
+					var languages []string
+					if c.String("languages") != "" {
+						for _, language := range strings.Split(
+							c.String("languages"), ",",
+						) {
+							languages = append(
+								languages, strings.TrimSpace(language),
+							)
+						}
+					}
+
+					arguments := txlib.CheckCommandArguments{
+						ResourceIds:           resourceIds,
+						Languages:             languages,
+						Mode:                  mode,
+						MinimumPercentage:     minimumPerc,
+						MinimumPercentageUnit: unit,
+					}
+					err = txlib.CheckCommand(&cfg, &api, &arguments)
+					var checkErr *txlib.CheckFailedError
+					if errors.As(err, &checkErr) {
+						return cli.Exit(errorColor("%s", err), 1)
+					} else if err != nil {
+						return cli.Exit(errorColor("%s", err), 2)
+					}
+					return nil
+				},
+			},
+			{
+				Name:  "branch",
+				Usage: "Manage the resources created by pushing with --branch",
+				Subcommands: []*cli.Command{
+					{
+						Name:  "list",
+						Usage: "tx branch list [resource_id...]",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name: "remote",
+								Usage: "Git remote whose branches also count " +
+									"as existing",
+							},
+						},
+						Action: func(c *cli.Context) error {
+							cfg, api, arguments, err := getBranchCommandSetup(c)
+							if err != nil {
+								return err
+							}
+							err = txlib.BranchListCommand(&cfg, &api, &arguments)
+							if err != nil {
+								return cli.Exit(errorColor("%s", err), 1)
+							}
+							return nil
+						},
+					},
+					{
+						Name:  "prune",
+						Usage: "tx branch prune [options] [resource_id...]",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name: "remote",
+								Usage: "Git remote whose branches also count " +
+									"as existing",
+							},
+							&cli.BoolFlag{
+								Name: "dry-run",
+								Usage: "Show which branch resources would be " +
+									"deleted without deleting them",
+							},
+							&cli.BoolFlag{
+								Name:    "force",
+								Aliases: []string{"f"},
+								Usage: "Whether to continue if there are " +
+									"translations in the resources",
+							},
+							&cli.BoolFlag{
+								Name:    "skip",
+								Aliases: []string{"s"},
+								Usage:   "Whether to skip on errors",
+							},
+						},
+						Action: func(c *cli.Context) error {
+							cfg, api, arguments, err := getBranchCommandSetup(c)
+							if err != nil {
+								return err
+							}
+							err = txlib.BranchPruneCommand(&cfg, &api, &arguments)
+							if err != nil {
+								return cli.Exit(errorColor("%s", err), 1)
+							}
+							return nil
+						},
+					},
+				},
+			},
 		},
 		Flags: flags,
 	}
@@ -1057,3 +1690,46 @@ func Main() {
 		log.Fatal(err)
 	}
 }
+
+// Load the configuration, the API connection and the arguments shared by the
+// 'tx branch' subcommands
+func getBranchCommandSetup(c *cli.Context) (
+	config.Config, jsonapi.Connection, txlib.BranchCommandArguments, error,
+) {
+	var api jsonapi.Connection
+	var arguments txlib.BranchCommandArguments
+	cfg, err := config.LoadFromPaths(c.String("root-config"),
+		c.String("config"))
+	if err != nil {
+		return cfg, api, arguments, err
+	}
+
+	hostname, token, err := txlib.GetHostAndToken(
+		&cfg, c.String("hostname"), c.String("token"),
+	)
+	if err != nil {
+		return cfg, api, arguments, err
+	}
+
+	client, err := txlib.GetClient(c.String("cacert"))
+	if err != nil {
+		return cfg, api, arguments, err
+	}
+
+	api = jsonapi.Connection{
+		Host:   hostname,
+		Token:  token,
+		Client: client,
+		Headers: map[string]string{
+			"Integration": "txclient",
+		},
+	}
+	arguments = txlib.BranchCommandArguments{
+		ResourceIds: c.Args().Slice(),
+		Remote:      c.String("remote"),
+		DryRun:      c.Bool("dry-run"),
+		Force:       c.Bool("force"),
+		Skip:        c.Bool("skip"),
+	}
+	return cfg, api, arguments, nil
+}
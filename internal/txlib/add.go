@@ -1,10 +1,13 @@
 package txlib
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
+	"sort"
 	"strings"
 
 	"github.com/fatih/color"
@@ -52,10 +55,21 @@ func validateFileFilter(input string) error {
 	}
 	input = normaliseFileFilter(input)
 	for _, part := range strings.Split(input, string(os.PathSeparator)) {
-		if strings.Count(part, "<lang>") > 1 {
-			return errors.New(
-				"<lang> cannot appear more than once in the same part of the path",
-			)
+		if strings.Contains(part, "**") && part != "**" {
+			return errors.New("'**' must be a whole part of the path")
+		}
+		seen := make(map[string]bool)
+		for _, item := range parseFileFilter(part) {
+			if item.token == "" {
+				continue
+			}
+			if seen[item.token] {
+				return fmt.Errorf(
+					"<%s> cannot appear more than once in the same part of the path",
+					item.token,
+				)
+			}
+			seen[item.token] = true
 		}
 	}
 	return nil
@@ -103,6 +117,147 @@ func i18nFormatExists(list []string, ext string) bool {
 	return false
 }
 
+// Formats that share file extensions with others and whose files can be told
+// apart by their content
+var sniffedI18nTypes = map[string][]string{
+	".json": {"KEYVALUEJSON", "STRUCTURED_JSON"},
+	".yml":  {"YML_KEY", "YAML_GENERIC"},
+	".yaml": {"YML_KEY", "YAML_GENERIC"},
+}
+
+var yamlLanguageKeyRegexp = regexp.MustCompile(`^[a-z]{2,3}(?:[_-][A-Za-z0-9]+)*$`)
+
+/*
+Guess the format of a file by looking at its content. Only formats in
+'sniffedI18nTypes' are recognised, and only when the content is typical of
+them; an empty string is returned otherwise, so that the user gets to choose.
+'isLanguageCode' tells whether a code is known to Transifex.
+*/
+func sniffI18nType(
+	ext string, content []byte, isLanguageCode func(string) bool,
+) string {
+	switch ext {
+	case ".json":
+		var data interface{}
+		if err := json.Unmarshal(content, &data); err != nil {
+			return ""
+		}
+		root, ok := data.(map[string]interface{})
+		if !ok {
+			return ""
+		}
+		// STRUCTURED_JSON keeps each string in a "string" field, next to
+		// fields like "context" and "developer_comment"
+		var isStructured func(value interface{}) bool
+		isStructured = func(value interface{}) bool {
+			object, ok := value.(map[string]interface{})
+			if !ok {
+				return false
+			}
+			if _, ok := object["string"].(string); ok {
+				return true
+			}
+			for _, child := range object {
+				if isStructured(child) {
+					return true
+				}
+			}
+			return false
+		}
+		for _, value := range root {
+			if isStructured(value) {
+				return "STRUCTURED_JSON"
+			}
+		}
+		// Only a flat object of strings is surely KEYVALUEJSON; nested objects
+		// may as well be the messages of other formats, like Chrome's
+		// {"key": {"message": "..."}}
+		for _, value := range root {
+			if _, ok := value.(string); !ok {
+				return ""
+			}
+		}
+		return "KEYVALUEJSON"
+	case ".yml", ".yaml":
+		// YML_KEY files, like the ones used by Rails, nest everything under
+		// a single language code
+		var topLevelKeys []string
+		for _, line := range strings.Split(string(content), "\n") {
+			line = strings.TrimRight(line, " \t\r")
+			if line == "" || line == "---" ||
+				strings.HasPrefix(line, "#") || strings.HasPrefix(line, "%") ||
+				strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t") {
+				continue
+			}
+			if strings.HasPrefix(line, "-") || !strings.Contains(line, ":") {
+				return ""
+			}
+			key := strings.SplitN(line, ":", 2)[0]
+			topLevelKeys = append(topLevelKeys, strings.Trim(key, `"'`))
+		}
+		if len(topLevelKeys) == 0 {
+			return ""
+		}
+		if len(topLevelKeys) == 1 &&
+			yamlLanguageKeyRegexp.MatchString(topLevelKeys[0]) {
+			// Could as well be a generic file with a root like 'app'
+			languageCode := strings.ReplaceAll(topLevelKeys[0], "-", "_")
+			if isLanguageCode(languageCode) {
+				return "YML_KEY"
+			}
+			return ""
+		}
+		return "YAML_GENERIC"
+	}
+	return ""
+}
+
+/*
+Find the formats that 'sourceFile' may be in, out of 'formats' as returned by
+'txapi.GetI18nFormats'. The candidates are the formats that accept the file's
+extension; if the content of the file matches one of them, it is the only one
+returned.
+*/
+func detectI18nTypes(
+	api *jsonapi.Connection,
+	sourceFile string,
+	formats map[string]*jsonapi.Resource,
+) ([]txapi.I18nFormatsAttributes, error) {
+	ext := strings.ToLower(filepath.Ext(sourceFile))
+	var candidates []txapi.I18nFormatsAttributes
+	for _, format := range formats {
+		var i18nFormatsAttributes txapi.I18nFormatsAttributes
+		err := format.MapAttributes(&i18nFormatsAttributes)
+		if err != nil {
+			return nil, err
+		}
+		if i18nFormatExists(i18nFormatsAttributes.FileExtensions, ext) {
+			candidates = append(candidates, i18nFormatsAttributes)
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].Name < candidates[j].Name
+	})
+	if len(candidates) <= 1 || sniffedI18nTypes[ext] == nil {
+		return candidates, nil
+	}
+
+	content, err := os.ReadFile(sourceFile)
+	if err != nil {
+		return nil, err
+	}
+	sniffed := sniffI18nType(ext, content, func(code string) bool {
+		language, err := txapi.GetLanguage(api, code)
+		return err == nil && language != nil
+	})
+	for _, candidate := range candidates {
+		if candidate.Name == sniffed {
+			return []txapi.I18nFormatsAttributes{candidate}, nil
+		}
+	}
+	return candidates, nil
+}
+
 func getSelectTemplate(str string) *promptui.SelectTemplates {
 	var template = &promptui.SelectTemplates{
 		Active:   "> {{.Name }} ({{.Value | faint}})",
@@ -366,18 +521,16 @@ func AddCommandInteractive(cfg *config.Config, api jsonapi.Connection) error {
 		if err != nil {
 			return err
 		}
-		fileExtension := filepath.Ext(answers.SourceFile)
-		for _, value := range formats {
-			var i18nFormatsAttributes txapi.I18nFormatsAttributes
-			_ = value.MapAttributes(&i18nFormatsAttributes)
-			// Add selection only if file extension is included in the extensions
-			if i18nFormatExists(i18nFormatsAttributes.FileExtensions, fileExtension) {
-				selectItems = append(selectItems, selectedItem{
-					Name: i18nFormatsAttributes.Name,
-					Value: i18nFormatsAttributes.Description + " " +
-						strings.Join(i18nFormatsAttributes.FileExtensions, ", "),
-				})
-			}
+		candidates, err := detectI18nTypes(&api, answers.SourceFile, formats)
+		if err != nil {
+			return err
+		}
+		for _, i18nFormatsAttributes := range candidates {
+			selectItems = append(selectItems, selectedItem{
+				Name: i18nFormatsAttributes.Name,
+				Value: i18nFormatsAttributes.Description + " " +
+					strings.Join(i18nFormatsAttributes.FileExtensions, ", "),
+			})
 		}
 
 		// Return no items error
@@ -386,34 +539,47 @@ func AddCommandInteractive(cfg *config.Config, api jsonapi.Connection) error {
 				"this file. Maybe choose another file")
 		}
 
-		prompt = promptui.Select{
-			Label:     "What is the file format of the source file?",
-			Items:     selectItems,
-			Templates: getSelectTemplate("Selected format"),
-			Searcher:  searchList,
-		}
+		// Only prompt if the source file could be in more than one format
+		if len(selectItems) == 1 {
+			fmt.Println()
+			fmt.Printf("Detected file format: %s\n", selectItems[0].Name)
+			answers.RType = selectItems[0].Name
+		} else {
+			prompt = promptui.Select{
+				Label:     "What is the file format of the source file?",
+				Items:     selectItems,
+				Templates: getSelectTemplate("Selected format"),
+				Searcher:  searchList,
+			}
 
-		fmt.Println()
-		idx, _, err = prompt.Run()
-		if err != nil {
-			if err == promptui.ErrInterrupt {
-				return err
-			} else {
-				return fmt.Errorf("something went wrong: %v", err)
+			fmt.Println()
+			idx, _, err = prompt.Run()
+			if err != nil {
+				if err == promptui.ErrInterrupt {
+					return err
+				} else {
+					return fmt.Errorf("something went wrong: %v", err)
+				}
 			}
-		}
 
-		answers.RType = selectItems[idx].Name
+			answers.RType = selectItems[idx].Name
+		}
 	}
-	err = AddCommand(cfg, &answers)
+	err = AddCommand(cfg, &api, &answers)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
+/*
+Add a resource to the local configuration. If 'args.RType' is empty, the
+format is detected from the source file, with the file formats fetched through
+'api'.
+*/
 func AddCommand(
 	cfg *config.Config,
+	api *jsonapi.Connection,
 	args *AddCommandArguments,
 ) error {
 
@@ -429,6 +595,13 @@ func AddCommand(
 		return err
 	}
 
+	if args.RType == "" {
+		args.RType, err = detectI18nType(api, args.OrganizationSlug, args.SourceFile)
+		if err != nil {
+			return err
+		}
+	}
+
 	cfg.AddResource(config.Resource{
 		OrganizationSlug: args.OrganizationSlug,
 		ProjectSlug:      args.ProjectSlug,
@@ -451,3 +624,39 @@ You can now push and pull content with 'tx push' and 'tx pull'`))
 
 	return nil
 }
+
+func detectI18nType(
+	api *jsonapi.Connection, organizationSlug, sourceFile string,
+) (string, error) {
+	organization := &jsonapi.Resource{
+		API:  api,
+		Type: "organizations",
+		Id:   fmt.Sprintf("o:%s", organizationSlug),
+	}
+	formats, err := txapi.GetI18nFormats(api, organization)
+	if err != nil {
+		return "", fmt.Errorf("unable to fetch i18n formats: %s", err)
+	}
+	candidates, err := detectI18nTypes(api, sourceFile, formats)
+	if err != nil {
+		return "", err
+	}
+	switch len(candidates) {
+	case 0:
+		return "", fmt.Errorf(
+			"no file format matches '%s'; use '--type' to set it", sourceFile,
+		)
+	case 1:
+		fmt.Printf("Detected file format: %s\n", candidates[0].Name)
+		return candidates[0].Name, nil
+	}
+	var names []string
+	for _, candidate := range candidates {
+		names = append(names, candidate.Name)
+	}
+	return "", fmt.Errorf(
+		"could not tell the file format of '%s'; use '--type' with one of: %s",
+		sourceFile,
+		strings.Join(names, ", "),
+	)
+}
@@ -11,12 +11,40 @@ import (
 	"github.com/transifex/cli/pkg/txapi"
 )
 
+/*
+File layouts of common platforms for 'tx add remote --preset'. The file filter
+is used unless one is given on the command line. The source file, if set, uses
+the same placeholders as the file filter, except for the language ones.
+*/
+type AddRemotePreset struct {
+	FileFilter         string
+	SourceFile         string
+	LanguageConvention string
+}
+
+var AddRemotePresets = map[string]AddRemotePreset{
+	"android": {
+		FileFilter:         "app/src/main/res/values-<lang>/<resource_slug>.xml",
+		SourceFile:         "app/src/main/res/values/<resource_slug>.xml",
+		LanguageConvention: "android",
+	},
+	"ios": {
+		FileFilter:         "<project_slug>/<lang>.lproj/<resource_slug>.<ext>",
+		LanguageConvention: "apple",
+	},
+	"web": {
+		FileFilter:         "locales/<lang>/<resource_slug>.<ext>",
+		LanguageConvention: "bcp47",
+	},
+}
+
 func AddRemoteCommand(
 	cfg *config.Config,
 	api *jsonapi.Connection,
 	projectUrl,
 	fileFilter string,
 	minimumPerc int,
+	preset *AddRemotePreset,
 ) error {
 	// "/org/proj/whatever..." => ["", "org", "proj", whatever...]
 	//                             ↑   ↑      ↑       ↑
@@ -77,26 +105,22 @@ func AddRemoteCommand(
 		}
 
 		// Construct file-filter
-		resourceFileFilter := strings.ReplaceAll(
-			fileFilter,
-			"<project_slug>",
-			projectSlug,
-		)
-
 		var resourceAttributes txapi.ResourceAttributes
 		resource.MapAttributes(&resourceAttributes)
-		resourceFileFilter = strings.ReplaceAll(
-			resourceFileFilter,
-			"<resource_slug>",
-			resourceAttributes.Slug,
-		)
-
-		if strings.Contains(resourceFileFilter, "<ext>") {
-			var i18nFormatAttributes txapi.I18nFormatsAttributes
-			i18nFormat.MapAttributes(&i18nFormatAttributes)
-			ext := i18nFormatAttributes.FileExtensions[0][1:]
-			resourceFileFilter = strings.ReplaceAll(resourceFileFilter, "<ext>", ext)
+		var i18nFormatAttributes txapi.I18nFormatsAttributes
+		i18nFormat.MapAttributes(&i18nFormatAttributes)
+		fillTemplate := func(template string) string {
+			result := strings.ReplaceAll(template, "<project_slug>", projectSlug)
+			result = strings.ReplaceAll(
+				result, "<resource_slug>", resourceAttributes.Slug,
+			)
+			if strings.Contains(result, "<ext>") {
+				ext := i18nFormatAttributes.FileExtensions[0][1:]
+				result = strings.ReplaceAll(result, "<ext>", ext)
+			}
+			return result
 		}
+		resourceFileFilter := fillTemplate(fileFilter)
 
 		// Construct source file
 		sourceLanguageRelationship, exists := project.Relationships["source_language"]
@@ -105,11 +129,19 @@ func AddRemoteCommand(
 		}
 		sourceLanguage := sourceLanguageRelationship.DataSingular
 		sourceLanguageCode := sourceLanguage.Id[2:]
-		sourceFile := strings.ReplaceAll(
-			resourceFileFilter,
-			"<lang>",
-			sourceLanguageCode,
-		)
+		languageConvention := ""
+		if preset != nil {
+			languageConvention = preset.LanguageConvention
+		}
+		var sourceFile string
+		if preset != nil && preset.SourceFile != "" {
+			sourceFile = fillTemplate(preset.SourceFile)
+		} else {
+			sourceFile = expandFileFilter(
+				resourceFileFilter,
+				toLocalLanguageCode(languageConvention, sourceLanguageCode),
+			)
+		}
 
 		// Construct minimum percentage
 		if minimumPerc == -1 {
@@ -118,15 +150,16 @@ func AddRemoteCommand(
 
 		// Add to local config (in RAM, will save to disk later)
 		cfg.AddResource(config.Resource{
-			OrganizationSlug:  organizationSlug,
-			ProjectSlug:       projectSlug,
-			ResourceSlug:      resourceAttributes.Slug,
-			FileFilter:        resourceFileFilter,
-			SourceFile:        sourceFile,
-			SourceLanguage:    sourceLanguageCode,
-			Type:              i18nFormat.Id,
-			MinimumPercentage: minimumPerc,
-			ResourceName:      resourceAttributes.Name,
+			OrganizationSlug:   organizationSlug,
+			ProjectSlug:        projectSlug,
+			ResourceSlug:       resourceAttributes.Slug,
+			FileFilter:         resourceFileFilter,
+			SourceFile:         sourceFile,
+			SourceLanguage:     sourceLanguageCode,
+			Type:               i18nFormat.Id,
+			MinimumPercentage:  minimumPerc,
+			ResourceName:       resourceAttributes.Name,
+			LanguageConvention: languageConvention,
 		})
 		fmt.Printf(
 			"Added '%s.%s' to configuration\n",
@@ -61,6 +61,7 @@ func TestAddRemote(t *testing.T) {
 		// Lets make the file filter a bit weird
 		"locale/<project_slug><project_slug>.<resource_slug>/<lang>.<ext>",
 		50,
+		nil,
 	)
 	if err != nil {
 		t.Errorf("%s", err)
@@ -85,6 +86,76 @@ func TestAddRemote(t *testing.T) {
 	}
 }
 
+func TestAddRemoteWithPreset(t *testing.T) {
+	curDir, _ := os.Getwd()
+	tempDir, _ := os.MkdirTemp("", "")
+	defer os.RemoveAll(tempDir)
+	_ = os.Chdir(tempDir)
+	defer os.Chdir(curDir)
+
+	resourcesUrl := fmt.Sprintf(
+		"/resources?%s=%s",
+		url.QueryEscape("filter[project]"),
+		url.QueryEscape(projectId),
+	)
+	i18nFormatsUrl := fmt.Sprintf(
+		"/i18n_formats?%s=%s",
+		url.QueryEscape("filter[organization]"),
+		url.QueryEscape("o:orgslug"),
+	)
+	mockData := jsonapi.MockData{
+		projectUrl: getProjectEndpoint(),
+		resourcesUrl: jsonapi.GetMockTextResponse(
+			`{"data": [{
+				"type": "resources",
+				"id": "o:orgslug:p:projslug:r:resslug",
+				"attributes": {"slug": "resslug"},
+				"relationships": {
+					"i18n_format": {"data": {"type": "i18n_formats", "id": "ANDROID"}}
+				}
+			}]}`,
+		),
+		i18nFormatsUrl: jsonapi.GetMockTextResponse(
+			`{"data": [{
+				"type": "i18n_formats",
+				"id": "ANDROID",
+				"attributes": {"file_extensions": [".xml"]}
+			}]}`,
+		),
+	}
+
+	api := jsonapi.GetTestConnection(mockData)
+	cfg := &config.Config{Local: &config.LocalConfig{}}
+
+	preset := AddRemotePresets["android"]
+	err := AddRemoteCommand(
+		cfg,
+		&api,
+		"https://app.transifex.com/orgslug/projslug/whatever/whatever/",
+		preset.FileFilter,
+		-1,
+		&preset,
+	)
+	if err != nil {
+		t.Errorf("%s", err)
+	}
+
+	actual := cfg.Local.Resources
+	expected := []config.Resource{{
+		OrganizationSlug:   "orgslug",
+		ProjectSlug:        "projslug",
+		ResourceSlug:       "resslug",
+		FileFilter:         "app/src/main/res/values-<lang>/resslug.xml",
+		SourceFile:         "app/src/main/res/values/resslug.xml",
+		SourceLanguage:     "en",
+		Type:               "ANDROID",
+		LanguageConvention: "android",
+	}}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Got request '%+v', expected '%+v'", actual, expected)
+	}
+}
+
 func TestAddRemoteInvalidFileFormat(t *testing.T) {
 	curDir, _ := os.Getwd()
 	tempDir, _ := os.MkdirTemp("", "")
@@ -139,6 +210,7 @@ func TestAddRemoteInvalidFileFormat(t *testing.T) {
 		// Lets make the file filter a bit weird
 		"locale/<project_slug><project_slug>.<resource_slug>/<lang>.<ext>",
 		50,
+		nil,
 	)
 	if err != nil {
 		t.Errorf("%s", err)
@@ -2,11 +2,15 @@ package txlib
 
 import (
 	"fmt"
+	"net/url"
 	"os"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/transifex/cli/internal/txlib/config"
+	"github.com/transifex/cli/pkg/assert"
+	"github.com/transifex/cli/pkg/jsonapi"
 )
 
 func TestNoSourceFileErrorAddCommand(t *testing.T) {
@@ -22,7 +26,7 @@ func TestNoSourceFileErrorAddCommand(t *testing.T) {
 		RType:            "type",
 		SourceFile:       "",
 	}
-	err := AddCommand(&cfg, &args)
+	err := AddCommand(&cfg, nil, &args)
 	if err == nil {
 		t.Errorf("No source file should return an error when trying to add")
 	}
@@ -57,7 +61,7 @@ func TestSuccessfulAddForAddCommand(t *testing.T) {
 		SourceFile:       "aaa.json",
 	}
 
-	err = AddCommand(&cfg, &args)
+	err = AddCommand(&cfg, nil, &args)
 	if err != nil {
 		t.Error(err)
 	}
@@ -84,6 +88,152 @@ func TestSuccessfulAddForAddCommand(t *testing.T) {
 	}
 }
 
+func TestSniffI18nType(t *testing.T) {
+	sniff := func(ext, content string) string {
+		return sniffI18nType(ext, []byte(content), func(code string) bool {
+			return code == "en" || code == "pt_BR"
+		})
+	}
+
+	assert.Equal(t, sniff(".json", `{"a": "b", "c": "d"}`), "KEYVALUEJSON")
+	assert.Equal(t, sniff(".json", `{"a": {"b": "c"}}`), "")
+	assert.Equal(
+		t, sniff(".json", `{"a": {"message": "b", "description": "c"}}`), "",
+	)
+	assert.Equal(t, sniff(".json", `{"a": {"defaultMessage": "b"}}`), "")
+	assert.Equal(
+		t,
+		sniff(".json", `{"a": {"b": {"string": "c", "context": "d"}}}`),
+		"STRUCTURED_JSON",
+	)
+	assert.Equal(t, sniff(".json", `["a"]`), "")
+	assert.Equal(t, sniff(".json", `{"a": `), "")
+
+	assert.Equal(
+		t, sniff(".yml", "---\n# comment\nen:\n  hello: world\n"), "YML_KEY",
+	)
+	assert.Equal(t, sniff(".yaml", "\"pt-BR\":\n  hello: mundo\n"), "YML_KEY")
+	assert.Equal(t, sniff(".yml", "hello: world\nbye: world\n"), "YAML_GENERIC")
+	assert.Equal(t, sniff(".yml", "messages:\n  hello: world\n"), "YAML_GENERIC")
+	// A generic file with a single root that looks like a language code
+	assert.Equal(t, sniff(".yml", "app:\n  name: x\n"), "")
+	assert.Equal(t, sniff(".yml", "- a\n- b\n"), "")
+	assert.Equal(t, sniff(".po", "msgid \"\""), "")
+}
+
+func TestAddCommandDetectsI18nType(t *testing.T) {
+	afterTest := beforeAddTest(t, nil, nil)
+	defer afterTest()
+
+	files := map[string]string{
+		"keyvalue.json":   `{"hello": "world"}`,
+		"structured.json": `{"hello": {"string": "world"}}`,
+		"broken.json":     `{"hello": `,
+		"messages.json":   `{"hello": {"message": "world"}}`,
+		"en.yml":          "en:\n  hello: world\n",
+		"generic.yml":     "hello: world\n",
+		"en.po":           `msgid "hello"`,
+		"en.txt":          "hello",
+	}
+	for name, content := range files {
+		err := os.WriteFile(name, []byte(content), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	i18nFormatsUrl := fmt.Sprintf(
+		"/i18n_formats?%s=%s",
+		url.QueryEscape("filter[organization]"),
+		url.QueryEscape("o:org"),
+	)
+	i18nFormats := `{"data": [
+		{"type": "i18n_formats", "id": "KEYVALUEJSON",
+		 "attributes": {"name": "KEYVALUEJSON", "file_extensions": [".json"]}},
+		{"type": "i18n_formats", "id": "STRUCTURED_JSON",
+		 "attributes": {"name": "STRUCTURED_JSON", "file_extensions": [".json"]}},
+		{"type": "i18n_formats", "id": "YML_KEY",
+		 "attributes": {"name": "YML_KEY", "file_extensions": [".yml", ".yaml"]}},
+		{"type": "i18n_formats", "id": "YAML_GENERIC",
+		 "attributes": {"name": "YAML_GENERIC", "file_extensions": [".yml", ".yaml"]}},
+		{"type": "i18n_formats", "id": "PO",
+		 "attributes": {"name": "PO", "file_extensions": [".po", ".pot"]}}
+	]}`
+	var requests []jsonapi.MockRequest
+	for range files {
+		requests = append(
+			requests,
+			jsonapi.MockRequest{Response: jsonapi.MockResponse{Text: i18nFormats}},
+		)
+	}
+	mockData := jsonapi.MockData{
+		i18nFormatsUrl: &jsonapi.MockEndpoint{Requests: requests},
+		// To tell that the root of 'en.yml' is a language code
+		"/languages": getLanguagesEndpoint([]string{"en", "fr"}),
+	}
+	api := jsonapi.GetTestConnection(mockData)
+
+	add := func(sourceFile string) (string, error) {
+		cfg := config.Config{
+			Local: &config.LocalConfig{Host: "host", Path: "localconf"},
+			Root:  &config.RootConfig{Path: "rootconf"},
+		}
+		err := cfg.Local.Save()
+		if err != nil {
+			t.Fatal(err)
+		}
+		args := AddCommandArguments{
+			OrganizationSlug: "org",
+			ProjectSlug:      "proj",
+			ResourceSlug:     "res",
+			FileFilter:       "<lang>" + sourceFile,
+			SourceFile:       sourceFile,
+		}
+		err = AddCommand(&cfg, &api, &args)
+		if err != nil {
+			return "", err
+		}
+		return cfg.Local.Resources[0].Type, nil
+	}
+
+	for sourceFile, expected := range map[string]string{
+		"keyvalue.json":   "KEYVALUEJSON",
+		"structured.json": "STRUCTURED_JSON",
+		"en.yml":          "YML_KEY",
+		"generic.yml":     "YAML_GENERIC",
+		"en.po":           "PO",
+	} {
+		rType, err := add(sourceFile)
+		if err != nil {
+			t.Fatalf("%s: %s", sourceFile, err)
+		}
+		assert.Equal(t, rType, expected)
+	}
+
+	_, err := add("broken.json")
+	if err == nil {
+		t.Fatal("Expected an error for a file that could be in many formats")
+	}
+	assert.True(
+		t,
+		strings.Contains(err.Error(), "one of: KEYVALUEJSON, STRUCTURED_JSON"),
+	)
+
+	_, err = add("messages.json")
+	if err == nil {
+		t.Fatal("Expected an error for a file with nested messages")
+	}
+	assert.True(
+		t,
+		strings.Contains(err.Error(), "one of: KEYVALUEJSON, STRUCTURED_JSON"),
+	)
+
+	_, err = add("en.txt")
+	if err == nil {
+		t.Fatal("Expected an error for a file that matches no format")
+	}
+}
+
 func beforeAddTest(t *testing.T,
 	languageCodes []string,
 	customFiles []string) func() {
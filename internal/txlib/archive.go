@@ -0,0 +1,244 @@
+package txlib
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const archiveManifestName = "manifest.json"
+
+type archiveManifest struct {
+	CreatedAt string                 `json:"created_at"`
+	Files     []archiveManifestEntry `json:"files"`
+}
+
+type archiveManifestEntry struct {
+	Path              string  `json:"path"`
+	Resource          string  `json:"resource"`
+	Language          string  `json:"language"`
+	Mode              string  `json:"mode"`
+	TotalStrings      int     `json:"total_strings"`
+	TranslatedStrings int     `json:"translated_strings"`
+	ReviewedStrings   int     `json:"reviewed_strings"`
+	ProofreadStrings  int     `json:"proofread_strings"`
+	Completion        float32 `json:"completion"`
+}
+
+/*
+Return the format of the archive based on the extension of 'archivePath':
+"zip", "tar" or "tar.gz".
+*/
+func getArchiveFormat(archivePath string) (string, error) {
+	lower := strings.ToLower(archivePath)
+	switch {
+	case strings.HasSuffix(lower, ".zip"):
+		return "zip", nil
+	case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+		return "tar.gz", nil
+	case strings.HasSuffix(lower, ".tar"):
+		return "tar", nil
+	}
+	return "", fmt.Errorf(
+		"unsupported archive '%s'; use a .zip, .tar or .tar.gz extension",
+		archivePath,
+	)
+}
+
+/*
+Return the path a pulled file should have inside the archive. Paths are made
+relative to the current directory and any leading '../' is dropped so that the
+archive can never write outside the directory it is extracted in.
+*/
+func getArchiveEntryPath(filePath string) string {
+	if filepath.IsAbs(filePath) {
+		cwd, err := os.Getwd()
+		if err == nil {
+			relative, err := filepath.Rel(cwd, filePath)
+			if err == nil {
+				filePath = relative
+			}
+		}
+	}
+	parts := strings.Split(filepath.ToSlash(filepath.Clean(filePath)), "/")
+	for len(parts) > 1 && (parts[0] == ".." || parts[0] == "") {
+		parts = parts[1:]
+	}
+	return strings.Join(parts, "/")
+}
+
+func makeArchiveManifest(staging *pullStaging, stagedFiles map[string]string) (
+	archiveManifest, []string,
+) {
+	var filePaths []string
+	for filePath := range stagedFiles {
+		filePaths = append(filePaths, filePath)
+	}
+	sort.Strings(filePaths)
+
+	manifest := archiveManifest{
+		CreatedAt: time.Now().UTC().Format(time.RFC3339),
+		Files:     []archiveManifestEntry{},
+	}
+	for _, filePath := range filePaths {
+		info := staging.info[filePath]
+		entry := archiveManifestEntry{
+			Path:     getArchiveEntryPath(filePath),
+			Resource: info.ResourceId,
+			Language: info.LanguageCode,
+			Mode:     info.Mode,
+		}
+		if info.Stats != nil {
+			entry.TotalStrings = info.Stats.TotalStrings
+			entry.TranslatedStrings = info.Stats.TranslatedStrings
+			entry.ReviewedStrings = info.Stats.ReviewedStrings
+			entry.ProofreadStrings = info.Stats.ProofreadStrings
+			if info.Stats.TotalStrings > 0 {
+				actedOnStrings := info.Stats.TranslatedStrings
+				switch info.Mode {
+				case "reviewed", "onlyreviewed":
+					actedOnStrings = info.Stats.ReviewedStrings
+				case "proofread", "onlyproofread":
+					actedOnStrings = info.Stats.ProofreadStrings
+				}
+				entry.Completion = getActedOnStringsPercentage(
+					float32(actedOnStrings), float32(info.Stats.TotalStrings),
+				)
+			}
+		}
+		manifest.Files = append(manifest.Files, entry)
+	}
+	return manifest, filePaths
+}
+
+/*
+Write every file downloaded in the staging area, along with a manifest
+describing them, into the archive at 'archivePath'. The archive is first
+written next to its destination and renamed into place once complete.
+*/
+func writePullArchive(staging *pullStaging, archivePath string) (int, error) {
+	format, err := getArchiveFormat(archivePath)
+	if err != nil {
+		return 0, err
+	}
+	stagedFiles := staging.stagedFiles()
+	manifest, filePaths := makeArchiveManifest(staging, stagedFiles)
+	manifestContent, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return 0, err
+	}
+
+	dir := filepath.Dir(archivePath)
+	err = os.MkdirAll(dir, os.ModePerm)
+	if err != nil {
+		return 0, err
+	}
+	out, err := os.CreateTemp(dir, "."+filepath.Base(archivePath)+".tx-*")
+	if err != nil {
+		return 0, err
+	}
+	tempPath := out.Name()
+	defer os.Remove(tempPath)
+
+	var add func(name string, content io.Reader, size int64) error
+	var closers []io.Closer
+	switch format {
+	case "zip":
+		zipWriter := zip.NewWriter(out)
+		closers = append(closers, zipWriter)
+		add = func(name string, content io.Reader, size int64) error {
+			header := &zip.FileHeader{
+				Name:     name,
+				Method:   zip.Deflate,
+				Modified: time.Now(),
+			}
+			writer, err := zipWriter.CreateHeader(header)
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(writer, content)
+			return err
+		}
+	default:
+		var writer io.Writer = out
+		if format == "tar.gz" {
+			gzipWriter := gzip.NewWriter(out)
+			closers = append(closers, gzipWriter)
+			writer = gzipWriter
+		}
+		tarWriter := tar.NewWriter(writer)
+		// The tar writer must be closed before the gzip one
+		closers = append([]io.Closer{tarWriter}, closers...)
+		add = func(name string, content io.Reader, size int64) error {
+			err := tarWriter.WriteHeader(&tar.Header{
+				Name:    name,
+				Mode:    0644,
+				Size:    size,
+				ModTime: time.Now(),
+			})
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(tarWriter, content)
+			return err
+		}
+	}
+
+	addFile := func(filePath string) error {
+		file, err := os.Open(stagedFiles[filePath])
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		stat, err := file.Stat()
+		if err != nil {
+			return err
+		}
+		return add(getArchiveEntryPath(filePath), file, stat.Size())
+	}
+
+	for _, filePath := range filePaths {
+		err = addFile(filePath)
+		if err != nil {
+			out.Close()
+			return 0, fmt.Errorf("could not add '%s' to archive: %w", filePath, err)
+		}
+	}
+	err = add(
+		archiveManifestName,
+		strings.NewReader(string(manifestContent)),
+		int64(len(manifestContent)),
+	)
+	if err != nil {
+		out.Close()
+		return 0, err
+	}
+	for _, closer := range closers {
+		err = closer.Close()
+		if err != nil {
+			out.Close()
+			return 0, err
+		}
+	}
+	err = out.Close()
+	if err != nil {
+		return 0, err
+	}
+	err = os.Chmod(tempPath, 0644)
+	if err != nil {
+		return 0, err
+	}
+	err = os.Rename(tempPath, archivePath)
+	if err != nil {
+		return 0, err
+	}
+	return len(filePaths), nil
+}
@@ -0,0 +1,258 @@
+package txlib
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/transifex/cli/internal/txlib/config"
+	"github.com/transifex/cli/pkg/jsonapi"
+	"github.com/transifex/cli/pkg/txapi"
+)
+
+type BranchCommandArguments struct {
+	ResourceIds []string
+	// Git remote whose branches also count as existing
+	Remote string
+	DryRun bool
+	// Delete branch resources even if they have translations
+	Force bool
+	Skip  bool
+}
+
+// A resource created by pushing a configured resource with a branch
+type branchResource struct {
+	cfgResource config.Resource
+	// The git branch as recorded in the resource's name or, for resources
+	// named otherwise, the part of the slug that isn't the resource's
+	branch string
+	// The slug of the configured resource the branch resource is of
+	mainResourceSlug string
+}
+
+/*
+Find the branch resources of each configured resource on Transifex. Resources
+named after a branch, as pushes name them, belong to a configured resource if
+their slug is the one 'branch_template' gives for that branch, see
+getBranchResourceSlug. Other resources are matched by slug alone. Resources
+that are themselves configured are never branch resources.
+*/
+func findBranchResources(
+	api *jsonapi.Connection,
+	cfg *config.Config,
+	cfgResources []*config.Resource,
+) ([][]branchResource, error) {
+	projectSlugs := make(map[string][]string)
+	projectNames := make(map[string]string)
+	configured := make(map[string]bool)
+	for _, cfgResource := range cfg.Local.Resources {
+		configured[cfgResource.GetAPv3Id()] = true
+	}
+	result := make([][]branchResource, len(cfgResources))
+	for i, cfgResource := range cfgResources {
+		projectId := fmt.Sprintf(
+			"o:%s:p:%s", cfgResource.OrganizationSlug, cfgResource.ProjectSlug,
+		)
+		slugs, exists := projectSlugs[projectId]
+		if !exists {
+			project, err := txapi.GetProjectById(api, projectId)
+			if err != nil {
+				return nil, err
+			}
+			if project == nil {
+				return nil, fmt.Errorf("project '%s' not found", projectId)
+			}
+			resources, err := txapi.GetResources(api, project)
+			if err != nil {
+				return nil, err
+			}
+			for _, resource := range resources {
+				var resourceAttributes txapi.ResourceAttributes
+				err := resource.MapAttributes(&resourceAttributes)
+				if err != nil {
+					return nil, err
+				}
+				slugs = append(slugs, resourceAttributes.Slug)
+				projectNames[resourceAttributes.Slug] = resourceAttributes.Name
+			}
+			sort.Strings(slugs)
+			projectSlugs[projectId] = slugs
+		}
+
+		pattern := getBranchResourceSlugPattern(
+			cfg.Local.BranchNaming, cfgResource.ResourceSlug,
+		)
+		for _, resourceSlug := range slugs {
+			branchCfgResource := *cfgResource
+			branchCfgResource.ResourceSlug = resourceSlug
+			if resourceSlug == cfgResource.ResourceSlug ||
+				configured[branchCfgResource.GetAPv3Id()] {
+				continue
+			}
+			branch, ok := parseBranchResourceName(projectNames[resourceSlug])
+			if ok {
+				if getBranchResourceSlug(
+					cfg.Local.BranchNaming, cfgResource.ResourceSlug, branch,
+				) != resourceSlug {
+					continue
+				}
+			} else if pattern.MatchString(resourceSlug) {
+				branch = strings.Trim(strings.Replace(
+					resourceSlug, cfgResource.ResourceSlug, "", 1,
+				), "-")
+			} else {
+				continue
+			}
+			result[i] = append(result[i], branchResource{
+				cfgResource:      branchCfgResource,
+				branch:           branch,
+				mainResourceSlug: cfgResource.ResourceSlug,
+			})
+		}
+	}
+	return result, nil
+}
+
+// The names of the git branches that exist locally and, if 'remote' is set, on
+// that remote
+func getExistingBranches(remote string) ([]string, error) {
+	branches, err := getGitBranches()
+	if err != nil {
+		return nil, err
+	}
+	if remote != "" {
+		remoteBranches, err := getGitRemoteBranches(remote)
+		if err != nil {
+			return nil, err
+		}
+		branches = append(branches, remoteBranches...)
+	}
+	return branches, nil
+}
+
+// Whether the branch resource belongs to one of the git branches
+func branchResourceExists(
+	cfg *config.Config, resource branchResource, branches []string,
+) bool {
+	for _, branch := range branches {
+		resourceSlug := getBranchResourceSlug(
+			cfg.Local.BranchNaming, resource.mainResourceSlug, branch,
+		)
+		if resourceSlug == resource.cfgResource.ResourceSlug {
+			return true
+		}
+	}
+	return false
+}
+
+func BranchListCommand(
+	cfg *config.Config,
+	api *jsonapi.Connection,
+	arguments *BranchCommandArguments,
+) error {
+	cfgResources, err := figureOutResources(arguments.ResourceIds, cfg)
+	if err != nil {
+		return err
+	}
+	branchResources, err := findBranchResources(api, cfg, cfgResources)
+	if err != nil {
+		return err
+	}
+	// Listing works outside of a git repository, just without telling which
+	// branches are gone
+	existingBranches, err := getExistingBranches(arguments.Remote)
+	if err != nil {
+		existingBranches = nil
+	}
+
+	for i, cfgResource := range cfgResources {
+		resourceName := fmt.Sprintf(
+			"%s.%s", cfgResource.ProjectSlug, cfgResource.ResourceSlug,
+		)
+		if len(branchResources[i]) == 0 {
+			fmt.Printf("%s: no branch resources\n", resourceName)
+			continue
+		}
+		fmt.Printf("%s\n", resourceName)
+		for _, branchResource := range branchResources[i] {
+			note := ""
+			if existingBranches != nil &&
+				!branchResourceExists(cfg, branchResource, existingBranches) {
+				note = " " + color.YellowString("[no git branch]")
+			}
+			fmt.Printf(
+				"  - %s (%s)%s\n",
+				branchResource.branch,
+				branchResource.cfgResource.ResourceSlug,
+				note,
+			)
+		}
+	}
+	return nil
+}
+
+/*
+Delete the branch resources whose git branch exists neither locally nor on
+'arguments.Remote'. The same checks as 'tx delete' apply, so resources with
+translations are kept unless 'arguments.Force' is set.
+*/
+func BranchPruneCommand(
+	cfg *config.Config,
+	api *jsonapi.Connection,
+	arguments *BranchCommandArguments,
+) error {
+	cfgResources, err := figureOutResources(arguments.ResourceIds, cfg)
+	if err != nil {
+		return err
+	}
+	// Unlike listing, pruning without knowing the branches would delete
+	// everything
+	existingBranches, err := getExistingBranches(arguments.Remote)
+	if err != nil {
+		return err
+	}
+	branchResources, err := findBranchResources(api, cfg, cfgResources)
+	if err != nil {
+		return err
+	}
+
+	pruned := 0
+	for i := range cfgResources {
+		for _, branchResource := range branchResources[i] {
+			if branchResourceExists(cfg, branchResource, existingBranches) {
+				continue
+			}
+			cfgResource := branchResource.cfgResource
+			if arguments.DryRun {
+				fmt.Printf(
+					"Would delete resource '%s.%s' (branch '%s')\n",
+					cfgResource.ProjectSlug,
+					cfgResource.ResourceSlug,
+					branchResource.branch,
+				)
+				pruned++
+				continue
+			}
+			err := deleteResource(api, cfg, cfgResource, DeleteCommandArguments{
+				Force: arguments.Force,
+				Skip:  arguments.Skip,
+			})
+			if err != nil {
+				if !arguments.Skip {
+					return err
+				}
+				color.Red("%s", err)
+				continue
+			}
+			pruned++
+		}
+	}
+
+	if arguments.DryRun {
+		fmt.Printf("\n# %d branch resource(s) would be deleted\n", pruned)
+	} else {
+		fmt.Printf("\n# Deleted %d branch resource(s)\n", pruned)
+	}
+	return nil
+}
@@ -0,0 +1,167 @@
+package txlib
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/transifex/cli/internal/txlib/config"
+	"github.com/transifex/cli/pkg/assert"
+	"github.com/transifex/cli/pkg/jsonapi"
+)
+
+func getBranchResourcesMockData() jsonapi.MockData {
+	resourcesListUrl := "/resources?filter%5Bproject%5D=o%3Aorgslug%3Ap%3Aprojslug"
+	return jsonapi.MockData{
+		projectUrl: getProjectEndpoint(),
+		resourcesListUrl: jsonapi.GetMockTextResponse(
+			`{"data": [
+				{"type": "resources", "id": "o:orgslug:p:projslug:r:resslug",
+				 "attributes": {"slug": "resslug"}},
+				{"type": "resources",
+				 "id": "o:orgslug:p:projslug:r:feature-x--resslug",
+				 "attributes": {"slug": "feature-x--resslug"}},
+				{"type": "resources",
+				 "id": "o:orgslug:p:projslug:r:old--resslug",
+				 "attributes": {"slug": "old--resslug"}},
+				{"type": "resources",
+				 "id": "o:orgslug:p:projslug:r:old--otherslug",
+				 "attributes": {"slug": "old--otherslug"}}
+			]}`,
+		),
+	}
+}
+
+func runBranchCommand(
+	command func(*BranchCommandArguments) error,
+	arguments *BranchCommandArguments,
+) (string, error) {
+	rescueStdout := os.Stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+
+	err := command(arguments)
+
+	w.Close()
+	out, _ := ioutil.ReadAll(r)
+	os.Stdout = rescueStdout
+	return string(out), err
+}
+
+func TestBranchListAndPrune(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	afterTest := beforeTest(t, nil, nil)
+	defer afterTest()
+
+	for _, gitArgs := range [][]string{
+		{"init", "-q"},
+		{"add", "."},
+		{"-c", "user.name=tx", "-c", "user.email=tx@example.com",
+			"commit", "-q", "-m", "initial"},
+		{"branch", "feature-x"},
+	} {
+		err := exec.Command("git", gitArgs...).Run()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg := getStandardConfig()
+	api := jsonapi.GetTestConnection(getBranchResourcesMockData())
+	result, err := runBranchCommand(
+		func(arguments *BranchCommandArguments) error {
+			return BranchListCommand(cfg, &api, arguments)
+		},
+		&BranchCommandArguments{},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, strings.Contains(
+		result, "  - feature-x (feature-x--resslug)\n",
+	))
+	assert.True(t, strings.Contains(
+		result, "  - old (old--resslug) [no git branch]\n",
+	))
+	assert.True(t, !strings.Contains(result, "otherslug"))
+
+	api = jsonapi.GetTestConnection(getBranchResourcesMockData())
+	result, err = runBranchCommand(
+		func(arguments *BranchCommandArguments) error {
+			return BranchPruneCommand(cfg, &api, arguments)
+		},
+		&BranchCommandArguments{DryRun: true},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(
+		t,
+		result,
+		"Would delete resource 'projslug.old--resslug' (branch 'old')\n\n"+
+			"# 1 branch resource(s) would be deleted\n",
+	)
+}
+
+func TestBranchPruneOutsideGit(t *testing.T) {
+	afterTest := beforeTest(t, nil, nil)
+	defer afterTest()
+
+	// Without a git repository every branch would look gone, so nothing
+	// should be requested
+	api := jsonapi.GetTestConnection(jsonapi.MockData{})
+	err := BranchPruneCommand(
+		getStandardConfig(), &api, &BranchCommandArguments{DryRun: true},
+	)
+	if err == nil {
+		t.Error("Expected an error outside of a git repository")
+	}
+}
+
+func TestFindBranchResourcesSkipsConfiguredResources(t *testing.T) {
+	resourcesListUrl := "/resources?filter%5Bproject%5D=o%3Aorgslug%3Ap%3Aprojslug"
+	api := jsonapi.GetTestConnection(jsonapi.MockData{
+		projectUrl: getProjectEndpoint(),
+		resourcesListUrl: jsonapi.GetMockTextResponse(
+			`{"data": [
+				{"type": "resources", "id": "o:orgslug:p:projslug:r:resslug",
+				 "attributes": {"slug": "resslug"}},
+				{"type": "resources",
+				 "id": "o:orgslug:p:projslug:r:legacy--resslug",
+				 "attributes": {"slug": "legacy--resslug"}},
+				{"type": "resources",
+				 "id": "o:orgslug:p:projslug:r:y--resslug",
+				 "attributes": {"slug": "y--resslug",
+				                "name": "aaa.json (branch y)"}},
+				{"type": "resources",
+				 "id": "o:orgslug:p:projslug:r:z--resslug",
+				 "attributes": {"slug": "z--resslug",
+				                "name": "aaa.json (branch other)"}}
+			]}`,
+		),
+	})
+	cfg := getStandardConfig()
+	legacy := cfg.Local.Resources[0]
+	legacy.ResourceSlug = "legacy--resslug"
+	cfg.Local.Resources = append(cfg.Local.Resources, legacy)
+
+	result, err := findBranchResources(
+		&api, cfg, []*config.Resource{&cfg.Local.Resources[0]},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var found []string
+	for _, resource := range result[0] {
+		found = append(
+			found, resource.cfgResource.ResourceSlug+":"+resource.branch,
+		)
+	}
+	// 'legacy--resslug' is configured and 'z--resslug' was created for a
+	// branch whose slug is different
+	assert.Equal(t, strings.Join(found, ","), "y--resslug:y")
+}
@@ -0,0 +1,309 @@
+package txlib
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/fatih/color"
+	"github.com/transifex/cli/internal/txlib/config"
+	"github.com/transifex/cli/pkg/jsonapi"
+	"github.com/transifex/cli/pkg/txapi"
+)
+
+type CheckCommandArguments struct {
+	ResourceIds []string
+	// Languages to check; if empty, all the languages of each resource that
+	// have a required percentage
+	Languages []string
+	// "translated", "reviewed" or "proofread"; if empty, the resource's pull
+	// mode decides
+	Mode string
+	// Overrides the 'required_perc' settings of the config if not -1
+	MinimumPercentage     int
+	MinimumPercentageUnit string
+}
+
+/*
+Returned by CheckCommand when languages are below the required completion, as
+opposed to errors that kept the check from running, so that CI can tell the
+two apart
+*/
+type CheckFailedError struct {
+	Offenders int
+	Checked   int
+}
+
+func (err *CheckFailedError) Error() string {
+	return fmt.Sprintf(
+		"%d of %d checked language(s) are below the required completion",
+		err.Offenders,
+		err.Checked,
+	)
+}
+
+// A language that did not reach its required completion
+type checkOffender struct {
+	resource   string
+	language   string
+	mode       string
+	completion string
+	required   int
+}
+
+/*
+Fail if any of the checked languages is less complete on Transifex than
+required, printing a table of the languages that fell short. Meant to be used
+as a gate in CI pipelines.
+*/
+func CheckCommand(
+	cfg *config.Config,
+	api *jsonapi.Connection,
+	arguments *CheckCommandArguments,
+) error {
+	var cfgResources []config.Resource
+	for _, resourceId := range arguments.ResourceIds {
+		cfgResource := cfg.FindResource(resourceId)
+		if cfgResource == nil {
+			return fmt.Errorf(
+				"could not find resource '%s' in local configuration",
+				resourceId,
+			)
+		}
+		cfgResources = append(cfgResources, *cfgResource)
+	}
+	if len(cfgResources) == 0 {
+		cfgResources = cfg.Local.Resources
+	}
+	if len(cfgResources) == 0 {
+		return errors.New("no resources found in config file")
+	}
+
+	fmt.Printf("# Checking %d resource(s)\n", len(cfgResources))
+
+	var offenders []checkOffender
+	checked := 0
+	for i := range cfgResources {
+		cfgResource := &cfgResources[i]
+		resourceOffenders, resourceChecked, err := checkResource(
+			cfg, api, cfgResource, arguments,
+		)
+		if err != nil {
+			return fmt.Errorf(
+				"%s.%s: %w",
+				cfgResource.ProjectSlug,
+				cfgResource.ResourceSlug,
+				err,
+			)
+		}
+		offenders = append(offenders, resourceOffenders...)
+		checked += resourceChecked
+	}
+
+	if checked == 0 {
+		return errors.New(
+			"no required percentage found; use '--min' or set " +
+				"'required_perc' in the config file",
+		)
+	}
+
+	if len(offenders) == 0 {
+		fmt.Println(color.GreenString(
+			"All %d checked language(s) meet the required completion",
+			checked,
+		))
+		return nil
+	}
+
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "RESOURCE\tLANGUAGE\tMODE\tCOMPLETION\tREQUIRED")
+	for _, offender := range offenders {
+		fmt.Fprintf(
+			writer,
+			"%s\t%s\t%s\t%s\t%d%%\n",
+			offender.resource,
+			offender.language,
+			offender.mode,
+			offender.completion,
+			offender.required,
+		)
+	}
+	writer.Flush()
+
+	return &CheckFailedError{Offenders: len(offenders), Checked: checked}
+}
+
+// Check the languages of one resource, returning the offenders and how many
+// languages were checked
+func checkResource(
+	cfg *config.Config,
+	api *jsonapi.Connection,
+	cfgResource *config.Resource,
+	arguments *CheckCommandArguments,
+) ([]checkOffender, int, error) {
+	sourceLanguage, err := getSourceLanguage(cfg, api, cfgResource)
+	if err != nil {
+		return nil, 0, err
+	}
+	stats, err := getStatusStats(api, cfgResource)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var remoteLanguageCodes []string
+	for languageId := range stats {
+		remoteLanguageCodes = append(
+			remoteLanguageCodes, strings.TrimPrefix(languageId, "l:"),
+		)
+	}
+	sort.Strings(remoteLanguageCodes)
+	localToRemote := makeLocalToRemoteLanguageMappings(*cfg, *cfgResource)
+	remoteToLocal := makeRemoteToLocalLanguageMappings(localToRemote)
+	// Languages given with '-l' may be local language codes
+	localLanguageCodes := append([]string{}, arguments.Languages...)
+	for localLanguageCode := range cfgResource.Overrides {
+		localLanguageCodes = append(localLanguageCodes, localLanguageCode)
+	}
+	sort.Strings(localLanguageCodes)
+	addConventionLanguageMappings(
+		*cfg, *cfgResource, localLanguageCodes, localToRemote, remoteToLocal,
+	)
+	addConventionRemoteLanguageMappings(
+		*cfg, *cfgResource, remoteLanguageCodes, localToRemote, remoteToLocal,
+	)
+
+	// The languages to check, as remote language codes
+	var languages []string
+	if len(arguments.Languages) > 0 {
+		for _, languageCode := range arguments.Languages {
+			remoteLanguageCode, exists := localToRemote[languageCode]
+			if !exists {
+				remoteLanguageCode = languageCode
+			}
+			languages = append(languages, remoteLanguageCode)
+		}
+	} else {
+		seen := make(map[string]bool)
+		for _, remoteLanguageCode := range remoteLanguageCodes {
+			if remoteLanguageCode != sourceLanguage {
+				seen[remoteLanguageCode] = true
+				languages = append(languages, remoteLanguageCode)
+			}
+		}
+		var configured []string
+		for languageCode := range cfgResource.LanguageRequiredPercentages {
+			remoteLanguageCode, exists := localToRemote[languageCode]
+			if !exists {
+				remoteLanguageCode = languageCode
+			}
+			if !seen[remoteLanguageCode] {
+				seen[remoteLanguageCode] = true
+				configured = append(configured, remoteLanguageCode)
+			}
+		}
+		sort.Strings(configured)
+		languages = append(languages, configured...)
+	}
+
+	resourceName := fmt.Sprintf(
+		"%s.%s", cfgResource.ProjectSlug, cfgResource.ResourceSlug,
+	)
+	pullArguments := &PullCommandArguments{
+		Mode:                  arguments.Mode,
+		MinimumPercentage:     -1,
+		MinimumPercentageUnit: arguments.MinimumPercentageUnit,
+	}
+	var offenders []checkOffender
+	checked := 0
+	for _, remoteLanguageCode := range languages {
+		localLanguageCode, exists := remoteToLocal[remoteLanguageCode]
+		if !exists {
+			localLanguageCode = remoteLanguageCode
+		}
+		required := getRequiredPercentage(
+			arguments, cfgResource, localLanguageCode, remoteLanguageCode,
+		)
+		if required <= 0 {
+			continue
+		}
+		checked++
+
+		mode, _, useWords := getPullPolicy(
+			pullArguments, cfgResource, localLanguageCode, remoteLanguageCode,
+		)
+		offender := checkOffender{
+			resource: resourceName,
+			language: localLanguageCode,
+			mode:     getCheckModeName(mode),
+			required: required,
+		}
+
+		stat, exists := stats[fmt.Sprintf("l:%s", remoteLanguageCode)]
+		if !exists {
+			offender.completion = "not in project"
+			offenders = append(offenders, offender)
+			continue
+		}
+		var attributes txapi.ResourceLanguageStatsAttributes
+		err := stat.MapAttributes(&attributes)
+		if err != nil {
+			return nil, 0, err
+		}
+		actedOnStrings, totalStrings := getActedOnStrings(
+			&attributes, mode, useWords,
+		)
+		if totalStrings == 0 {
+			// Nothing to translate
+			continue
+		}
+		if shouldSkipDueToStringPercentage(
+			required, actedOnStrings, totalStrings,
+		) {
+			offender.completion = fmt.Sprintf(
+				"%.1f%%",
+				getActedOnStringsPercentage(
+					float32(actedOnStrings), float32(totalStrings),
+				),
+			)
+			offenders = append(offenders, offender)
+		}
+	}
+	return offenders, checked, nil
+}
+
+/*
+The percentage a language needs to reach. The command line takes precedence,
+then the language's 'required_perc.<lang>' and then the resource's
+'required_perc'. 0 means the language is not checked.
+*/
+func getRequiredPercentage(
+	arguments *CheckCommandArguments, cfgResource *config.Resource,
+	localLanguageCode, remoteLanguageCode string,
+) int {
+	if arguments.MinimumPercentage != -1 {
+		return arguments.MinimumPercentage
+	}
+	required, exists :=
+		cfgResource.LanguageRequiredPercentages[localLanguageCode]
+	if !exists {
+		required, exists =
+			cfgResource.LanguageRequiredPercentages[remoteLanguageCode]
+	}
+	if exists {
+		return required
+	}
+	return cfgResource.RequiredPercentage
+}
+
+func getCheckModeName(mode string) string {
+	switch mode {
+	case "reviewed", "onlyreviewed":
+		return "reviewed"
+	case "proofread", "onlyproofread":
+		return "proofread"
+	}
+	return "translated"
+}
@@ -0,0 +1,130 @@
+package txlib
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/transifex/cli/pkg/assert"
+	"github.com/transifex/cli/pkg/jsonapi"
+)
+
+func getCheckStatsEndpoint() *jsonapi.MockEndpoint {
+	return jsonapi.GetMockTextResponse(fmt.Sprintf(
+		`{"data": [{"type": "resource_language_stats",
+		            "id": "%[1]s:l:en",
+		            "attributes": {"total_strings": 10,
+		                           "translated_strings": 10,
+		                           "reviewed_strings": 10},
+		            "relationships": {"language": {"data": {
+		              "type": "languages", "id": "l:en"}}}},
+		           {"type": "resource_language_stats",
+		            "id": "%[1]s:l:el",
+		            "attributes": {"total_strings": 10,
+		                           "translated_strings": 10,
+		                           "reviewed_strings": 2},
+		            "relationships": {"language": {"data": {
+		              "type": "languages", "id": "l:el"}}}},
+		           {"type": "resource_language_stats",
+		            "id": "%[1]s:l:de",
+		            "attributes": {"total_strings": 10,
+		                           "translated_strings": 5,
+		                           "reviewed_strings": 5},
+		            "relationships": {"language": {"data": {
+		              "type": "languages", "id": "l:de"}}}}]}`,
+		resourceId,
+	))
+}
+
+func runCheckCommand(arguments *CheckCommandArguments) (string, error) {
+	cfg := getStandardConfig()
+	cfg.Local.Resources[0].SourceLanguage = "en"
+	cfg.Local.Resources[0].LanguageRequiredPercentages = map[string]int{
+		"de": 50,
+	}
+	api := jsonapi.GetTestConnection(jsonapi.MockData{
+		resourceUrl:          getResourceEndpoint(),
+		statsUrlAllLanguages: getCheckStatsEndpoint(),
+	})
+
+	rescueStdout := os.Stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+
+	err := CheckCommand(cfg, &api, arguments)
+
+	w.Close()
+	out, _ := ioutil.ReadAll(r)
+	os.Stdout = rescueStdout
+	return string(out), err
+}
+
+func TestCheckCommandFailsBelowMinimum(t *testing.T) {
+	afterTest := beforeTest(t, nil, nil)
+	defer afterTest()
+
+	result, err := runCheckCommand(&CheckCommandArguments{
+		Languages:         []string{"el", "fr", "de"},
+		Mode:              "reviewed",
+		MinimumPercentage: 95,
+	})
+	if err == nil {
+		t.Fatal("Expected an error")
+	}
+	assert.Equal(
+		t,
+		err.Error(),
+		"3 of 3 checked language(s) are below the required completion",
+	)
+	var checkErr *CheckFailedError
+	assert.True(t, errors.As(err, &checkErr))
+	lines := strings.Split(strings.TrimSpace(result), "\n")
+	assert.Equal(t, len(lines), 5)
+	assert.Equal(
+		t,
+		strings.Join(strings.Fields(lines[2]), " "),
+		"projslug.resslug el reviewed 20.0% 95%",
+	)
+	assert.Equal(
+		t,
+		strings.Join(strings.Fields(lines[3]), " "),
+		"projslug.resslug fr reviewed not in project 95%",
+	)
+	assert.Equal(
+		t,
+		strings.Join(strings.Fields(lines[4]), " "),
+		"projslug.resslug de reviewed 50.0% 95%",
+	)
+}
+
+func TestCheckCommandUsesConfig(t *testing.T) {
+	afterTest := beforeTest(t, nil, nil)
+	defer afterTest()
+
+	// Only 'de' has a required percentage, which it meets
+	result, err := runCheckCommand(&CheckCommandArguments{
+		MinimumPercentage: -1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, strings.Contains(
+		result, "All 1 checked language(s) meet the required completion",
+	))
+}
+
+func TestCheckCommandWithoutThreshold(t *testing.T) {
+	afterTest := beforeTest(t, nil, nil)
+	defer afterTest()
+
+	_, err := runCheckCommand(&CheckCommandArguments{
+		Languages:         []string{"el"},
+		MinimumPercentage: -1,
+	})
+	if err == nil || !strings.Contains(err.Error(), "no required percentage") {
+		t.Errorf("Expected an error about missing thresholds, got %v", err)
+	}
+}
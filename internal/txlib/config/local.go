@@ -18,6 +18,167 @@ type LocalConfig struct {
 	LanguageMappings map[string]string
 	Resources        []Resource
 	Path             string
+	Hooks            Hooks
+	Fallbacks        map[string][]string
+	// How local language codes are written: "android", "apple", "bcp47" or
+	// "posix". Explicit language mappings take precedence
+	LanguageConvention string
+	BranchNaming       BranchNaming
+}
+
+var languageConventions = []string{"android", "apple", "bcp47", "posix"}
+
+func loadLanguageConvention(section *ini.Section) (string, error) {
+	convention := strings.ToLower(section.Key("lang_convention").String())
+	if convention == "" {
+		return "", nil
+	}
+	for _, known := range languageConventions {
+		if convention == known {
+			return convention, nil
+		}
+	}
+	return "", fmt.Errorf(
+		"'lang_convention' needs to be one of '%s', got '%s'",
+		strings.Join(languageConventions, "', '"), convention,
+	)
+}
+
+/*
+How the slugs of branch resources are made. 'Template' can use '<branch>' (the
+slugified branch name, cut to 'MaxLength' characters if that is set),
+'<branch_hash>' (the first 'HashLength' characters of the branch name's SHA-1)
+and '<resource_slug>'. An empty template means '<branch>--<resource_slug>'.
+*/
+type BranchNaming struct {
+	Template   string
+	MaxLength  int
+	HashLength int
+}
+
+const DefaultBranchTemplate = "<branch>--<resource_slug>"
+
+func loadBranchNaming(section *ini.Section) (BranchNaming, error) {
+	var result BranchNaming
+	result.Template = section.Key("branch_template").String()
+	if result.Template != "" {
+		if !strings.Contains(result.Template, "<resource_slug>") {
+			return result, errors.New(
+				"'branch_template' needs to contain '<resource_slug>'",
+			)
+		}
+		if !strings.Contains(result.Template, "<branch>") &&
+			!strings.Contains(result.Template, "<branch_hash>") {
+			return result, errors.New(
+				"'branch_template' needs to contain '<branch>' or " +
+					"'<branch_hash>'",
+			)
+		}
+	}
+	for _, item := range []struct {
+		key   string
+		value *int
+		max   int
+	}{
+		{"branch_max_length", &result.MaxLength, 0},
+		{"branch_hash_length", &result.HashLength, 40},
+	} {
+		if !section.HasKey(item.key) {
+			continue
+		}
+		value, err := section.Key(item.key).Int()
+		if err != nil || value < 1 {
+			return result, fmt.Errorf(
+				"'%s' needs to be a positive number, got '%s'",
+				item.key,
+				section.Key(item.key).String(),
+			)
+		}
+		if item.max != 0 && value > item.max {
+			return result, fmt.Errorf(
+				"'%s' can't be more than %d, got %d", item.key, item.max, value,
+			)
+		}
+		*item.value = value
+	}
+	return result, nil
+}
+
+func saveBranchNaming(section *ini.Section, naming BranchNaming) error {
+	for _, item := range []struct {
+		key   string
+		value string
+	}{
+		{"branch_template", naming.Template},
+		{"branch_max_length", formatPositive(naming.MaxLength)},
+		{"branch_hash_length", formatPositive(naming.HashLength)},
+	} {
+		if item.value == "" {
+			continue
+		}
+		_, err := section.NewKey(item.key, item.value)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func formatPositive(value int) string {
+	if value <= 0 {
+		return ""
+	}
+	return strconv.Itoa(value)
+}
+
+/*
+Shell commands to run around push and pull. They can be set in the main
+section, applying to every resource, or in a resource's section, replacing the
+main section's for that resource.
+*/
+type Hooks struct {
+	// Run before pushing a resource
+	PrePush string
+	// Run after all the files of a resource have been pulled
+	PostPull string
+	// Run after each pulled file
+	PostPullFile string
+}
+
+var hookKeys = []string{"pre_push", "post_pull", "post_pull_file"}
+
+func (hooks *Hooks) get(key string) *string {
+	switch key {
+	case "pre_push":
+		return &hooks.PrePush
+	case "post_pull":
+		return &hooks.PostPull
+	case "post_pull_file":
+		return &hooks.PostPullFile
+	}
+	return nil
+}
+
+func loadHooks(section *ini.Section) Hooks {
+	var hooks Hooks
+	for _, key := range hookKeys {
+		*hooks.get(key) = section.Key(key).String()
+	}
+	return hooks
+}
+
+func saveHooks(section *ini.Section, hooks Hooks) error {
+	for _, key := range hookKeys {
+		value := *hooks.get(key)
+		if value == "" {
+			continue
+		}
+		_, err := section.NewKey(key, value)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 type Resource struct {
@@ -34,6 +195,143 @@ type Resource struct {
 	ResourceName         string
 	ReplaceEditedStrings bool
 	KeepTranslations     bool
+	Hooks                Hooks
+	// Translation mode to pull with, unless given on the command line
+	Mode string
+	// Per-language overrides of Mode and MinimumPercentage, keyed by language
+	// code
+	LanguageModes              map[string]string
+	LanguageMinimumPercentages map[string]int
+	// Whether minimum percentages are computed on "strings" (the default) or
+	// "words"
+	MinimumPercentageUnit string
+	// Completion that 'tx check' requires, overall and per language code. 0
+	// means no requirement
+	RequiredPercentage          int
+	LanguageRequiredPercentages map[string]int
+	// Language code -> languages to fill missing translations from, in order
+	Fallbacks map[string][]string
+	// Line endings ("lf" or "crlf"), byte order mark and encoding ("utf-8",
+	// "utf-16", "utf-16le" or "utf-16be") that pulled files are converted to.
+	// If not set, files are saved as they are downloaded
+	Eol      string
+	Bom      *bool
+	Encoding string
+	// Glob patterns of paths that the file filter should not match
+	FileFilterExclude []string
+	// Overrides the main section's LanguageConvention
+	LanguageConvention string
+}
+
+// The translation modes that files can be pulled with
+var PullModes = []string{
+	"default", "reviewed", "proofread", "translator", "untranslated",
+	"onlytranslated", "onlyreviewed", "onlyproofread", "sourceastranslation",
+}
+
+// Read a translation mode, one of PullModes; empty means not set
+func loadPullMode(key *ini.Key) (string, error) {
+	value := key.String()
+	if value == "" {
+		return "", nil
+	}
+	for _, mode := range PullModes {
+		if value == mode {
+			return value, nil
+		}
+	}
+	return "", fmt.Errorf(
+		"'%s' needs to be one of '%s', got '%s'",
+		key.Name(),
+		strings.Join(PullModes, "', '"),
+		value,
+	)
+}
+
+// Read a percentage between 1 and 100
+func loadPercentage(key *ini.Key) (int, error) {
+	value, err := key.Int()
+	if err != nil {
+		return 0, fmt.Errorf("'%s' needs to be a number: %s", key.Name(), err)
+	}
+	if value < 1 || value > 100 {
+		return 0, fmt.Errorf(
+			"'%s' needs to be between 1 and 100, got %d", key.Name(), value,
+		)
+	}
+	return value, nil
+}
+
+// Split a comma-separated list, dropping empty items
+func parseList(value string) []string {
+	var result []string
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+/*
+Parse fallback chains in the form 'pt_BR: pt, en, es_MX: es'. Every item with a
+colon starts a new chain, the rest are appended to the current one.
+*/
+func parseFallbacks(value string) (map[string][]string, error) {
+	result := make(map[string][]string)
+	if strings.TrimSpace(value) == "" {
+		return result, nil
+	}
+	key := ""
+	for _, item := range strings.Split(value, ",") {
+		err := fmt.Errorf("invalid fallback '%s'", strings.TrimSpace(item))
+		language := strings.TrimSpace(item)
+		if strings.Contains(item, ":") {
+			split := strings.Split(item, ":")
+			if len(split) != 2 {
+				return nil, err
+			}
+			key = strings.TrimSpace(split[0])
+			language = strings.TrimSpace(split[1])
+			if key == "" {
+				return nil, err
+			}
+		}
+		if key == "" || language == "" {
+			return nil, err
+		}
+		result[key] = append(result[key], language)
+	}
+	return result, nil
+}
+
+func formatFallbacks(fallbacks map[string][]string) string {
+	var keys []string
+	for key := range fallbacks {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var chains []string
+	for _, key := range keys {
+		chains = append(chains, fmt.Sprintf(
+			"%s: %s", key, strings.Join(fallbacks[key], ", "),
+		))
+	}
+	return strings.Join(chains, ", ")
+}
+
+func fallbacksEqual(left, right map[string][]string) bool {
+	if len(left) != len(right) {
+		return false
+	}
+	for key, leftValue := range left {
+		rightValue, exists := right[key]
+		if !exists || strings.Join(leftValue, ",") != strings.Join(rightValue, ",") {
+			return false
+		}
+	}
+	return true
 }
 
 func loadLocalConfig() (*LocalConfig, error) {
@@ -83,6 +381,19 @@ func loadLocalConfigFromBytes(data []byte) (*LocalConfig, error) {
 	if result.Host == "" {
 		return nil, errors.New("local config's main section has no host")
 	}
+	result.Hooks = loadHooks(mainSection)
+	result.Fallbacks, err = parseFallbacks(mainSection.Key("fallback").String())
+	if err != nil {
+		return nil, err
+	}
+	result.LanguageConvention, err = loadLanguageConvention(mainSection)
+	if err != nil {
+		return nil, err
+	}
+	result.BranchNaming, err = loadBranchNaming(mainSection)
+	if err != nil {
+		return nil, err
+	}
 	languageMappings := mainSection.Key("lang_map").String()
 	if languageMappings != "" {
 		for _, mapping := range strings.Split(languageMappings, ",") {
@@ -144,19 +455,79 @@ func loadLocalConfigFromBytes(data []byte) (*LocalConfig, error) {
 		}
 
 		resource := Resource{
-			OrganizationSlug:     organizationSlug,
-			ProjectSlug:          projectSlug,
-			ResourceSlug:         resourceSlug,
-			FileFilter:           section.Key("file_filter").String(),
-			SourceFile:           section.Key("source_file").String(),
-			SourceLanguage:       section.Key("source_lang").String(),
-			Type:                 section.Key("type").String(),
-			LanguageMappings:     make(map[string]string),
-			Overrides:            make(map[string]string),
-			MinimumPercentage:    -1,
-			ResourceName:         section.Key("resource_name").String(),
-			ReplaceEditedStrings: replaceEditedStrings,
-			KeepTranslations:     keepTranslations,
+			OrganizationSlug:           organizationSlug,
+			ProjectSlug:                projectSlug,
+			ResourceSlug:               resourceSlug,
+			FileFilter:                 section.Key("file_filter").String(),
+			SourceFile:                 section.Key("source_file").String(),
+			SourceLanguage:             section.Key("source_lang").String(),
+			Type:                       section.Key("type").String(),
+			LanguageMappings:           make(map[string]string),
+			Overrides:                  make(map[string]string),
+			MinimumPercentage:          -1,
+			ResourceName:               section.Key("resource_name").String(),
+			ReplaceEditedStrings:       replaceEditedStrings,
+			KeepTranslations:           keepTranslations,
+			Hooks:                      loadHooks(section),
+			LanguageModes:              make(map[string]string),
+			LanguageMinimumPercentages: make(map[string]int),
+			MinimumPercentageUnit:      section.Key("minimum_perc_unit").String(),
+			FileFilterExclude:          parseList(section.Key("file_filter_exclude").String()),
+		}
+		resource.LanguageRequiredPercentages = make(map[string]int)
+
+		if section.HasKey("mode") {
+			resource.Mode, err = loadPullMode(section.Key("mode"))
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		resource.Fallbacks, err = parseFallbacks(section.Key("fallback").String())
+		if err != nil {
+			return nil, err
+		}
+
+		resource.LanguageConvention, err = loadLanguageConvention(section)
+		if err != nil {
+			return nil, err
+		}
+
+		resource.Eol = strings.ToLower(section.Key("eol").String())
+		if resource.Eol != "" && resource.Eol != "lf" && resource.Eol != "crlf" {
+			return nil, fmt.Errorf(
+				"'eol' needs to be 'lf' or 'crlf', got '%s'", resource.Eol,
+			)
+		}
+
+		if section.HasKey("bom") {
+			bom, err := section.Key("bom").Bool()
+			if err != nil {
+				return nil, fmt.Errorf(
+					"'bom' needs to be 'true' or 'false': %s", err,
+				)
+			}
+			resource.Bom = &bom
+		}
+
+		resource.Encoding = strings.ToLower(section.Key("encoding").String())
+		switch resource.Encoding {
+		case "", "utf-8", "utf-16", "utf-16le", "utf-16be":
+		default:
+			return nil, fmt.Errorf(
+				"'encoding' needs to be one of 'utf-8', 'utf-16', 'utf-16le' "+
+					"or 'utf-16be', got '%s'",
+				resource.Encoding,
+			)
+		}
+
+		if resource.MinimumPercentageUnit != "" &&
+			resource.MinimumPercentageUnit != "strings" &&
+			resource.MinimumPercentageUnit != "words" {
+			return nil, fmt.Errorf(
+				"'minimum_perc_unit' needs to be 'strings' or 'words', got '%s'",
+				resource.MinimumPercentageUnit,
+			)
 		}
 
 		// Get first the perc in string to check if exists because .Key returns
@@ -168,6 +539,15 @@ func loadLocalConfigFromBytes(data []byte) (*LocalConfig, error) {
 			}
 		}
 
+		if section.HasKey("required_perc") {
+			resource.RequiredPercentage, err = loadPercentage(
+				section.Key("required_perc"),
+			)
+			if err != nil {
+				return nil, err
+			}
+		}
+
 		languageMappings := section.Key("lang_map").String()
 		if languageMappings != "" {
 			for _, mapping := range strings.Split(languageMappings, ",") {
@@ -186,11 +566,33 @@ func loadLocalConfigFromBytes(data []byte) (*LocalConfig, error) {
 		}
 
 		for _, key := range section.Keys() {
-			if strings.Index(key.Name(), "trans.") != 0 {
-				continue
+			if strings.HasPrefix(key.Name(), "trans.") {
+				code := key.Name()[len("trans."):]
+				resource.Overrides[code] = key.String()
+			} else if strings.HasPrefix(key.Name(), "mode.") {
+				code := key.Name()[len("mode."):]
+				mode, err := loadPullMode(key)
+				if err != nil {
+					return nil, err
+				}
+				resource.LanguageModes[code] = mode
+			} else if strings.HasPrefix(key.Name(), "minimum_perc.") {
+				code := key.Name()[len("minimum_perc."):]
+				minimumPerc, err := key.Int()
+				if err != nil {
+					return nil, fmt.Errorf(
+						"'%s' needs to be a number: %s", key.Name(), err,
+					)
+				}
+				resource.LanguageMinimumPercentages[code] = minimumPerc
+			} else if strings.HasPrefix(key.Name(), "required_perc.") {
+				code := key.Name()[len("required_perc."):]
+				requiredPerc, err := loadPercentage(key)
+				if err != nil {
+					return nil, err
+				}
+				resource.LanguageRequiredPercentages[code] = requiredPerc
 			}
-			code := key.Name()[len("trans."):]
-			resource.Overrides[code] = key.String()
 		}
 
 		result.Resources = append(result.Resources, resource)
@@ -237,6 +639,26 @@ func (localCfg LocalConfig) saveToWriter(file io.Writer) error {
 			return err
 		}
 	}
+	err = saveHooks(main, localCfg.Hooks)
+	if err != nil {
+		return err
+	}
+	if len(localCfg.Fallbacks) != 0 {
+		_, err = main.NewKey("fallback", formatFallbacks(localCfg.Fallbacks))
+		if err != nil {
+			return err
+		}
+	}
+	if localCfg.LanguageConvention != "" {
+		_, err = main.NewKey("lang_convention", localCfg.LanguageConvention)
+		if err != nil {
+			return err
+		}
+	}
+	err = saveBranchNaming(main, localCfg.BranchNaming)
+	if err != nil {
+		return err
+	}
 
 	for _, resource := range localCfg.Resources {
 		section, err := cfg.NewSection(resource.Name())
@@ -251,6 +673,16 @@ func (localCfg LocalConfig) saveToWriter(file io.Writer) error {
 			}
 		}
 
+		if len(resource.FileFilterExclude) != 0 {
+			_, err := section.NewKey(
+				"file_filter_exclude",
+				strings.Join(resource.FileFilterExclude, ", "),
+			)
+			if err != nil {
+				return err
+			}
+		}
+
 		if resource.SourceFile != "" {
 			_, err := section.NewKey("source_file", resource.SourceFile)
 			if err != nil {
@@ -307,6 +739,95 @@ func (localCfg LocalConfig) saveToWriter(file io.Writer) error {
 			}
 		}
 
+		if resource.Mode != "" {
+			_, err := section.NewKey("mode", resource.Mode)
+			if err != nil {
+				return err
+			}
+		}
+
+		for key, value := range resource.LanguageModes {
+			_, err = section.NewKey(fmt.Sprintf("mode.%s", key), value)
+			if err != nil {
+				return err
+			}
+		}
+
+		for key, value := range resource.LanguageMinimumPercentages {
+			_, err = section.NewKey(
+				fmt.Sprintf("minimum_perc.%s", key), strconv.Itoa(value),
+			)
+			if err != nil {
+				return err
+			}
+		}
+
+		if resource.MinimumPercentageUnit != "" {
+			_, err := section.NewKey(
+				"minimum_perc_unit", resource.MinimumPercentageUnit,
+			)
+			if err != nil {
+				return err
+			}
+		}
+
+		if resource.RequiredPercentage != 0 {
+			_, err := section.NewKey(
+				"required_perc", strconv.Itoa(resource.RequiredPercentage),
+			)
+			if err != nil {
+				return err
+			}
+		}
+
+		for key, value := range resource.LanguageRequiredPercentages {
+			_, err = section.NewKey(
+				fmt.Sprintf("required_perc.%s", key), strconv.Itoa(value),
+			)
+			if err != nil {
+				return err
+			}
+		}
+
+		if len(resource.Fallbacks) != 0 {
+			_, err := section.NewKey(
+				"fallback", formatFallbacks(resource.Fallbacks),
+			)
+			if err != nil {
+				return err
+			}
+		}
+
+		if resource.LanguageConvention != "" {
+			_, err := section.NewKey(
+				"lang_convention", resource.LanguageConvention,
+			)
+			if err != nil {
+				return err
+			}
+		}
+
+		if resource.Eol != "" {
+			_, err := section.NewKey("eol", resource.Eol)
+			if err != nil {
+				return err
+			}
+		}
+
+		if resource.Bom != nil {
+			_, err := section.NewKey("bom", strconv.FormatBool(*resource.Bom))
+			if err != nil {
+				return err
+			}
+		}
+
+		if resource.Encoding != "" {
+			_, err := section.NewKey("encoding", resource.Encoding)
+			if err != nil {
+				return err
+			}
+		}
+
 		section.NewKey(
 			"replace_edited_strings", strconv.FormatBool(resource.ReplaceEditedStrings),
 		)
@@ -314,6 +835,11 @@ func (localCfg LocalConfig) saveToWriter(file io.Writer) error {
 		section.NewKey(
 			"keep_translations", strconv.FormatBool(resource.KeepTranslations),
 		)
+
+		err = saveHooks(section, resource.Hooks)
+		if err != nil {
+			return err
+		}
 	}
 
 	_, err = cfg.WriteTo(file)
@@ -346,6 +872,22 @@ func localConfigsEqual(left, right *LocalConfig) bool {
 		}
 	}
 
+	if left.LanguageConvention != right.LanguageConvention {
+		return false
+	}
+
+	if left.BranchNaming != right.BranchNaming {
+		return false
+	}
+
+	if left.Hooks != right.Hooks {
+		return false
+	}
+
+	if !fallbacksEqual(left.Fallbacks, right.Fallbacks) {
+		return false
+	}
+
 	if len(left.Resources) != len(right.Resources) {
 		return false
 	}
@@ -358,6 +900,10 @@ func localConfigsEqual(left, right *LocalConfig) bool {
 		if leftResource.FileFilter != rightResource.FileFilter {
 			return false
 		}
+		if strings.Join(leftResource.FileFilterExclude, ",") !=
+			strings.Join(rightResource.FileFilterExclude, ",") {
+			return false
+		}
 		if leftResource.SourceFile != rightResource.SourceFile {
 			return false
 		}
@@ -402,6 +948,64 @@ func localConfigsEqual(left, right *LocalConfig) bool {
 		if leftResource.ReplaceEditedStrings != rightResource.ReplaceEditedStrings {
 			return false
 		}
+
+		if leftResource.Hooks != rightResource.Hooks {
+			return false
+		}
+
+		if leftResource.Mode != rightResource.Mode {
+			return false
+		}
+		if len(leftResource.LanguageModes) != len(rightResource.LanguageModes) {
+			return false
+		}
+		for key, leftValue := range leftResource.LanguageModes {
+			rightValue, exists := rightResource.LanguageModes[key]
+			if !exists || leftValue != rightValue {
+				return false
+			}
+		}
+		if len(leftResource.LanguageMinimumPercentages) !=
+			len(rightResource.LanguageMinimumPercentages) {
+			return false
+		}
+		for key, leftValue := range leftResource.LanguageMinimumPercentages {
+			rightValue, exists := rightResource.LanguageMinimumPercentages[key]
+			if !exists || leftValue != rightValue {
+				return false
+			}
+		}
+		if leftResource.MinimumPercentageUnit != rightResource.MinimumPercentageUnit {
+			return false
+		}
+		if leftResource.RequiredPercentage != rightResource.RequiredPercentage {
+			return false
+		}
+		if len(leftResource.LanguageRequiredPercentages) !=
+			len(rightResource.LanguageRequiredPercentages) {
+			return false
+		}
+		for key, leftValue := range leftResource.LanguageRequiredPercentages {
+			rightValue, exists := rightResource.LanguageRequiredPercentages[key]
+			if !exists || leftValue != rightValue {
+				return false
+			}
+		}
+		if !fallbacksEqual(leftResource.Fallbacks, rightResource.Fallbacks) {
+			return false
+		}
+
+		if leftResource.LanguageConvention != rightResource.LanguageConvention {
+			return false
+		}
+		if leftResource.Eol != rightResource.Eol ||
+			leftResource.Encoding != rightResource.Encoding {
+			return false
+		}
+		if (leftResource.Bom == nil) != (rightResource.Bom == nil) ||
+			(leftResource.Bom != nil && *leftResource.Bom != *rightResource.Bom) {
+			return false
+		}
 	}
 
 	return true
@@ -486,6 +1090,46 @@ func findLocalPath(path string) (string, error) {
 	return filepath.Join(curDir, ".tx", "config"), nil
 }
 
+/*
+GetHooks Return the hooks that apply to a resource: the ones set in its own
+section, falling back to the ones set in the main section.
+*/
+func (localCfg *LocalConfig) GetHooks(resource *Resource) Hooks {
+	result := localCfg.Hooks
+	for _, key := range hookKeys {
+		value := *resource.Hooks.get(key)
+		if value != "" {
+			*result.get(key) = value
+		}
+	}
+	return result
+}
+
+/*
+GetFallbacks Return the languages to fill missing translations of 'language'
+from, as set in the resource's section or, failing that, in the main section.
+*/
+func (localCfg *LocalConfig) GetFallbacks(
+	resource *Resource, language string,
+) []string {
+	fallbacks, exists := resource.Fallbacks[language]
+	if exists {
+		return fallbacks
+	}
+	return localCfg.Fallbacks[language]
+}
+
+/*
+GetLanguageConvention Return the convention local language codes follow for
+the resource, as set in its section or, failing that, in the main section.
+*/
+func (localCfg *LocalConfig) GetLanguageConvention(resource *Resource) string {
+	if resource.LanguageConvention != "" {
+		return resource.LanguageConvention
+	}
+	return localCfg.LanguageConvention
+}
+
 func (localCfg *Resource) GetAPv3Id() string {
 	return fmt.Sprintf(
 		"o:%s:p:%s:r:%s",
@@ -2,6 +2,8 @@ package config
 
 import (
 	"bytes"
+	"fmt"
+	"strings"
 	"testing"
 )
 
@@ -184,3 +186,424 @@ func TestChangeSaveAndLoadLocalConfig(t *testing.T) {
 		)
 	}
 }
+
+func TestLocalConfigHooks(t *testing.T) {
+	localCfg, err := loadLocalConfigFromBytes([]byte(`
+[main]
+host = https://app.transifex.com
+post_pull_file = prettier --write "$TX_FILE"
+pre_push = make messages
+
+[o:org:p:proj:r:res]
+file_filter = locale/<lang>.json
+source_file = locale/en.json
+type = KEYVALUEJSON
+post_pull_file = ./format.sh
+post_pull = ./reindex.sh
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := Hooks{
+		PrePush:      "make messages",
+		PostPull:     "./reindex.sh",
+		PostPullFile: "./format.sh",
+	}
+	actual := localCfg.GetHooks(&localCfg.Resources[0])
+	if actual != expected {
+		t.Errorf("Hooks are wrong; got %+v, expected %+v", actual, expected)
+	}
+
+	var buffer bytes.Buffer
+	err = localCfg.saveToWriter(&buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newLocalCfg, err := loadLocalConfigFromBytes(buffer.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !localConfigsEqual(localCfg, newLocalCfg) {
+		t.Errorf(
+			"Local config is wrong; got %+v, expected %+v",
+			newLocalCfg,
+			localCfg,
+		)
+	}
+}
+
+func TestLocalConfigPullPolicies(t *testing.T) {
+	localCfg, err := loadLocalConfigFromBytes([]byte(`
+[main]
+host = https://app.transifex.com
+
+[o:org:p:proj:r:res]
+file_filter = locale/<lang>.json
+source_file = locale/en.json
+type = KEYVALUEJSON
+mode = translator
+mode.de = reviewed
+minimum_perc = 20
+minimum_perc.ja = 80
+minimum_perc_unit = words
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resource := localCfg.Resources[0]
+	if resource.Mode != "translator" ||
+		resource.LanguageModes["de"] != "reviewed" ||
+		resource.MinimumPercentage != 20 ||
+		resource.LanguageMinimumPercentages["ja"] != 80 ||
+		resource.MinimumPercentageUnit != "words" {
+		t.Errorf("Pull policies are wrong; got %+v", resource)
+	}
+
+	var buffer bytes.Buffer
+	err = localCfg.saveToWriter(&buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newLocalCfg, err := loadLocalConfigFromBytes(buffer.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !localConfigsEqual(localCfg, newLocalCfg) {
+		t.Errorf(
+			"Local config is wrong; got %+v, expected %+v",
+			newLocalCfg,
+			localCfg,
+		)
+	}
+
+	_, err = loadLocalConfigFromBytes([]byte(`
+[main]
+host = https://app.transifex.com
+
+[o:org:p:proj:r:res]
+minimum_perc_unit = characters
+`))
+	if err == nil {
+		t.Error("Expected an error for an invalid minimum_perc_unit")
+	}
+
+	for _, key := range []string{"mode", "mode.de"} {
+		_, err = loadLocalConfigFromBytes([]byte(fmt.Sprintf(`
+[main]
+host = https://app.transifex.com
+
+[o:org:p:proj:r:res]
+%s = reviwed
+`, key)))
+		if err == nil {
+			t.Errorf("Expected an error for an invalid %s", key)
+		}
+	}
+}
+
+func TestLocalConfigFallbacks(t *testing.T) {
+	localCfg, err := loadLocalConfigFromBytes([]byte(`
+[main]
+host = https://app.transifex.com
+fallback = pt_BR: pt, en, es_MX: es
+
+[o:org:p:proj:r:res]
+file_filter = locale/<lang>.json
+source_file = locale/en.json
+type = KEYVALUEJSON
+fallback = pt_BR: en
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resource := &localCfg.Resources[0]
+	if strings.Join(localCfg.GetFallbacks(resource, "pt_BR"), ",") != "en" {
+		t.Errorf("Wrong fallbacks for pt_BR: %v", localCfg.GetFallbacks(resource, "pt_BR"))
+	}
+	if strings.Join(localCfg.GetFallbacks(resource, "es_MX"), ",") != "es" {
+		t.Errorf("Wrong fallbacks for es_MX: %v", localCfg.GetFallbacks(resource, "es_MX"))
+	}
+	if len(localCfg.GetFallbacks(resource, "fr")) != 0 {
+		t.Errorf("Wrong fallbacks for fr: %v", localCfg.GetFallbacks(resource, "fr"))
+	}
+
+	var buffer bytes.Buffer
+	err = localCfg.saveToWriter(&buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newLocalCfg, err := loadLocalConfigFromBytes(buffer.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !localConfigsEqual(localCfg, newLocalCfg) {
+		t.Errorf(
+			"Local config is wrong; got %+v, expected %+v",
+			newLocalCfg,
+			localCfg,
+		)
+	}
+
+	_, err = loadLocalConfigFromBytes([]byte(`
+[main]
+host = https://app.transifex.com
+fallback = pt, en
+`))
+	if err == nil {
+		t.Error("Expected an error for an invalid fallback")
+	}
+}
+
+func TestLocalConfigTextFormat(t *testing.T) {
+	localCfg, err := loadLocalConfigFromBytes([]byte(`
+[main]
+host = https://app.transifex.com
+
+[o:org:p:proj:r:res]
+file_filter = locale/<lang>.strings
+source_file = locale/en.strings
+type = STRINGS
+eol = LF
+bom = false
+encoding = utf-16
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resource := localCfg.Resources[0]
+	if resource.Eol != "lf" || resource.Encoding != "utf-16" ||
+		resource.Bom == nil || *resource.Bom {
+		t.Errorf("Wrong text format: %+v", resource)
+	}
+
+	var buffer bytes.Buffer
+	err = localCfg.saveToWriter(&buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newLocalCfg, err := loadLocalConfigFromBytes(buffer.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !localConfigsEqual(localCfg, newLocalCfg) {
+		t.Errorf(
+			"Local config is wrong; got %+v, expected %+v",
+			newLocalCfg,
+			localCfg,
+		)
+	}
+
+	for _, line := range []string{"eol = cr", "bom = maybe", "encoding = latin-1"} {
+		_, err = loadLocalConfigFromBytes([]byte(`
+[o:org:p:proj:r:res]
+file_filter = locale/<lang>.strings
+source_file = locale/en.strings
+type = STRINGS
+` + line + "\n"))
+		if err == nil {
+			t.Errorf("Expected an error for '%s'", line)
+		}
+	}
+}
+
+func TestLocalConfigFileFilterExclude(t *testing.T) {
+	localCfg, err := loadLocalConfigFromBytes([]byte(`
+[main]
+host = https://app.transifex.com
+
+[o:org:p:proj:r:res]
+file_filter = **/locale/<lang>/messages.json
+file_filter_exclude = node_modules, build/**,
+source_file = locale/en/messages.json
+type = KEYVALUEJSON
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exclude := localCfg.Resources[0].FileFilterExclude
+	if strings.Join(exclude, "|") != "node_modules|build/**" {
+		t.Errorf("Wrong exclude patterns: %v", exclude)
+	}
+
+	var buffer bytes.Buffer
+	err = localCfg.saveToWriter(&buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newLocalCfg, err := loadLocalConfigFromBytes(buffer.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !localConfigsEqual(localCfg, newLocalCfg) {
+		t.Errorf(
+			"Local config is wrong; got %+v, expected %+v",
+			newLocalCfg,
+			localCfg,
+		)
+	}
+}
+
+func TestLocalConfigLanguageConvention(t *testing.T) {
+	localCfg, err := loadLocalConfigFromBytes([]byte(`
+[main]
+host = https://app.transifex.com
+lang_convention = android
+
+[o:org:p:proj:r:res]
+file_filter = <lang>.lproj/Localizable.strings
+source_file = en.lproj/Localizable.strings
+type = STRINGS
+lang_convention = Apple
+
+[o:org:p:proj:r:res2]
+file_filter = res/values-<lang>/strings.xml
+source_file = res/values/strings.xml
+type = ANDROID
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if localCfg.GetLanguageConvention(&localCfg.Resources[0]) != "apple" {
+		t.Errorf("Wrong convention for res: %+v", localCfg.Resources[0])
+	}
+	if localCfg.GetLanguageConvention(&localCfg.Resources[1]) != "android" {
+		t.Errorf("Wrong convention for res2: %+v", localCfg.Resources[1])
+	}
+
+	var buffer bytes.Buffer
+	err = localCfg.saveToWriter(&buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newLocalCfg, err := loadLocalConfigFromBytes(buffer.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !localConfigsEqual(localCfg, newLocalCfg) {
+		t.Errorf(
+			"Local config is wrong; got %+v, expected %+v",
+			newLocalCfg,
+			localCfg,
+		)
+	}
+
+	_, err = loadLocalConfigFromBytes([]byte(`
+[main]
+host = https://app.transifex.com
+lang_convention = windows
+`))
+	if err == nil {
+		t.Error("Expected an error for an unknown convention")
+	}
+}
+
+func TestLocalConfigRequiredPercentages(t *testing.T) {
+	localCfg, err := loadLocalConfigFromBytes([]byte(`
+[main]
+host = https://app.transifex.com
+
+[o:org:p:proj:r:res]
+file_filter = locale/<lang>.po
+source_file = locale/en.po
+type = PO
+required_perc = 90
+required_perc.de = 100
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resource := localCfg.Resources[0]
+	if resource.RequiredPercentage != 90 ||
+		resource.LanguageRequiredPercentages["de"] != 100 {
+		t.Errorf("Wrong required percentages: %+v", resource)
+	}
+
+	var buffer bytes.Buffer
+	err = localCfg.saveToWriter(&buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newLocalCfg, err := loadLocalConfigFromBytes(buffer.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !localConfigsEqual(localCfg, newLocalCfg) {
+		t.Errorf(
+			"Local config is wrong; got %+v, expected %+v",
+			newLocalCfg,
+			localCfg,
+		)
+	}
+
+	_, err = loadLocalConfigFromBytes([]byte(`
+[main]
+host = https://app.transifex.com
+
+[o:org:p:proj:r:res]
+file_filter = locale/<lang>.po
+type = PO
+required_perc.de = 101
+`))
+	if err == nil {
+		t.Error("Expected an error for a percentage above 100")
+	}
+}
+
+func TestLocalConfigBranchNaming(t *testing.T) {
+	localCfg, err := loadLocalConfigFromBytes([]byte(`
+[main]
+host = https://app.transifex.com
+branch_template = <branch>-<branch_hash>--<resource_slug>
+branch_max_length = 20
+branch_hash_length = 6
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := BranchNaming{
+		Template:   "<branch>-<branch_hash>--<resource_slug>",
+		MaxLength:  20,
+		HashLength: 6,
+	}
+	if localCfg.BranchNaming != expected {
+		t.Errorf("Wrong branch naming: %+v", localCfg.BranchNaming)
+	}
+
+	var buffer bytes.Buffer
+	err = localCfg.saveToWriter(&buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newLocalCfg, err := loadLocalConfigFromBytes(buffer.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !localConfigsEqual(localCfg, newLocalCfg) {
+		t.Errorf(
+			"Local config is wrong; got %+v, expected %+v",
+			newLocalCfg,
+			localCfg,
+		)
+	}
+
+	for _, main := range []string{
+		"branch_template = <branch>",
+		"branch_template = <resource_slug>",
+		"branch_max_length = 0",
+		"branch_hash_length = 41",
+	} {
+		_, err = loadLocalConfigFromBytes([]byte(
+			"[main]\nhost = https://app.transifex.com\n" + main + "\n",
+		))
+		if err == nil {
+			t.Errorf("Expected an error for '%s'", main)
+		}
+	}
+}
@@ -1,11 +1,11 @@
 package txlib
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 
 	"github.com/fatih/color"
-	"github.com/gosimple/slug"
 	"github.com/transifex/cli/internal/txlib/config"
 	"github.com/transifex/cli/pkg/jsonapi"
 	"github.com/transifex/cli/pkg/txapi"
@@ -16,6 +16,23 @@ type DeleteCommandArguments struct {
 	Force       bool
 	Skip        bool
 	Branch      string
+	// Only list the resources that would be deleted
+	DryRun bool
+	// Delete without asking for confirmation
+	Yes bool
+	// Delete the resources of the configured projects that have no section in
+	// the config, instead of configured ones
+	Orphans bool
+}
+
+// A resource on Transifex that is about to be deleted
+type resourceToDelete struct {
+	cfgResource config.Resource
+	resource    *jsonapi.Resource
+	// -1 if the stats could not be fetched
+	translatedStrings int
+	// Whether to remove the resource's section from the config once deleted
+	inConfig bool
 }
 
 func DeleteCommand(
@@ -23,34 +40,113 @@ func DeleteCommand(
 	api jsonapi.Connection,
 	arguments *DeleteCommandArguments,
 ) error {
-	var cfgResources []*config.Resource
+	branch, err := figureOutBranch(arguments.Branch)
+	if err != nil {
+		return err
+	}
+	arguments.Branch = branch
+	fmt.Printf("# Initiating Delete\n\n")
 
-	if arguments.Branch == "-1" {
-		arguments.Branch = ""
-	} else if arguments.Branch == "" {
-		arguments.Branch = getGitBranch()
-		if arguments.Branch == "" {
-			fmt.Println("Couldn't find branch information")
+	var toDelete []resourceToDelete
+	// With '--skip', the number of resources left out because of errors
+	var failed int
+	if arguments.Orphans {
+		if len(arguments.ResourceIds) != 0 || arguments.Branch != "" {
+			return errors.New(
+				"'--orphans' can't be combined with resource ids or '--branch'",
+			)
 		}
+		toDelete, failed, err = findOrphanResources(&api, cfg, arguments)
+	} else {
+		toDelete, failed, err = findResourcesToDelete(&api, cfg, arguments)
+	}
+	if err != nil {
+		return err
+	}
+	// If there are no resources found stop
+	if len(toDelete) == 0 {
+		if failed > 0 {
+			return fmt.Errorf(
+				"nothing to delete; %d resource(s) were skipped because of "+
+					"errors",
+				failed,
+			)
+		} else if arguments.Orphans {
+			fmt.Println("No orphan resources found")
+		} else {
+			color.Red("Given resources not found in config file.")
+		}
+		return nil
+	}
+
+	printResourcesToDelete(toDelete)
+	if arguments.DryRun {
+		fmt.Printf("\n# %d resource(s) would be deleted\n", len(toDelete))
+		return nil
+	}
+	if !arguments.Yes {
+		confirmed, err := confirm(
+			fmt.Sprintf("Delete %d resource(s)", len(toDelete)),
+		)
+		if err != nil {
+			return err
+		}
+		if !confirmed {
+			fmt.Println("Delete was cancelled!")
+			return nil
+		}
+	}
+
+	var deleteErr error
+	for _, item := range toDelete {
+		err := txapi.DeleteResource(&api, item.resource)
+		if err != nil {
+			color.Red("Resource deletion for '%s' failed",
+				item.cfgResource.ResourceSlug)
+			if !arguments.Skip {
+				deleteErr = err
+				break
+			}
+			color.Red("%s", err)
+			continue
+		}
+		color.Green("Resource '%s' deleted", item.cfgResource.ResourceSlug)
+		if item.inConfig {
+			// Remove successful deletes from config
+			cfg.RemoveResource(item.cfgResource)
+		}
+	}
+
+	// Save even if a deletion failed, so that the resources deleted before it
+	// don't stay in the config
+	err = cfg.Save()
+	if err != nil {
+		return err
 	}
-	fmt.Printf("# Initiating Delete\n\n")
 
+	return deleteErr
+}
+
+// Find the configured resources that the resource ids refer to on Transifex
+func findResourcesToDelete(
+	api *jsonapi.Connection,
+	cfg *config.Config,
+	arguments *DeleteCommandArguments,
+) ([]resourceToDelete, int, error) {
+	var cfgResources []*config.Resource
 	for _, resourceId := range arguments.ResourceIds {
 
 		// Split resource id to check if it is a bulk delete
 		parts := strings.Split(resourceId, ".")
 		if len(parts) != 2 {
 			if !arguments.Skip {
-				return fmt.Errorf(
+				return nil, 0, fmt.Errorf(
 					"wrong resource id for %s. Aborting",
 					resourceId,
 				)
-			} else {
-				fmt.Printf(
-					"Wrong resource id for %s. Aborting",
-					resourceId,
-				)
 			}
+			color.Red("Wrong resource id for %s, skipping", resourceId)
+			continue
 		}
 
 		projectSlug := parts[0]
@@ -61,17 +157,17 @@ func DeleteCommand(
 			cfgResource := cfg.FindResource(resourceId)
 			if cfgResource == nil {
 				if !arguments.Skip {
-					return fmt.Errorf(
+					return nil, 0, fmt.Errorf(
 						"could not find resource '%s' in local configuration. Aborting",
 						resourceId,
 					)
-				} else {
-					fmt.Printf(
-						"could not find resource '%s' in local configuration.",
-						resourceId,
-					)
-					break
 				}
+				color.Red(
+					"Could not find resource '%s' in local configuration, "+
+						"skipping",
+					resourceId,
+				)
+				continue
 			}
 			cfgResources = append(cfgResources, cfgResource)
 		} else {
@@ -80,56 +176,181 @@ func DeleteCommand(
 		}
 
 	}
-	// If there are no resources found stop
-	if len(cfgResources) == 0 {
-		color.Red("Given resources not found in config file.")
-		return nil
-	}
 
-	// Delete each resource
+	var result []resourceToDelete
+	failed := 0
 	for _, item := range cfgResources {
-		// Delete Resource from Server
 		cfgResource := *item
-		if arguments.Branch != "" {
-			cfgResource.ResourceSlug = fmt.Sprintf("%s--%s",
-				slug.Make(arguments.Branch),
-				cfgResource.ResourceSlug)
-		}
-		err := deleteResource(&api, cfg, cfgResource, *arguments)
+		cfgResource.ResourceSlug = getBranchResourceSlug(
+			cfg.Local.BranchNaming, cfgResource.ResourceSlug, arguments.Branch,
+		)
+		item, err := getResourceToDelete(api, cfgResource, arguments)
 		if err != nil {
 			if !arguments.Skip {
-				return err
-			} else {
-				color.Red("Given resources not found in config file.")
+				return nil, 0, err
 			}
-		} else {
-			// Remove successful deletes from config
-			cfg.RemoveResource(cfgResource)
+			color.Red("%s", err)
+			failed++
+			continue
 		}
+		// Branch resources don't have sections of their own
+		item.inConfig = arguments.Branch == ""
+		result = append(result, *item)
 	}
+	return result, failed, nil
+}
 
-	err := cfg.Save()
+func getResourceToDelete(
+	api *jsonapi.Connection,
+	cfgResource config.Resource,
+	arguments *DeleteCommandArguments,
+) (*resourceToDelete, error) {
+	resource, project, err := findResourceToDelete(api, cfgResource)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	translatedStrings := getTranslatedStringsCount(api, project, resource)
+	err = checkTranslationsBeforeDelete(
+		cfgResource, translatedStrings, *arguments,
+	)
+	if err != nil {
+		return nil, err
 	}
+	return &resourceToDelete{
+		cfgResource:       cfgResource,
+		resource:          resource,
+		translatedStrings: translatedStrings,
+	}, nil
+}
 
-	return nil
+/*
+Find the resources on Transifex that belong to a project of the config but have
+no section in it, eg because the section was removed by hand. Branch resources
+of configured resources are not orphans; 'tx branch prune' takes care of them.
+*/
+func findOrphanResources(
+	api *jsonapi.Connection,
+	cfg *config.Config,
+	arguments *DeleteCommandArguments,
+) ([]resourceToDelete, int, error) {
+	// Project id -> a configured resource of the project
+	var projectIds []string
+	projects := make(map[string]config.Resource)
+	configured := make(map[string][]string)
+	for _, cfgResource := range cfg.Local.Resources {
+		projectId := fmt.Sprintf(
+			"o:%s:p:%s", cfgResource.OrganizationSlug, cfgResource.ProjectSlug,
+		)
+		if _, exists := projects[projectId]; !exists {
+			projects[projectId] = cfgResource
+			projectIds = append(projectIds, projectId)
+		}
+		configured[projectId] = append(
+			configured[projectId], cfgResource.ResourceSlug,
+		)
+	}
+
+	var result []resourceToDelete
+	failed := 0
+	for _, projectId := range projectIds {
+		project, err := txapi.GetProjectById(api, projectId)
+		if err != nil {
+			return nil, 0, err
+		}
+		if project == nil {
+			return nil, 0, fmt.Errorf("project '%s' not found", projectId)
+		}
+		resources, err := txapi.GetResources(api, project)
+		if err != nil {
+			return nil, 0, err
+		}
+		for _, resource := range resources {
+			var resourceAttributes txapi.ResourceAttributes
+			err := resource.MapAttributes(&resourceAttributes)
+			if err != nil {
+				return nil, 0, err
+			}
+			if isConfiguredResource(
+				cfg, resourceAttributes.Slug, configured[projectId],
+			) {
+				continue
+			}
+			cfgResource := config.Resource{
+				OrganizationSlug: projects[projectId].OrganizationSlug,
+				ProjectSlug:      projects[projectId].ProjectSlug,
+				ResourceSlug:     resourceAttributes.Slug,
+			}
+			translatedStrings := getTranslatedStringsCount(api, project, resource)
+			err = checkTranslationsBeforeDelete(
+				cfgResource, translatedStrings, *arguments,
+			)
+			if err != nil {
+				if !arguments.Skip {
+					return nil, 0, err
+				}
+				color.Red("%s", err)
+				failed++
+				continue
+			}
+			result = append(result, resourceToDelete{
+				cfgResource:       cfgResource,
+				resource:          resource,
+				translatedStrings: translatedStrings,
+			})
+		}
+	}
+	return result, failed, nil
 }
 
-func deleteResource(
-	api *jsonapi.Connection, cfg *config.Config, cfgResource config.Resource,
-	args DeleteCommandArguments,
-) error {
+// Whether 'resourceSlug' is one of 'resourceSlugs' or a branch of one of them
+func isConfiguredResource(
+	cfg *config.Config, resourceSlug string, resourceSlugs []string,
+) bool {
+	for _, mainResourceSlug := range resourceSlugs {
+		if resourceSlug == mainResourceSlug {
+			return true
+		}
+		pattern := getBranchResourceSlugPattern(
+			cfg.Local.BranchNaming, mainResourceSlug,
+		)
+		if pattern.MatchString(resourceSlug) {
+			return true
+		}
+	}
+	return false
+}
 
+func printResourcesToDelete(toDelete []resourceToDelete) {
+	fmt.Println("The following resources will be deleted:")
+	for _, item := range toDelete {
+		translations := "translations unknown"
+		if item.translatedStrings >= 0 {
+			translations = fmt.Sprintf(
+				"%d translated string(s)", item.translatedStrings,
+			)
+		}
+		fmt.Printf(
+			"  - %s.%s (%s)\n",
+			item.cfgResource.ProjectSlug,
+			item.cfgResource.ResourceSlug,
+			translations,
+		)
+	}
+}
+
+// Find a resource on Transifex, returning it and its project
+func findResourceToDelete(
+	api *jsonapi.Connection, cfgResource config.Resource,
+) (*jsonapi.Resource, *jsonapi.Resource, error) {
 	// Get Organization from Server
 	organization, err := txapi.GetOrganization(api,
 		cfgResource.OrganizationSlug)
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
 
 	if organization == nil {
-		return fmt.Errorf("organization '%s' not found",
+		return nil, nil, fmt.Errorf("organization '%s' not found",
 			cfgResource.OrganizationSlug)
 	}
 
@@ -137,11 +358,11 @@ func deleteResource(
 	project, err := txapi.GetProject(api, organization,
 		cfgResource.ProjectSlug)
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
 
 	if project == nil {
-		return fmt.Errorf("project '%s - %s' not found",
+		return nil, nil, fmt.Errorf("project '%s - %s' not found",
 			cfgResource.OrganizationSlug,
 			cfgResource.ProjectSlug)
 
@@ -150,40 +371,92 @@ func deleteResource(
 	// Get Resource from Server
 	resource, err := txapi.GetResource(api, project, cfgResource.ResourceSlug)
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
 
 	if resource == nil {
-		return fmt.Errorf("resource '%s - %s - %s' not found",
+		return nil, nil, fmt.Errorf("resource '%s - %s - %s' not found",
 			cfgResource.OrganizationSlug,
 			cfgResource.ProjectSlug,
 			cfgResource.ResourceSlug)
 	}
+	return resource, project, nil
+}
 
-	msg := fmt.Sprintf("Deleting resource '%s'",
-		cfgResource.ResourceSlug)
-	fmt.Println(msg)
+// Count the translated strings of a resource in all languages but the source
+// language, or return -1 if the stats are not available
+func getTranslatedStringsCount(
+	api *jsonapi.Connection, project, resource *jsonapi.Resource,
+) int {
+	remoteStats, err := txapi.GetResourceStats(api, resource, nil)
+	if err != nil {
+		return -1
+	}
+	sourceLanguageId := ""
+	sourceLanguageRelationship, exists := project.Relationships["source_language"]
+	if exists && sourceLanguageRelationship.DataSingular != nil {
+		sourceLanguageId = sourceLanguageRelationship.DataSingular.Id
+	}
+	result := 0
+	for languageId, remoteStat := range remoteStats {
+		if languageId == sourceLanguageId {
+			continue
+		}
+		var remoteStatAttributes txapi.ResourceLanguageStatsAttributes
+		err := remoteStat.MapAttributes(&remoteStatAttributes)
+		if err != nil {
+			return -1
+		}
+		result += remoteStatAttributes.TranslatedStrings
+	}
+	return result
+}
+
+// Refuse to delete a resource with translations, or whose translations could
+// not be counted ('translatedStrings' is -1), unless forced
+func checkTranslationsBeforeDelete(
+	cfgResource config.Resource,
+	translatedStrings int,
+	args DeleteCommandArguments,
+) error {
+	if args.Force {
+		return nil
+	}
+	if translatedStrings < 0 {
+		return fmt.Errorf(
+			"Aborting because the translations in %s could not be counted; "+
+				"use '--force' to delete anyway",
+			cfgResource.ResourceSlug,
+		)
+	}
+	if translatedStrings > 0 {
+		return fmt.Errorf("Aborting due to translations in %s",
+			cfgResource.ResourceSlug)
+	}
+	return nil
+}
+
+func deleteResource(
+	api *jsonapi.Connection, cfg *config.Config, cfgResource config.Resource,
+	args DeleteCommandArguments,
+) error {
+	resource, project, err := findResourceToDelete(api, cfgResource)
 	if err != nil {
 		return err
 	}
 
+	msg := fmt.Sprintf("Deleting resource '%s'",
+		cfgResource.ResourceSlug)
+	fmt.Println(msg)
+
 	if !args.Force {
-		remoteStats, _ := txapi.GetResourceStats(api, resource, nil)
-		for languageId := range remoteStats {
-			if languageId == project.
-				Relationships["source_language"].DataSingular.Id {
-				continue
-			}
-			var remoteStatAttributes txapi.ResourceLanguageStatsAttributes
-			err := remoteStats[languageId].MapAttributes(&remoteStatAttributes)
-			if err != nil {
-				return err
-			}
-			if remoteStatAttributes.TranslatedStrings > 0 {
-				msg := fmt.Sprintf("Aborting due to translations in %s",
-					cfgResource.ResourceSlug)
-				return fmt.Errorf(msg)
-			}
+		err = checkTranslationsBeforeDelete(
+			cfgResource,
+			getTranslatedStringsCount(api, project, resource),
+			args,
+		)
+		if err != nil {
+			return err
 		}
 	}
 
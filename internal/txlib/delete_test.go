@@ -2,7 +2,9 @@ package txlib
 
 import (
 	"fmt"
+	"io/ioutil"
 	"log"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
@@ -39,6 +41,7 @@ func TestDeleteSingleResource(t *testing.T) {
 		getStandardConfigDelete(),
 		*getStandardConfigDelete().FindResource("projslug.resslug"),
 		DeleteCommandArguments{
+			Branch:      "-1",
 			ResourceIds: []string{},
 		},
 	)
@@ -46,6 +49,37 @@ func TestDeleteSingleResource(t *testing.T) {
 		t.Errorf("%s", err)
 	}
 }
+func TestDeleteAbortedByUnknownTranslations(t *testing.T) {
+	mockData := getMockedDataForResourceDelete()
+	// The stats can't be fetched
+	delete(mockData, resourceLanguageStatsUrlDeleteCommand)
+	api := jsonapi.GetTestConnection(mockData)
+
+	err := deleteResource(
+		&api,
+		getStandardConfigDelete(),
+		*getStandardConfigDelete().FindResource("projslug.resslug"),
+		DeleteCommandArguments{Branch: "-1"},
+	)
+	if err == nil {
+		t.Fatal("Expected the deletion to be aborted")
+	}
+	assert.True(t, strings.Contains(err.Error(), "could not be counted"))
+
+	mockData = getMockedDataForResourceDelete()
+	delete(mockData, resourceLanguageStatsUrlDeleteCommand)
+	api = jsonapi.GetTestConnection(mockData)
+	err = deleteResource(
+		&api,
+		getStandardConfigDelete(),
+		*getStandardConfigDelete().FindResource("projslug.resslug"),
+		DeleteCommandArguments{Branch: "-1", Force: true},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
 func TestDeleteAbortedByTranslations(t *testing.T) {
 	mockData := getMockedDataForResourceDelete()
 	mockData[resourceLanguageStatsUrlDeleteCommand] = deleteGetResLangStatsEndpoint()
@@ -56,6 +90,7 @@ func TestDeleteAbortedByTranslations(t *testing.T) {
 		getStandardConfigDelete(),
 		*getStandardConfigDelete().FindResource("projslug.resslug"),
 		DeleteCommandArguments{
+			Branch:      "-1",
 			ResourceIds: []string{},
 		},
 	)
@@ -75,6 +110,7 @@ func TestDeleteForceFlag(t *testing.T) {
 		getStandardConfigDelete(),
 		*getStandardConfigDelete().FindResource("projslug.resslug"),
 		DeleteCommandArguments{
+			Branch:      "-1",
 			ResourceIds: []string{},
 			Force:       true,
 		},
@@ -96,6 +132,8 @@ func TestDeleteCommandResourceNotInConfig(t *testing.T) {
 		},
 		api,
 		&DeleteCommandArguments{
+			Yes:         true,
+			Branch:      "-1",
 			ResourceIds: []string{"a.b"},
 		},
 	)
@@ -136,6 +174,8 @@ func TestDeleteCommandMultipleDeletes(t *testing.T) {
 		&cfg,
 		api,
 		&DeleteCommandArguments{
+			Yes:         true,
+			Branch:      "-1",
 			ResourceIds: []string{"projslug.resslug", "projslug.resslug1"},
 		},
 	)
@@ -175,6 +215,8 @@ func TestDeleteCommandBulkDeletes(t *testing.T) {
 		&cfg,
 		api,
 		&DeleteCommandArguments{
+			Yes:         true,
+			Branch:      "-1",
 			ResourceIds: []string{"projslug.*"},
 		},
 	)
@@ -229,6 +271,8 @@ func TestDeleteUpdatesConfig(t *testing.T) {
 		&cfg,
 		api,
 		&DeleteCommandArguments{
+			Yes:         true,
+			Branch:      "-1",
 			ResourceIds: []string{"projslug.*"},
 		},
 	)
@@ -254,6 +298,7 @@ func TestForceWorks(t *testing.T) {
 		getStandardConfigDelete(),
 		*getStandardConfigDelete().FindResource("projslug.resslug"),
 		DeleteCommandArguments{
+			Branch:      "-1",
 			ResourceIds: []string{},
 			Force:       true,
 		},
@@ -295,6 +340,8 @@ func TestSkipWorks(t *testing.T) {
 		&cfg,
 		api,
 		&DeleteCommandArguments{
+			Yes:    true,
+			Branch: "-1",
 			ResourceIds: []string{"projslug.resslugdoesntexist",
 				"projslug.resslug", "projslug.resslug1"},
 		},
@@ -306,6 +353,8 @@ func TestSkipWorks(t *testing.T) {
 		&cfg,
 		api,
 		&DeleteCommandArguments{
+			Yes:    true,
+			Branch: "-1",
 			ResourceIds: []string{"projslug.resslugdoesntexist",
 				"projslug.resslug", "projslug.resslug1"},
 			Skip: true,
@@ -347,6 +396,7 @@ func TestDeleteBranchWorks(t *testing.T) {
 		&cfg,
 		api,
 		&DeleteCommandArguments{
+			Yes:         true,
 			ResourceIds: []string{"projslug.resslug", "projslug.resslug1"},
 			Branch:      "abranch",
 		},
@@ -356,6 +406,164 @@ func TestDeleteBranchWorks(t *testing.T) {
 	}
 }
 
+func getTwoResourcesDeleteConfig(t *testing.T, tmpDir string) config.Config {
+	cfg, err := config.LoadFromPaths("", filepath.Join(tmpDir, ".tx", "config"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.Local.Resources = getStandardConfigDelete().Local.Resources
+	return cfg
+}
+
+func TestDeleteDryRun(t *testing.T) {
+	var pkgDir, tmpDir = beforeDeleteTest(t)
+	defer afterDeleteTest(pkgDir, tmpDir)
+	cfg := getTwoResourcesDeleteConfig(t, tmpDir)
+
+	mockData := getMockedDataForResourceDelete()
+	api := jsonapi.GetTestConnection(mockData)
+	err := DeleteCommand(&cfg, api, &DeleteCommandArguments{
+		Branch:      "-1",
+		ResourceIds: []string{"projslug.*"},
+		DryRun:      true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, mockData[resourceUrlDeleteCommand].Count, 0)
+	assert.Equal(t, mockData[resource1UrlDeleteCommand].Count, 0)
+	assert.Equal(t, len(cfg.Local.Resources), 2)
+}
+
+func TestDeleteCancelled(t *testing.T) {
+	var pkgDir, tmpDir = beforeDeleteTest(t)
+	defer afterDeleteTest(pkgDir, tmpDir)
+	cfg := getTwoResourcesDeleteConfig(t, tmpDir)
+
+	defer func(original func(string) (bool, error)) {
+		confirm = original
+	}(confirm)
+	asked := ""
+	confirm = func(label string) (bool, error) {
+		asked = label
+		return false, nil
+	}
+
+	mockData := getMockedDataForResourceDelete()
+	api := jsonapi.GetTestConnection(mockData)
+	err := DeleteCommand(&cfg, api, &DeleteCommandArguments{
+		Branch:      "-1",
+		ResourceIds: []string{"projslug.*"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, asked, "Delete 2 resource(s)")
+	assert.Equal(t, mockData[resourceUrlDeleteCommand].Count, 0)
+	assert.Equal(t, len(cfg.Local.Resources), 2)
+}
+
+func TestDeleteSkipContinuesAfterMissingResource(t *testing.T) {
+	var pkgDir, tmpDir = beforeDeleteTest(t)
+	defer afterDeleteTest(pkgDir, tmpDir)
+	cfg := getTwoResourcesDeleteConfig(t, tmpDir)
+
+	mockData := getMockedDataForResourceDelete()
+	api := jsonapi.GetTestConnection(mockData)
+	err := DeleteCommand(&cfg, api, &DeleteCommandArguments{
+		Branch: "-1",
+		ResourceIds: []string{"wrongid", "projslug.resslugdoesntexist",
+			"projslug.resslug", "projslug.resslug1"},
+		Skip: true,
+		Yes:  true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, mockData[resourceUrlDeleteCommand].Count, 1)
+	assert.Equal(t, mockData[resource1UrlDeleteCommand].Count, 1)
+	assert.Equal(t, len(cfg.Local.Resources), 0)
+}
+
+func TestDeleteSkipFailsWhenEveryLookupFails(t *testing.T) {
+	var pkgDir, tmpDir = beforeDeleteTest(t)
+	defer afterDeleteTest(pkgDir, tmpDir)
+	cfg := getTwoResourcesDeleteConfig(t, tmpDir)
+
+	// Every request fails
+	api := jsonapi.GetTestConnection(jsonapi.MockData{})
+	err := DeleteCommand(&cfg, api, &DeleteCommandArguments{
+		Branch:      "-1",
+		ResourceIds: []string{"projslug.resslug", "projslug.resslug1"},
+		Skip:        true,
+		Yes:         true,
+	})
+	if err == nil {
+		t.Fatal("Expected the failed lookups to be reported")
+	}
+	assert.Equal(
+		t,
+		err.Error(),
+		"nothing to delete; 2 resource(s) were skipped because of errors",
+	)
+	assert.Equal(t, len(cfg.Local.Resources), 2)
+}
+
+func TestDeleteOrphans(t *testing.T) {
+	var pkgDir, tmpDir = beforeDeleteTest(t)
+	defer afterDeleteTest(pkgDir, tmpDir)
+	cfg := getTwoResourcesDeleteConfig(t, tmpDir)
+	cfg.Local.Resources = cfg.Local.Resources[:1]
+
+	orphanUrl := "/resources/o:orgslug:p:projslug:r:orphan"
+	orphanStatsUrl := "/resource_language_stats?" +
+		"filter%5Bproject%5D=o%3Aorgslug%3Ap%3Aprojslug&" +
+		"filter%5Bresource%5D=o%3Aorgslug%3Ap%3Aprojslug%3Ar%3Aorphan"
+	mockData := jsonapi.MockData{
+		"/projects/o:orgslug:p:projslug": getProjectEndpoint(),
+		resourcesUrlDeleteCommand: jsonapi.GetMockTextResponse(
+			`{"data": [
+				{"type": "resources", "id": "o:orgslug:p:projslug:r:resslug",
+				 "attributes": {"slug": "resslug"}},
+				{"type": "resources",
+				 "id": "o:orgslug:p:projslug:r:abranch--resslug",
+				 "attributes": {"slug": "abranch--resslug"}},
+				{"type": "resources", "id": "o:orgslug:p:projslug:r:orphan",
+				 "attributes": {"slug": "orphan"}}
+			]}`,
+		),
+		orphanStatsUrl: deleteGetResLangStatsEndpoint(),
+		orphanUrl:      jsonapi.GetMockTextResponse(""),
+	}
+
+	rescueStdout := os.Stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+
+	api := jsonapi.GetTestConnection(mockData)
+	err := DeleteCommand(&cfg, api, &DeleteCommandArguments{
+		Branch:  "-1",
+		Orphans: true,
+		Force:   true,
+		Yes:     true,
+	})
+
+	w.Close()
+	out, _ := ioutil.ReadAll(r)
+	os.Stdout = rescueStdout
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, strings.Contains(
+		string(out), "  - projslug.orphan (2 translated string(s))\n",
+	))
+	assert.True(t, !strings.Contains(string(out), "abranch--resslug"))
+	assert.Equal(t, mockData[orphanUrl].Count, 1)
+	assert.Equal(t, mockData[orphanUrl].Requests[0].Request.Method, "DELETE")
+	assert.Equal(t, len(cfg.Local.Resources), 1)
+}
+
 func getStandardConfigDelete() *config.Config {
 	return &config.Config{
 		Local: &config.LocalConfig{
@@ -552,22 +760,52 @@ func deleteGetResLangStatsEndpoint() *jsonapi.MockEndpoint {
 								                  "resource": {}}}]}`,
 	)
 }
+
+func getResLangStatsUrlDeleteCommand(resourceSlug string) string {
+	return "/resource_language_stats?" +
+		"filter%5Bproject%5D=o%3Aorgslug%3Ap%3Aprojslug&" +
+		"filter%5Bresource%5D=o%3Aorgslug%3Ap%3Aprojslug%3Ar%3A" +
+		url.QueryEscape(resourceSlug)
+}
+
+// Stats without translations, enough for a resource to be looked at a few
+// times
+func deleteGetNoTranslationsStatsEndpoint() *jsonapi.MockEndpoint {
+	var requests []jsonapi.MockRequest
+	for i := 0; i < 3; i++ {
+		requests = append(requests, jsonapi.MockRequest{
+			Response: jsonapi.MockResponse{Text: `{"data": []}`},
+		})
+	}
+	return &jsonapi.MockEndpoint{Requests: requests}
+}
+
 func getMockedDataForResourceDelete() jsonapi.MockData {
-	return jsonapi.MockData{
+	mockData := jsonapi.MockData{
 		"/organizations":          deleteGetOrganizationEndpoint(),
 		projectsUrlDeleteCommand:  deleteGetProjectsEndpoint(),
 		resourceUrlDeleteCommand:  deleteGetResourceEndpoint(),
 		resource1UrlDeleteCommand: deleteGetResourceEndpoint(),
 		resourcesUrlDeleteCommand: deleteGetResourcesEndpoint(),
 	}
+	for _, resourceSlug := range []string{"resslug", "resslug1"} {
+		mockData[getResLangStatsUrlDeleteCommand(resourceSlug)] =
+			deleteGetNoTranslationsStatsEndpoint()
+	}
+	return mockData
 }
 
 func getMockedDataForBranchResourceDelete() jsonapi.MockData {
-	return jsonapi.MockData{
+	mockData := jsonapi.MockData{
 		"/organizations":                deleteGetOrganizationEndpoint(),
 		projectsUrlDeleteCommand:        deleteGetProjectsEndpoint(),
 		resourceUrlBranchDeleteCommand:  deleteGetResourceBranchEndpoint(),
 		resource1UrlBranchDeleteCommand: deleteGetResourceBranch1Endpoint(),
 		resourcesUrlDeleteCommand:       deleteGetResourcesBranchEndpoint(),
 	}
+	for _, resourceSlug := range []string{"abranch--resslug", "abranch--resslug1"} {
+		mockData[getResLangStatsUrlDeleteCommand(resourceSlug)] =
+			deleteGetNoTranslationsStatsEndpoint()
+	}
+	return mockData
 }
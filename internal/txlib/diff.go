@@ -0,0 +1,267 @@
+package txlib
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+type diffOp struct {
+	kind byte // ' ', '-' or '+'
+	line string
+}
+
+/*
+Past this many removed and added lines, a diff isn't worth searching for and the
+changed part is shown as removed and added as a whole. This bounds both time
+and memory, eg when every line changed from CRLF to LF.
+*/
+const maxDiffEdits = 1000
+
+/*
+Compute the line operations that turn 'left' into 'right'. Common leading and
+trailing lines are trimmed and what remains is diffed with Myers' algorithm,
+which takes time and memory in proportion to the number of changes rather than
+to the product of the file lengths.
+*/
+func diffLines(left, right []string) []diffOp {
+	prefix := 0
+	for prefix < len(left) && prefix < len(right) && left[prefix] == right[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(left)-prefix && suffix < len(right)-prefix &&
+		left[len(left)-1-suffix] == right[len(right)-1-suffix] {
+		suffix++
+	}
+
+	var result []diffOp
+	for _, line := range left[:prefix] {
+		result = append(result, diffOp{' ', line})
+	}
+
+	a := left[prefix : len(left)-suffix]
+	b := right[prefix : len(right)-suffix]
+	if ops, ok := myersDiff(a, b, maxDiffEdits); ok {
+		result = append(result, ops...)
+	} else {
+		for _, line := range a {
+			result = append(result, diffOp{'-', line})
+		}
+		for _, line := range b {
+			result = append(result, diffOp{'+', line})
+		}
+	}
+
+	for _, line := range left[len(left)-suffix:] {
+		result = append(result, diffOp{' ', line})
+	}
+	return result
+}
+
+/*
+Find the shortest edit script from 'a' to 'b' with Myers' greedy algorithm.
+'v[k]' holds the furthest position in 'a' reached on diagonal 'k' (the
+position in 'a' minus the one in 'b'), and a copy of the diagonals in use is
+kept for every number of edits so that the path can be traced back. Returns
+false if more than 'maxEdits' edits are needed.
+*/
+func myersDiff(a, b []string, maxEdits int) ([]diffOp, bool) {
+	n, m := len(a), len(b)
+	maxD := n + m
+	if maxD > maxEdits {
+		maxD = maxEdits
+	}
+	offset := maxD + 1
+	v := make([]int, 2*maxD+3)
+	var trace [][]int
+	for d := 0; d <= maxD; d++ {
+		snapshot := make([]int, 2*d+1)
+		copy(snapshot, v[offset-d:offset+d+1])
+		trace = append(trace, snapshot)
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				return backtrackMyersDiff(a, b, trace), true
+			}
+		}
+	}
+	return nil, false
+}
+
+func backtrackMyersDiff(a, b []string, trace [][]int) []diffOp {
+	var reversed []diffOp
+	x, y := len(a), len(b)
+	for d := len(trace) - 1; d >= 0; d-- {
+		// 'trace[d]' holds the diagonals from -d to d as they were before
+		// step d
+		v := trace[d]
+		k := x - y
+		var previousK int
+		if k == -d || (k != d && v[k-1+d] < v[k+1+d]) {
+			previousK = k + 1
+		} else {
+			previousK = k - 1
+		}
+		previousX := 0
+		if d > 0 {
+			previousX = v[previousK+d]
+		}
+		previousY := previousX - previousK
+		for x > previousX && y > previousY {
+			reversed = append(reversed, diffOp{' ', a[x-1]})
+			x--
+			y--
+		}
+		if d == 0 {
+			break
+		}
+		if x == previousX {
+			reversed = append(reversed, diffOp{'+', b[y-1]})
+		} else {
+			reversed = append(reversed, diffOp{'-', a[x-1]})
+		}
+		x, y = previousX, previousY
+	}
+	result := make([]diffOp, len(reversed))
+	for i, op := range reversed {
+		result[len(reversed)-1-i] = op
+	}
+	return result
+}
+
+func splitLines(content string) []string {
+	if content == "" {
+		return nil
+	}
+	lines := strings.Split(content, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+/*
+Return a unified diff between 'leftContent' and 'rightContent' with 'context'
+lines of context around each change, along with the number of added and
+removed lines. An empty string is returned if there are no changes.
+*/
+func unifiedDiff(
+	leftName, rightName, leftContent, rightContent string, context int,
+) (string, int, int) {
+	ops := diffLines(splitLines(leftContent), splitLines(rightContent))
+
+	added, removed := 0, 0
+	var changes []int
+	for index, op := range ops {
+		if op.kind == '+' {
+			added++
+		} else if op.kind == '-' {
+			removed++
+		}
+		if op.kind != ' ' {
+			changes = append(changes, index)
+		}
+	}
+	if len(changes) == 0 {
+		return "", 0, 0
+	}
+
+	// Group changes that are close enough to share their context into hunks
+	type hunk struct{ start, end int }
+	var hunks []hunk
+	for _, index := range changes {
+		start := index - context
+		if start < 0 {
+			start = 0
+		}
+		end := index + context + 1
+		if end > len(ops) {
+			end = len(ops)
+		}
+		if len(hunks) > 0 && start <= hunks[len(hunks)-1].end {
+			hunks[len(hunks)-1].end = end
+		} else {
+			hunks = append(hunks, hunk{start, end})
+		}
+	}
+
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "--- %s\n+++ %s\n", leftName, rightName)
+
+	// Line numbers (0-based) in left and right at the start of 'ops'
+	leftLine, rightLine, position := 0, 0, 0
+	for _, h := range hunks {
+		for ; position < h.start; position++ {
+			if ops[position].kind != '+' {
+				leftLine++
+			}
+			if ops[position].kind != '-' {
+				rightLine++
+			}
+		}
+		leftCount, rightCount := 0, 0
+		var body strings.Builder
+		for ; position < h.end; position++ {
+			op := ops[position]
+			if op.kind != '+' {
+				leftCount++
+			}
+			if op.kind != '-' {
+				rightCount++
+			}
+			fmt.Fprintf(&body, "%c%s\n", op.kind, op.line)
+		}
+		fmt.Fprintf(
+			&builder, "@@ -%s +%s @@\n%s",
+			formatHunkRange(leftLine, leftCount),
+			formatHunkRange(rightLine, rightCount),
+			body.String(),
+		)
+		leftLine += leftCount
+		rightLine += rightCount
+	}
+	return builder.String(), added, removed
+}
+
+func formatHunkRange(start, count int) string {
+	if count == 0 {
+		// An empty range refers to the line before it
+		return fmt.Sprintf("%d,0", start)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
+
+func colorizeDiff(diff string) string {
+	var builder strings.Builder
+	for index, line := range splitLines(diff) {
+		switch {
+		case index < 2:
+			// The '---' and '+++' header lines
+			line = color.New(color.Bold).Sprint(line)
+		case strings.HasPrefix(line, "@@"):
+			line = color.New(color.FgCyan).Sprint(line)
+		case strings.HasPrefix(line, "+"):
+			line = color.New(color.FgGreen).Sprint(line)
+		case strings.HasPrefix(line, "-"):
+			line = color.New(color.FgRed).Sprint(line)
+		}
+		builder.WriteString(line + "\n")
+	}
+	return builder.String()
+}
@@ -0,0 +1,82 @@
+package txlib
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/transifex/cli/pkg/assert"
+)
+
+func TestUnifiedDiffNoChanges(t *testing.T) {
+	diff, added, removed := unifiedDiff("a", "b", "one\ntwo\n", "one\ntwo\n", 3)
+	assert.Equal(t, diff, "")
+	assert.Equal(t, added, 0)
+	assert.Equal(t, removed, 0)
+}
+
+func TestUnifiedDiffChangedLine(t *testing.T) {
+	left := "1\n2\n3\n4\n5\n6\n7\n8\n9\n"
+	right := "1\n2\n3\n4\nfive\n6\n7\n8\n9\n"
+	diff, added, removed := unifiedDiff("a/f", "b/f", left, right, 3)
+	assert.Equal(t, diff, "--- a/f\n+++ b/f\n@@ -2,7 +2,7 @@\n"+
+		" 2\n 3\n 4\n-5\n+five\n 6\n 7\n 8\n")
+	assert.Equal(t, added, 1)
+	assert.Equal(t, removed, 1)
+}
+
+func TestUnifiedDiffSeparateHunks(t *testing.T) {
+	left := "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\n"
+	right := "A\nb\nc\nd\ne\nf\ng\nh\ni\nj\nk\n"
+	diff, added, removed := unifiedDiff("a/f", "b/f", left, right, 1)
+	assert.Equal(t, diff, "--- a/f\n+++ b/f\n"+
+		"@@ -1,2 +1,2 @@\n-a\n+A\n b\n"+
+		"@@ -10 +10,2 @@\n j\n+k\n")
+	assert.Equal(t, added, 2)
+	assert.Equal(t, removed, 1)
+}
+
+func TestUnifiedDiffNewFile(t *testing.T) {
+	diff, added, removed := unifiedDiff("/dev/null", "b/f", "", "x\ny\n", 3)
+	assert.Equal(t, diff, "--- /dev/null\n+++ b/f\n@@ -0,0 +1,2 @@\n+x\n+y\n")
+	assert.Equal(t, added, 2)
+	assert.Equal(t, removed, 0)
+}
+
+func TestUnifiedDiffLargeFile(t *testing.T) {
+	var lines []string
+	for i := 0; i < 50000; i++ {
+		lines = append(lines, fmt.Sprintf("msgid \"string %d\"", i))
+	}
+	left := strings.Join(lines, "\n") + "\n"
+	lines[0] = "\"PO-Revision-Date: 2024-01-01\""
+	lines[49990] = "msgstr \"changed\""
+	right := strings.Join(lines, "\n") + "\n"
+
+	diff, added, removed := unifiedDiff("a/f", "b/f", left, right, 0)
+	assert.Equal(t, added, 2)
+	assert.Equal(t, removed, 2)
+	assert.Equal(t, strings.Count(diff, "@@ -"), 2)
+
+	// Every line differs, the whole file is replaced
+	crlf := strings.ReplaceAll(left, "\n", "\r\n")
+	_, added, removed = unifiedDiff("a/f", "b/f", left, crlf, 0)
+	assert.Equal(t, added, 50000)
+	assert.Equal(t, removed, 50000)
+}
+
+func TestDiffLinesReconstructsBothSides(t *testing.T) {
+	left := strings.Split("a b c a b b a x y", " ")
+	right := strings.Split("c b a b a c y z", " ")
+	var gotLeft, gotRight []string
+	for _, op := range diffLines(left, right) {
+		if op.kind != '+' {
+			gotLeft = append(gotLeft, op.line)
+		}
+		if op.kind != '-' {
+			gotRight = append(gotRight, op.line)
+		}
+	}
+	assert.Equal(t, strings.Join(gotLeft, " "), strings.Join(left, " "))
+	assert.Equal(t, strings.Join(gotRight, " "), strings.Join(right, " "))
+}
@@ -0,0 +1,524 @@
+package txlib
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+/*
+Fill the translations that are missing or empty in 'target' with the ones found
+in 'fallbacks', trying each fallback in order. 'fileType' is the i18n type of
+the resource. Return the new content of 'target' and the number of keys that
+were filled.
+*/
+func fillFromFallbacks(
+	fileType string, target []byte, fallbacks [][]byte,
+) ([]byte, int, error) {
+	switch fileType {
+	case "KEYVALUEJSON":
+		return fillJsonFromFallbacks(target, fallbacks, false)
+	case "STRUCTURED_JSON":
+		return fillJsonFromFallbacks(target, fallbacks, true)
+	case "YML", "YML_KEY", "YAML_GENERIC":
+		return fillYamlFromFallbacks(target, fallbacks, fileType == "YML")
+	case "ANDROID":
+		return fillAndroidFromFallbacks(target, fallbacks)
+	}
+	return nil, 0, fmt.Errorf(
+		"language fallbacks are not supported for '%s' files", fileType,
+	)
+}
+
+func supportsFallbacks(fileType string) bool {
+	switch fileType {
+	case "KEYVALUEJSON", "STRUCTURED_JSON", "YML", "YML_KEY", "YAML_GENERIC",
+		"ANDROID":
+		return true
+	}
+	return false
+}
+
+// JSON
+
+/*
+JSON object that remembers the order of its keys, so that filled files keep
+the layout of the downloaded ones.
+*/
+type jsonObject struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+func decodeOrderedJson(data []byte) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	value, err := decodeOrderedJsonValue(decoder)
+	if err != nil {
+		return nil, err
+	}
+	_, err = decoder.Token()
+	if err != io.EOF {
+		return nil, errors.New("unexpected content after JSON value")
+	}
+	return value, nil
+}
+
+func decodeOrderedJsonValue(decoder *json.Decoder) (interface{}, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch token {
+	case json.Delim('{'):
+		object := &jsonObject{values: make(map[string]interface{})}
+		for decoder.More() {
+			keyToken, err := decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+			key := keyToken.(string)
+			value, err := decodeOrderedJsonValue(decoder)
+			if err != nil {
+				return nil, err
+			}
+			if _, exists := object.values[key]; !exists {
+				object.keys = append(object.keys, key)
+			}
+			object.values[key] = value
+		}
+		_, err = decoder.Token()
+		return object, err
+	case json.Delim('['):
+		var array []interface{}
+		for decoder.More() {
+			value, err := decodeOrderedJsonValue(decoder)
+			if err != nil {
+				return nil, err
+			}
+			array = append(array, value)
+		}
+		_, err = decoder.Token()
+		return array, err
+	}
+	return token, nil
+}
+
+func encodeOrderedJson(
+	buffer *bytes.Buffer, value interface{}, indent, prefix string,
+) {
+	switch typed := value.(type) {
+	case *jsonObject:
+		if len(typed.keys) == 0 {
+			buffer.WriteString("{}")
+			return
+		}
+		buffer.WriteString("{\n")
+		for index, key := range typed.keys {
+			encodedKey, _ := json.Marshal(key)
+			buffer.WriteString(prefix + indent)
+			buffer.Write(encodedKey)
+			buffer.WriteString(": ")
+			encodeOrderedJson(buffer, typed.values[key], indent, prefix+indent)
+			if index < len(typed.keys)-1 {
+				buffer.WriteString(",")
+			}
+			buffer.WriteString("\n")
+		}
+		buffer.WriteString(prefix + "}")
+	case []interface{}:
+		if len(typed) == 0 {
+			buffer.WriteString("[]")
+			return
+		}
+		buffer.WriteString("[\n")
+		for index, item := range typed {
+			buffer.WriteString(prefix + indent)
+			encodeOrderedJson(buffer, item, indent, prefix+indent)
+			if index < len(typed)-1 {
+				buffer.WriteString(",")
+			}
+			buffer.WriteString("\n")
+		}
+		buffer.WriteString(prefix + "]")
+	default:
+		// Avoid escaping characters like '<' and '&' that are common in
+		// translations
+		encoder := json.NewEncoder(buffer)
+		encoder.SetEscapeHTML(false)
+		encoder.Encode(typed)
+		// 'Encode' adds a newline
+		buffer.Truncate(buffer.Len() - 1)
+	}
+}
+
+/*
+Return the indentation used by a JSON file, based on its first indented line.
+*/
+func detectJsonIndent(data []byte) string {
+	for _, line := range strings.Split(string(data), "\n")[1:] {
+		trimmed := strings.TrimLeft(line, " \t")
+		if trimmed != "" {
+			if len(trimmed) == len(line) {
+				break
+			}
+			return line[:len(line)-len(trimmed)]
+		}
+	}
+	return "  "
+}
+
+func isEmptyJsonString(value interface{}) bool {
+	str, ok := value.(string)
+	return ok && str == ""
+}
+
+/*
+Return whether 'value' is a single STRUCTURED_JSON translation, ie an object
+with a 'string' key.
+*/
+func isStructuredJsonUnit(value interface{}) bool {
+	object, ok := value.(*jsonObject)
+	if !ok {
+		return false
+	}
+	_, exists := object.values["string"]
+	return exists
+}
+
+func countJsonUnits(value interface{}, structured bool) int {
+	if structured && isStructuredJsonUnit(value) {
+		if isEmptyJsonString(value.(*jsonObject).values["string"]) {
+			return 0
+		}
+		return 1
+	}
+	switch typed := value.(type) {
+	case *jsonObject:
+		count := 0
+		for _, key := range typed.keys {
+			count += countJsonUnits(typed.values[key], structured)
+		}
+		return count
+	case string:
+		if !structured && typed != "" {
+			return 1
+		}
+	}
+	return 0
+}
+
+func fillJsonObject(target, fallback *jsonObject, structured bool) int {
+	filled := 0
+	for _, key := range fallback.keys {
+		fallbackValue := fallback.values[key]
+		targetValue, exists := target.values[key]
+		if !exists {
+			units := countJsonUnits(fallbackValue, structured)
+			if units > 0 {
+				target.keys = append(target.keys, key)
+				target.values[key] = fallbackValue
+				filled += units
+			}
+			continue
+		}
+		if structured && isStructuredJsonUnit(targetValue) {
+			if !isStructuredJsonUnit(fallbackValue) {
+				continue
+			}
+			targetString := targetValue.(*jsonObject).values["string"]
+			fallbackString := fallbackValue.(*jsonObject).values["string"]
+			if isEmptyJsonString(targetString) &&
+				!isEmptyJsonString(fallbackString) {
+				targetValue.(*jsonObject).values["string"] = fallbackString
+				filled++
+			}
+			continue
+		}
+		targetObject, targetIsObject := targetValue.(*jsonObject)
+		fallbackObject, fallbackIsObject := fallbackValue.(*jsonObject)
+		if targetIsObject && fallbackIsObject {
+			filled += fillJsonObject(targetObject, fallbackObject, structured)
+		} else if !structured && isEmptyJsonString(targetValue) &&
+			countJsonUnits(fallbackValue, structured) > 0 {
+			target.values[key] = fallbackValue
+			filled++
+		}
+	}
+	return filled
+}
+
+func fillJsonFromFallbacks(
+	target []byte, fallbacks [][]byte, structured bool,
+) ([]byte, int, error) {
+	targetValue, err := decodeOrderedJson(target)
+	if err != nil {
+		return nil, 0, err
+	}
+	targetObject, ok := targetValue.(*jsonObject)
+	if !ok {
+		return nil, 0, errors.New("JSON file does not contain an object")
+	}
+
+	filled := 0
+	for _, fallback := range fallbacks {
+		fallbackValue, err := decodeOrderedJson(fallback)
+		if err != nil {
+			return nil, 0, err
+		}
+		fallbackObject, ok := fallbackValue.(*jsonObject)
+		if !ok {
+			return nil, 0, errors.New("JSON file does not contain an object")
+		}
+		filled += fillJsonObject(targetObject, fallbackObject, structured)
+	}
+	if filled == 0 {
+		return target, 0, nil
+	}
+
+	var buffer bytes.Buffer
+	encodeOrderedJson(&buffer, targetObject, detectJsonIndent(target), "")
+	buffer.WriteString("\n")
+	return buffer.Bytes(), filled, nil
+}
+
+// YAML
+
+func isEmptyYamlNode(node *yaml.Node) bool {
+	return node.Kind == yaml.ScalarNode &&
+		(node.Tag == "!!null" || (node.Tag == "!!str" && node.Value == ""))
+}
+
+func countYamlUnits(node *yaml.Node) int {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		if isEmptyYamlNode(node) {
+			return 0
+		}
+		return 1
+	case yaml.MappingNode:
+		count := 0
+		for index := 1; index < len(node.Content); index += 2 {
+			count += countYamlUnits(node.Content[index])
+		}
+		return count
+	}
+	// Sequences, eg plurals in some formats, are treated as a whole
+	if len(node.Content) > 0 {
+		return 1
+	}
+	return 0
+}
+
+func fillYamlMapping(target, fallback *yaml.Node) int {
+	filled := 0
+	for index := 0; index+1 < len(fallback.Content); index += 2 {
+		key := fallback.Content[index]
+		fallbackValue := fallback.Content[index+1]
+
+		var targetValue *yaml.Node
+		for targetIndex := 0; targetIndex+1 < len(target.Content); targetIndex += 2 {
+			if target.Content[targetIndex].Value == key.Value {
+				targetValue = target.Content[targetIndex+1]
+				break
+			}
+		}
+
+		if targetValue == nil {
+			units := countYamlUnits(fallbackValue)
+			if units > 0 {
+				target.Content = append(target.Content, key, fallbackValue)
+				filled += units
+			}
+		} else if targetValue.Kind == yaml.MappingNode &&
+			fallbackValue.Kind == yaml.MappingNode {
+			filled += fillYamlMapping(targetValue, fallbackValue)
+		} else if isEmptyYamlNode(targetValue) &&
+			countYamlUnits(fallbackValue) > 0 {
+			*targetValue = *fallbackValue
+			filled++
+		}
+	}
+	return filled
+}
+
+/*
+Return the mapping holding the translations of a YAML document. For Rails-style
+files ('rootIsLanguage'), this is the value of the single top-level key, which
+is the language code and so differs between the target and its fallbacks.
+*/
+func getYamlTranslations(document *yaml.Node, rootIsLanguage bool) (*yaml.Node, error) {
+	if document.Kind != yaml.DocumentNode || len(document.Content) == 0 ||
+		document.Content[0].Kind != yaml.MappingNode {
+		return nil, errors.New("YAML file does not contain a mapping")
+	}
+	root := document.Content[0]
+	if rootIsLanguage && len(root.Content) == 2 &&
+		root.Content[1].Kind == yaml.MappingNode {
+		return root.Content[1], nil
+	}
+	return root, nil
+}
+
+func fillYamlFromFallbacks(
+	target []byte, fallbacks [][]byte, rootIsLanguage bool,
+) ([]byte, int, error) {
+	var targetDocument yaml.Node
+	err := yaml.Unmarshal(target, &targetDocument)
+	if err != nil {
+		return nil, 0, err
+	}
+	targetTranslations, err := getYamlTranslations(&targetDocument, rootIsLanguage)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	filled := 0
+	for _, fallback := range fallbacks {
+		var fallbackDocument yaml.Node
+		err := yaml.Unmarshal(fallback, &fallbackDocument)
+		if err != nil {
+			return nil, 0, err
+		}
+		fallbackTranslations, err := getYamlTranslations(
+			&fallbackDocument, rootIsLanguage,
+		)
+		if err != nil {
+			return nil, 0, err
+		}
+		filled += fillYamlMapping(targetTranslations, fallbackTranslations)
+	}
+	if filled == 0 {
+		return target, 0, nil
+	}
+
+	var buffer bytes.Buffer
+	encoder := yaml.NewEncoder(&buffer)
+	encoder.SetIndent(2)
+	err = encoder.Encode(&targetDocument)
+	if err != nil {
+		return nil, 0, err
+	}
+	err = encoder.Close()
+	if err != nil {
+		return nil, 0, err
+	}
+	return buffer.Bytes(), filled, nil
+}
+
+// Android
+
+var androidElementRegex = regexp.MustCompile(
+	`(?s)<(string|plurals|string-array)\s[^>]*?\bname="([^"]*)"[^>]*?` +
+		`(?:/>|>(.*?)</(?:string|plurals|string-array)>)`,
+)
+
+type androidElement struct {
+	kind    string
+	name    string
+	content string
+	// Position of the element in the file
+	start, end int
+}
+
+func findAndroidElements(data string) []androidElement {
+	var result []androidElement
+	for _, match := range androidElementRegex.FindAllStringSubmatchIndex(data, -1) {
+		element := androidElement{
+			kind:  data[match[2]:match[3]],
+			name:  data[match[4]:match[5]],
+			start: match[0],
+			end:   match[1],
+		}
+		if match[6] != -1 {
+			element.content = data[match[6]:match[7]]
+		}
+		result = append(result, element)
+	}
+	return result
+}
+
+func (element androidElement) isEmpty() bool {
+	return strings.TrimSpace(element.content) == ""
+}
+
+func (element androidElement) text(data string) string {
+	return data[element.start:element.end]
+}
+
+func fillAndroidFromFallbacks(target []byte, fallbacks [][]byte) ([]byte, int, error) {
+	data := string(target)
+	if !strings.Contains(data, "</resources>") {
+		return nil, 0, errors.New("file is not an Android resources file")
+	}
+
+	filled := 0
+	for _, fallback := range fallbacks {
+		fallbackData := string(fallback)
+		targetElements := make(map[string]androidElement)
+		for _, element := range findAndroidElements(data) {
+			targetElements[element.kind+":"+element.name] = element
+		}
+
+		indent := "    "
+		elements := findAndroidElements(data)
+		if len(elements) > 0 {
+			lineStart := strings.LastIndex(data[:elements[0].start], "\n") + 1
+			if strings.TrimSpace(data[lineStart:elements[0].start]) == "" {
+				indent = data[lineStart:elements[0].start]
+			}
+		}
+
+		var replacements []androidElement
+		var additions []string
+		for _, element := range findAndroidElements(fallbackData) {
+			if element.isEmpty() ||
+				strings.Contains(element.text(fallbackData), `translatable="false"`) {
+				continue
+			}
+			targetElement, exists := targetElements[element.kind+":"+element.name]
+			if !exists {
+				additions = append(additions, element.text(fallbackData))
+			} else if targetElement.isEmpty() {
+				targetElement.content = element.text(fallbackData)
+				replacements = append(replacements, targetElement)
+			}
+		}
+
+		// Replace empty elements, last first so that positions stay valid
+		sort.Slice(replacements, func(i, j int) bool {
+			return replacements[i].start > replacements[j].start
+		})
+		for _, replacement := range replacements {
+			data = data[:replacement.start] + replacement.content +
+				data[replacement.end:]
+		}
+
+		if len(additions) > 0 {
+			closing := strings.LastIndex(data, "</resources>")
+			insertAt := strings.LastIndex(data[:closing], "\n") + 1
+			var builder strings.Builder
+			if strings.TrimSpace(data[insertAt:closing]) != "" {
+				// '</resources>' is not on its own line
+				insertAt = closing
+				builder.WriteString("\n")
+			}
+			for _, addition := range additions {
+				builder.WriteString(indent + addition + "\n")
+			}
+			data = data[:insertAt] + builder.String() + data[insertAt:]
+		}
+		filled += len(replacements) + len(additions)
+	}
+	if filled == 0 {
+		return target, 0, nil
+	}
+	return []byte(data), filled, nil
+}
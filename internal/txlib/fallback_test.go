@@ -0,0 +1,140 @@
+package txlib
+
+import (
+	"testing"
+
+	"github.com/transifex/cli/pkg/assert"
+)
+
+func TestFillKeyValueJsonFromFallbacks(t *testing.T) {
+	target := `{
+    "hello": "Olá",
+    "bye": "",
+    "nested": {
+        "one": ""
+    }
+}
+`
+	pt := `{"bye": "", "nested": {"one": "Um"}, "extra": "Extra pt"}`
+	en := `{"hello": "Hello", "bye": "Bye", "nested": {"one": "One", "two": "Two"}}`
+
+	result, filled, err := fillFromFallbacks(
+		"KEYVALUEJSON", []byte(target), [][]byte{[]byte(pt), []byte(en)},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, filled, 4)
+	assert.Equal(t, string(result), `{
+    "hello": "Olá",
+    "bye": "Bye",
+    "nested": {
+        "one": "Um",
+        "two": "Two"
+    },
+    "extra": "Extra pt"
+}
+`)
+}
+
+func TestFillStructuredJsonFromFallbacks(t *testing.T) {
+	target := `{
+  "hello": {"string": "", "context": "greeting"}
+}`
+	en := `{
+  "hello": {"string": "Hello", "context": "other"},
+  "bye": {"string": "Bye"}
+}`
+
+	result, filled, err := fillFromFallbacks(
+		"STRUCTURED_JSON", []byte(target), [][]byte{[]byte(en)},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, filled, 2)
+	assert.Equal(t, string(result), `{
+  "hello": {
+    "string": "Hello",
+    "context": "greeting"
+  },
+  "bye": {
+    "string": "Bye"
+  }
+}
+`)
+}
+
+func TestFillYamlFromFallbacks(t *testing.T) {
+	target := `pt-BR:
+  hello: Olá
+  bye: ""
+`
+	pt := `pt:
+  bye: Adeus
+  extra: Extra
+`
+
+	result, filled, err := fillFromFallbacks(
+		"YML", []byte(target), [][]byte{[]byte(pt)},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, filled, 2)
+	assert.Equal(t, string(result), `pt-BR:
+  hello: Olá
+  bye: Adeus
+  extra: Extra
+`)
+}
+
+func TestFillAndroidFromFallbacks(t *testing.T) {
+	target := `<?xml version="1.0" encoding="utf-8"?>
+<resources>
+  <string name="hello">Olá</string>
+  <string name="bye"></string>
+</resources>
+`
+	en := `<?xml version="1.0" encoding="utf-8"?>
+<resources>
+    <string name="hello">Hello</string>
+    <string name="bye">Bye</string>
+    <string name="app_id" translatable="false">com.example</string>
+    <plurals name="items">
+        <item quantity="one">One item</item>
+        <item quantity="other">%d items</item>
+    </plurals>
+</resources>
+`
+
+	result, filled, err := fillFromFallbacks(
+		"ANDROID", []byte(target), [][]byte{[]byte(en)},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, filled, 2)
+	assert.Equal(t, string(result), `<?xml version="1.0" encoding="utf-8"?>
+<resources>
+  <string name="hello">Olá</string>
+  <string name="bye">Bye</string>
+  <plurals name="items">
+        <item quantity="one">One item</item>
+        <item quantity="other">%d items</item>
+    </plurals>
+</resources>
+`)
+}
+
+func TestFillFromFallbacksNothingToFill(t *testing.T) {
+	target := `{"hello":"Olá"}`
+	result, filled, err := fillFromFallbacks(
+		"KEYVALUEJSON", []byte(target), [][]byte{[]byte(`{"hello": "Hello"}`)},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, filled, 0)
+	assert.Equal(t, string(result), target)
+}
@@ -157,6 +157,230 @@ func TestSearchFileWithTwoLangs(t *testing.T) {
 	}
 }
 
+func TestSearchFileFilterAndroidLayout(t *testing.T) {
+	afterTest := beforeFileFilterTest(t)
+	defer afterTest()
+
+	// <curDir>/
+	//   + values/strings.xml
+	//   + values-fr/strings.xml
+	//   + values-pt-rBR/strings.xml
+	//   + values-night/strings.xml
+	for _, dir := range []string{
+		"values", "values-fr", "values-pt-rBR", "values-night",
+	} {
+		err := os.Mkdir(dir, os.ModeDir|0755)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = os.WriteFile(filepath.Join(dir, "strings.xml"), nil, 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	curDir, err := os.Getwd()
+	if err != nil {
+		t.Error(err)
+	}
+	actual := searchFileFilter(
+		curDir, filepath.Join("values-<language>-r<region>", "strings.xml"),
+	)
+	expected := map[string]string{
+		"fr":    filepath.Join(curDir, "values-fr", "strings.xml"),
+		"pt_BR": filepath.Join(curDir, "values-pt-rBR", "strings.xml"),
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Got '%+v', expected '%+v'", actual, expected)
+	}
+}
+
+func TestSearchFileFilterAppleLayout(t *testing.T) {
+	afterTest := beforeFileFilterTest(t)
+	defer afterTest()
+
+	// <curDir>/
+	//   + pt-BR.lproj/Localizable.strings
+	//   + zh-Hans.lproj/Localizable.strings
+	//   + Base.lproj/Localizable.strings
+	for _, dir := range []string{"pt-BR.lproj", "zh-Hans.lproj", "Base.lproj"} {
+		err := os.Mkdir(dir, os.ModeDir|0755)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = os.WriteFile(
+			filepath.Join(dir, "Localizable.strings"), nil, 0644,
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	curDir, err := os.Getwd()
+	if err != nil {
+		t.Error(err)
+	}
+	actual := searchFileFilter(
+		curDir, filepath.Join("<lang_hyphen>.lproj", "Localizable.strings"),
+	)
+	expected := map[string]string{
+		"pt_BR":   filepath.Join(curDir, "pt-BR.lproj", "Localizable.strings"),
+		"zh_Hans": filepath.Join(curDir, "zh-Hans.lproj", "Localizable.strings"),
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Got '%+v', expected '%+v'", actual, expected)
+	}
+
+	actual = searchFileFilter(
+		curDir, filepath.Join("<lang_upper>.lproj", "Localizable.strings"),
+	)
+	if len(actual) != 0 {
+		t.Errorf("Got '%+v', expected nothing", actual)
+	}
+}
+
+func TestSearchFileFilterWildcards(t *testing.T) {
+	afterTest := beforeFileFilterTest(t)
+	defer afterTest()
+
+	// <curDir>/
+	//   + modules/app/locale/el/messages.json
+	//   + modules/app/locale/node_modules/messages.json
+	//   + modules/node_modules/dep/locale/fr/messages.json
+	//   + modules/.cache/locale/de/messages.json
+	//   + web/locale/fr/messages-v2.json
+	for _, path := range []string{
+		"modules/app/locale/el/messages.json",
+		"modules/app/locale/node_modules/messages.json",
+		"modules/node_modules/dep/locale/fr/messages.json",
+		"modules/.cache/locale/de/messages.json",
+		"web/locale/fr/messages-v2.json",
+	} {
+		err := os.MkdirAll(filepath.Dir(path), os.ModePerm)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = os.WriteFile(path, nil, 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	curDir, err := os.Getwd()
+	if err != nil {
+		t.Error(err)
+	}
+
+	actual := searchFileFilter(
+		curDir,
+		filepath.Join("modules", "**", "locale", "<lang>", "messages.json"),
+		"node_modules",
+	)
+	expected := map[string]string{
+		"el": filepath.Join(
+			curDir, "modules", "app", "locale", "el", "messages.json",
+		),
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Got '%+v', expected '%+v'", actual, expected)
+	}
+
+	actual = searchFileFilter(
+		curDir, filepath.Join("*", "locale", "<lang>", "messages*.json"),
+	)
+	expected = map[string]string{
+		"fr": filepath.Join(curDir, "web", "locale", "fr", "messages-v2.json"),
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Got '%+v', expected '%+v'", actual, expected)
+	}
+
+	actual = searchFileFilter(
+		curDir,
+		filepath.Join("**", "locale", "<lang>", "*.json"),
+		"modules/app/**",
+		"web",
+	)
+	expected = map[string]string{
+		"fr": filepath.Join(
+			curDir, "modules", "node_modules", "dep", "locale", "fr",
+			"messages.json",
+		),
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Got '%+v', expected '%+v'", actual, expected)
+	}
+}
+
+func TestCompileGlob(t *testing.T) {
+	assert.True(t, compileGlob("node_modules").MatchString("a/node_modules"))
+	assert.True(t, compileGlob("*.bak").MatchString("locale/fr.bak"))
+	assert.True(t, !compileGlob("node_modules").MatchString("node_modules2"))
+	assert.True(t, compileGlob("build/**").MatchString("build/a/b"))
+	assert.True(t, !compileGlob("build/**").MatchString("src/build/a"))
+	assert.True(t, compileGlob("**/test/*").MatchString("a/b/test/x"))
+	assert.True(t, compileGlob("**/test/*").MatchString("test/x"))
+}
+
+func TestExpandFileFilter(t *testing.T) {
+	filter := "values-<language>-r<region>/strings.xml"
+	assert.Equal(t, expandFileFilter(filter, "pt_BR"), "values-pt-rBR/strings.xml")
+	assert.Equal(t, expandFileFilter(filter, "fr"), "values-fr/strings.xml")
+	assert.Equal(t, expandFileFilter("<region>/<lang>.po", "fr"), "/fr.po")
+	assert.Equal(
+		t,
+		expandFileFilter(
+			"<lang>/<lang_hyphen>/<lang_lower>/<lang_upper>.txt", "pt_BR",
+		),
+		"pt_BR/pt-BR/pt_br/PT_BR.txt",
+	)
+	assert.Equal(
+		t,
+		expandFileFilter("<lang_underscore>.json", "zh-Hans"),
+		"zh_Hans.json",
+	)
+}
+
+func TestCanonicalLanguageCode(t *testing.T) {
+	assert.Equal(t, canonicalLanguageCode("PT-BR"), "pt_BR")
+	assert.Equal(t, canonicalLanguageCode("zh_hans_cn"), "zh_Hans_CN")
+	assert.Equal(t, canonicalLanguageCode("es-419"), "es_419")
+	assert.Equal(t, canonicalLanguageCode("fr"), "fr")
+}
+
+func TestPseudoFileFilter(t *testing.T) {
+	assert.Equal(t, pseudoFileFilter("locale/<lang>.po"), "locale/<lang>_pseudo.po")
+	assert.Equal(
+		t,
+		pseudoFileFilter("values-<language>-r<region>/strings.xml"),
+		"values-<language>-r<region>_pseudo/strings.xml",
+	)
+	assert.Equal(
+		t,
+		pseudoFileFilter("locale/<lang>/aaa-<lang>.json"),
+		"locale/<lang>_pseudo/aaa-<lang>_pseudo.json",
+	)
+}
+
+func TestValidateFileFilterRepeatedTokens(t *testing.T) {
+	err := validateFileFilter("locale/<language>-<language>.po")
+	if err == nil {
+		t.Error("Expected an error for a repeated placeholder")
+	}
+	err = validateFileFilter("<language>/<language>-<region>.po")
+	if err != nil {
+		t.Error(err)
+	}
+	err = validateFileFilter("src/**/<lang>.po")
+	if err != nil {
+		t.Error(err)
+	}
+	err = validateFileFilter("src/a**/<lang>.po")
+	if err == nil {
+		t.Error("Expected an error for '**' inside a part of the path")
+	}
+}
+
 func TestNormaliseFileFilterLinuxBased(t *testing.T) {
 	result := normaliseFileFilter("en/text.txt")
 	expected := filepath.Join("en", "text.txt")
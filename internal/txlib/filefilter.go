@@ -1,7 +1,9 @@
 package txlib
 
 import (
+	"fmt"
 	"os"
+	"regexp"
 	"strings"
 )
 
@@ -98,62 +100,190 @@ Examples:
     searchFileFilter("/path/to/root", "<lang>/file.txt")
     // map[string]string{"en": "/path/to/root/en/file.txt",
                          "fr": "/path/to/root/fr/file.txt"}
+
+The other placeholders (see 'fileFilterTokenPatterns') are matched the same
+way. Once a part of the path has been matched, the values it captured are
+filled into the rest of the file filter, and the language code is put
+together from them at the end, eg 'values-pt-rBR' matched against
+'values-<language>-r<region>' is returned under 'pt_BR'.
 */
 
-func searchFileFilter(root, fileFilter string) map[string]string {
+func searchFileFilter(root, fileFilter string, exclude ...string) map[string]string {
 	result := make(map[string]string)
+	search := fileFilterSearch{result: result}
+	for _, pattern := range exclude {
+		search.exclude = append(search.exclude, compileGlob(pattern))
+	}
+	search.searchParts(
+		root, "", normaliseFileFilter(fileFilter), make(map[string]string),
+	)
+	return result
+}
 
-	fileFilter = normaliseFileFilter(fileFilter)
+type fileFilterSearch struct {
+	exclude []*regexp.Regexp
+	result  map[string]string
+}
 
+// 'relativePath' is the path of 'root' relative to where the search started,
+// always with forward slashes, used to match the exclude patterns
+func (search *fileFilterSearch) searchParts(
+	root, relativePath, fileFilter string, captures map[string]string,
+) {
+	if search.isExcluded(relativePath) {
+		return
+	}
 	if len(fileFilter) == 0 {
 		fileInfo, err := os.Stat(root)
 		if err != nil || fileInfo.IsDir() {
-			return result
+			return
+		}
+		languageCode := getCapturedLanguageCode(captures)
+		// With wildcards, many files may match; keep the first one
+		if _, exists := search.result[languageCode]; !exists {
+			search.result[languageCode] = root
 		}
-		result[""] = root
-		return result
+		return
 	}
 	fileFilterSlice := strings.Split(fileFilter, PathSeparator)
-	if !strings.Contains(fileFilterSlice[0], "<lang>") {
-		// Recursively go deeper
-		newRoot := strings.Join([]string{root, fileFilterSlice[0]}, PathSeparator)
-		newFileFilter := strings.Join(fileFilterSlice[1:], PathSeparator)
-		return searchFileFilter(newRoot, newFileFilter)
-	} else {
-		parts := strings.Split(fileFilterSlice[0], "<lang>")
-		left := parts[0]
-		right := parts[1]
+	newFileFilter := strings.Join(fileFilterSlice[1:], PathSeparator)
 
+	if fileFilterSlice[0] == "**" {
+		// Zero directories
+		search.searchParts(root, relativePath, newFileFilter, captures)
+		// One or more directories; hidden ones are skipped
 		fileInfos, err := os.ReadDir(root)
 		if err != nil {
-			return result
+			return
 		}
 		for _, fileInfo := range fileInfos {
 			name := fileInfo.Name()
-			if len(name) < len(left)+len(right) ||
-				// before-fr-after
-				// ^^^^^^^
-				name[:len(left)] != left ||
-				// before-fr-after
-				//          ^^^^^^
-				name[len(name)-len(right):] != right {
+			if !fileInfo.IsDir() || strings.HasPrefix(name, ".") {
 				continue
 			}
-			languageCode := name[len(left) : len(name)-len(right)]
+			search.searchParts(
+				strings.Join([]string{root, name}, PathSeparator),
+				joinRelativePath(relativePath, name),
+				fileFilter,
+				captures,
+			)
+		}
+		return
+	}
 
-			newRoot := strings.Join([]string{root, name}, PathSeparator)
-			newFileFilter := strings.Join(fileFilterSlice[1:], PathSeparator)
-			// IT doesn't make sense to capture 'en/fr' with '<lang>/<lang>'
-			newFileFilter = strings.ReplaceAll(newFileFilter, "<lang>", languageCode)
-			answer := searchFileFilter(newRoot, newFileFilter)
+	items := parseFileFilter(fileFilterSlice[0])
+	values := getCapturedTokenValues(captures)
+
+	if !strings.Contains(fileFilterSlice[0], "*") {
+		if name, ok := expandFileFilterItems(items, values); ok {
+			// Recursively go deeper
+			search.searchParts(
+				strings.Join([]string{root, name}, PathSeparator),
+				joinRelativePath(relativePath, name),
+				newFileFilter,
+				captures,
+			)
+			return
+		}
+	}
 
-			path, exists := answer[""]
-			if exists {
-				result[languageCode] = path
+	pattern, groupTokens := compileFileFilterItems(items, values)
+	fileInfos, err := os.ReadDir(root)
+	if err != nil {
+		return
+	}
+	for _, fileInfo := range fileInfos {
+		name := fileInfo.Name()
+		match := pattern.FindStringSubmatch(name)
+		if match == nil {
+			continue
+		}
+		newCaptures := make(map[string]string)
+		for token, value := range captures {
+			newCaptures[token] = value
+		}
+		consistent := true
+		for i, token := range groupTokens {
+			value := match[i+1]
+			if previous, exists := newCaptures[token]; exists && previous != value {
+				// The same token appeared twice in the part with different
+				// values
+				consistent = false
+				break
 			}
+			newCaptures[token] = value
+		}
+		if !consistent {
+			continue
+		}
+		search.searchParts(
+			strings.Join([]string{root, name}, PathSeparator),
+			joinRelativePath(relativePath, name),
+			newFileFilter,
+			newCaptures,
+		)
+	}
+}
+
+func joinRelativePath(relativePath, name string) string {
+	if relativePath == "" || relativePath == "." {
+		return name
+	}
+	return relativePath + "/" + name
+}
+
+func (search *fileFilterSearch) isExcluded(relativePath string) bool {
+	if relativePath == "" {
+		return false
+	}
+	for _, pattern := range search.exclude {
+		if pattern.MatchString(relativePath) {
+			return true
 		}
-		return result
 	}
+	return false
+}
+
+/*
+Turn an exclude pattern into a regular expression that matches relative paths
+with forward slashes. '*' matches anything within a part of the path, '**'
+matches any number of parts. A pattern without slashes, like 'node_modules' or
+'*.bak', matches a file or directory with that name anywhere.
+*/
+func compileGlob(pattern string) *regexp.Regexp {
+	pattern = strings.ReplaceAll(pattern, "\\", "/")
+	pattern = strings.Trim(pattern, "/")
+	var result strings.Builder
+	if !strings.Contains(pattern, "/") {
+		result.WriteString("(?:^|/)")
+	} else {
+		result.WriteString("^")
+		pattern = strings.TrimPrefix(pattern, "./")
+	}
+	for i := 0; i < len(pattern); i++ {
+		switch {
+		case strings.HasPrefix(pattern[i:], "**/"):
+			result.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(pattern[i:], "**"):
+			result.WriteString(".*")
+			i++
+		case pattern[i] == '*':
+			result.WriteString("[^/]*")
+		case pattern[i] == '?':
+			result.WriteString("[^/]")
+		default:
+			result.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		}
+	}
+	result.WriteString("$")
+	return regexp.MustCompile(result.String())
+}
+
+// Whether 'fileFilter' uses '*' or '**', in which case it cannot be used to
+// work out the path of a new file
+func hasFileFilterWildcards(fileFilter string) bool {
+	return strings.Contains(fileFilter, "*")
 }
 
 /**
@@ -171,3 +301,263 @@ func normaliseFileFilter(fileFilter string) string {
 	}
 	return fileFilter
 }
+
+/*
+Placeholders that can appear in file filters, with the patterns used to
+recognize them in the names of existing files. Using 'pt_BR' as an example:
+
+  - <lang>: the language code as it is, 'pt_BR'
+  - <lang_underscore>: 'pt_BR'
+  - <lang_hyphen>: 'pt-BR'
+  - <lang_lower>: 'pt_br'
+  - <lang_upper>: 'PT_BR'
+  - <language>: 'pt'
+  - <region>: 'BR'
+
+When a language has no region, <region> is left out along with the text that
+separates it from the previous placeholder, so that 'values-<language>-r<region>'
+becomes 'values-fr' for French and 'values-pt-rBR' for Brazilian Portuguese.
+*/
+var fileFilterTokenPatterns = map[string]string{
+	"lang":            `.+`,
+	"lang_underscore": `[A-Za-z]{2,3}(?:_[A-Za-z0-9]+)*`,
+	"lang_hyphen":     `[A-Za-z]{2,3}(?:-[A-Za-z0-9]+)*`,
+	"lang_lower":      `[a-z]{2,3}(?:[_-][a-z0-9]+)*`,
+	"lang_upper":      `[A-Z]{2,3}(?:[_-][A-Z0-9]+)*`,
+	"language":        `[A-Za-z]{2,3}`,
+	"region":          `[A-Za-z0-9]+(?:_[A-Za-z0-9]+)*`,
+}
+
+// Placeholders that hold the whole language code, in order of preference
+var fullLanguageTokens = []string{
+	"lang", "lang_underscore", "lang_hyphen", "lang_lower", "lang_upper",
+}
+
+var fileFilterTokenRegex = regexp.MustCompile(
+	`<(lang|lang_underscore|lang_hyphen|lang_lower|lang_upper|language|region)>`,
+)
+
+// A piece of a file filter; either literal text or a placeholder
+type fileFilterItem struct {
+	literal string
+	token   string
+}
+
+func parseFileFilter(fileFilter string) []fileFilterItem {
+	var items []fileFilterItem
+	position := 0
+	for _, match := range fileFilterTokenRegex.FindAllStringSubmatchIndex(fileFilter, -1) {
+		if match[0] > position {
+			items = append(items, fileFilterItem{literal: fileFilter[position:match[0]]})
+		}
+		items = append(items, fileFilterItem{token: fileFilter[match[2]:match[3]]})
+		position = match[1]
+	}
+	if position < len(fileFilter) {
+		items = append(items, fileFilterItem{literal: fileFilter[position:]})
+	}
+	return items
+}
+
+// Insert '_pseudo' after the last language placeholder of every part of
+// 'fileFilter', eg 'locale/<lang>.po' becomes 'locale/<lang>_pseudo.po'
+func pseudoFileFilter(fileFilter string) string {
+	var result strings.Builder
+	position := 0
+	matches := fileFilterTokenRegex.FindAllStringIndex(fileFilter, -1)
+	for i, match := range matches {
+		end := match[1]
+		if i+1 < len(matches) &&
+			!strings.ContainsAny(fileFilter[end:matches[i+1][0]], "/\\") {
+			// Not the last placeholder of this part
+			continue
+		}
+		result.WriteString(fileFilter[position:end])
+		result.WriteString("_pseudo")
+		position = end
+	}
+	result.WriteString(fileFilter[position:])
+	return result.String()
+}
+
+// Whether 'fileFilter' has a placeholder for the language
+func FileFilterHasLanguage(fileFilter string) bool {
+	return fileFilterTokenRegex.MatchString(fileFilter)
+}
+
+/*
+Replace the placeholders of 'fileFilter' with the values for 'languageCode',
+for example 'values-<language>-r<region>/strings.xml' with 'pt_BR' becomes
+'values-pt-rBR/strings.xml'
+*/
+func expandFileFilter(fileFilter, languageCode string) string {
+	result, _ := expandFileFilterItems(
+		parseFileFilter(fileFilter), getLanguageTokenValues(languageCode),
+	)
+	return result
+}
+
+// Join 'items' replacing placeholders with 'values'. The second return value
+// is false if a placeholder has no value
+func expandFileFilterItems(
+	items []fileFilterItem, values map[string]string,
+) (string, bool) {
+	var result strings.Builder
+	pending := ""
+	for _, item := range items {
+		if item.token == "" {
+			result.WriteString(pending)
+			pending = item.literal
+			continue
+		}
+		value, exists := values[item.token]
+		if !exists {
+			return "", false
+		}
+		if item.token == "region" && value == "" {
+			// Drop the separator in front of the missing region, but not
+			// anything before a path separator
+			index := strings.LastIndexAny(pending, "/\\")
+			pending = pending[:index+1]
+		}
+		result.WriteString(pending)
+		pending = ""
+		result.WriteString(value)
+	}
+	result.WriteString(pending)
+	return result.String(), true
+}
+
+// Make a regular expression that matches a single part of a path against
+// 'items'. Placeholders with a value in 'values' need to match it exactly; the
+// rest are captured in groups, listed in the returned slice
+func compileFileFilterItems(
+	items []fileFilterItem, values map[string]string,
+) (*regexp.Regexp, []string) {
+	var pattern strings.Builder
+	var groupTokens []string
+	pending := ""
+	pattern.WriteString("^")
+	for _, item := range items {
+		if item.token == "" {
+			pattern.WriteString(quoteFileFilterLiteral(pending))
+			pending = item.literal
+			continue
+		}
+		value, exists := values[item.token]
+		if exists {
+			if item.token == "region" && value == "" {
+				pending = ""
+			}
+			pattern.WriteString(quoteFileFilterLiteral(pending))
+			pattern.WriteString(regexp.QuoteMeta(value))
+		} else if item.token == "region" {
+			// The region and its separator are optional
+			pattern.WriteString(fmt.Sprintf(
+				"(?:%s(%s))?",
+				quoteFileFilterLiteral(pending),
+				fileFilterTokenPatterns[item.token],
+			))
+			groupTokens = append(groupTokens, item.token)
+		} else {
+			pattern.WriteString(quoteFileFilterLiteral(pending))
+			pattern.WriteString("(" + fileFilterTokenPatterns[item.token] + ")")
+			groupTokens = append(groupTokens, item.token)
+		}
+		pending = ""
+	}
+	pattern.WriteString(quoteFileFilterLiteral(pending))
+	pattern.WriteString("$")
+	return regexp.MustCompile(pattern.String()), groupTokens
+}
+
+// Escape literal text of a file filter, except for '*' which matches anything
+// within a part of the path
+func quoteFileFilterLiteral(literal string) string {
+	parts := strings.Split(literal, "*")
+	for i, part := range parts {
+		parts[i] = regexp.QuoteMeta(part)
+	}
+	return strings.Join(parts, ".*")
+}
+
+// Split a language code like 'pt_BR' or 'zh-Hans' into its language and region
+func splitLanguageCode(languageCode string) (string, string) {
+	index := strings.IndexAny(languageCode, "_-")
+	if index == -1 {
+		return languageCode, ""
+	}
+	return languageCode[:index],
+		strings.ReplaceAll(languageCode[index+1:], "-", "_")
+}
+
+func getLanguageTokenValues(languageCode string) map[string]string {
+	language, region := splitLanguageCode(languageCode)
+	underscore := strings.ReplaceAll(languageCode, "-", "_")
+	return map[string]string{
+		"lang":            languageCode,
+		"lang_underscore": underscore,
+		"lang_hyphen":     strings.ReplaceAll(languageCode, "_", "-"),
+		"lang_lower":      strings.ToLower(underscore),
+		"lang_upper":      strings.ToUpper(underscore),
+		"language":        language,
+		"region":          region,
+	}
+}
+
+/*
+Restore the usual casing of a language code that was written in a single case
+or with hyphens, eg 'PT-BR' becomes 'pt_BR' and 'zh_hans' becomes 'zh_Hans'
+*/
+func canonicalLanguageCode(languageCode string) string {
+	parts := strings.FieldsFunc(languageCode, func(r rune) bool {
+		return r == '_' || r == '-'
+	})
+	for i, part := range parts {
+		if i == 0 {
+			parts[i] = strings.ToLower(part)
+		} else if len(part) == 4 {
+			// Script, eg 'Hans'
+			parts[i] = strings.ToUpper(part[:1]) + strings.ToLower(part[1:])
+		} else if len(part) == 2 || len(part) == 3 {
+			// Region, eg 'BR' or '419'
+			parts[i] = strings.ToUpper(part)
+		}
+	}
+	return strings.Join(parts, "_")
+}
+
+func getCapturedFullLanguageCode(captures map[string]string) (string, bool) {
+	for _, token := range fullLanguageTokens {
+		value, exists := captures[token]
+		if !exists {
+			continue
+		}
+		if token == "lang" || token == "lang_underscore" {
+			return value, true
+		}
+		return canonicalLanguageCode(value), true
+	}
+	return "", false
+}
+
+// Work out the language code from the placeholder values captured while
+// searching; "" if there were no placeholders
+func getCapturedLanguageCode(captures map[string]string) string {
+	if languageCode, ok := getCapturedFullLanguageCode(captures); ok {
+		return languageCode
+	}
+	languageCode := captures["language"]
+	if region := captures["region"]; region != "" {
+		languageCode += "_" + region
+	}
+	return languageCode
+}
+
+// Values of the placeholders that are known from what has been captured so far
+func getCapturedTokenValues(captures map[string]string) map[string]string {
+	if languageCode, ok := getCapturedFullLanguageCode(captures); ok {
+		return getLanguageTokenValues(languageCode)
+	}
+	return captures
+}
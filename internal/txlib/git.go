@@ -1,7 +1,11 @@
 package txlib
 
 import (
+	"fmt"
+	"os"
 	"os/exec"
+	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -9,6 +13,58 @@ import (
 	"github.com/go-git/go-git/v5"
 )
 
+/*
+Environment variables that CI services set to the branch being built, in order
+of preference. Pull request variables come before the others because, for pull
+requests, the others hold the target branch or a merge ref. Values that are
+refs other than branches, like tags, are ignored.
+*/
+var ciBranchVariables = []string{
+	// GitHub Actions
+	"GITHUB_HEAD_REF", "GITHUB_REF",
+	// GitLab CI
+	"CI_MERGE_REQUEST_SOURCE_BRANCH_NAME", "CI_COMMIT_BRANCH",
+	// Jenkins
+	"CHANGE_BRANCH", "BRANCH_NAME",
+	// Bitbucket Pipelines
+	"BITBUCKET_BRANCH",
+	// Azure Pipelines
+	"SYSTEM_PULLREQUEST_SOURCEBRANCH", "BUILD_SOURCEBRANCH",
+	// CircleCI
+	"CIRCLE_BRANCH",
+}
+
+/*
+Figure out the branch being worked on. The TX_BRANCH environment variable takes
+precedence, then the variables set by CI services, since CI checkouts are
+usually at a detached HEAD, and finally git itself. Returns an empty string if
+the branch can't be determined.
+*/
+func getCurrentBranch() string {
+	if branch := os.Getenv("TX_BRANCH"); branch != "" {
+		return branch
+	}
+	if branch := getCIBranch(); branch != "" {
+		return branch
+	}
+	return getGitBranch()
+}
+
+func getCIBranch() string {
+	for _, variable := range ciBranchVariables {
+		value := strings.TrimSpace(os.Getenv(variable))
+		if strings.HasPrefix(value, "refs/heads/") {
+			value = strings.TrimPrefix(value, "refs/heads/")
+		} else if strings.HasPrefix(value, "refs/") {
+			continue
+		}
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
 func getGitBranch() string {
 	result := getGitBranchFromBinary()
 	if result != "" {
@@ -18,15 +74,12 @@ func getGitBranch() string {
 }
 
 func getGitBranchFromBinary() string {
-	out, err := exec.Command("git", "symbolic-ref", "HEAD").Output()
+	// Fails if HEAD is detached
+	out, err := exec.Command("git", "symbolic-ref", "--short", "HEAD").Output()
 	if err != nil {
 		return ""
 	}
-	parts := strings.Split(strings.TrimSpace(string(out)), "/")
-	if len(parts) == 0 {
-		return ""
-	}
-	return parts[len(parts)-1]
+	return strings.TrimSpace(string(out))
 }
 
 func getGitBranchFromGoGit() string {
@@ -35,7 +88,7 @@ func getGitBranchFromGoGit() string {
 		return ""
 	} else {
 		head, err := repo.Head()
-		if err != nil {
+		if err != nil || !head.Name().IsBranch() {
 			return ""
 		} else {
 			return head.Name().Short()
@@ -83,3 +136,163 @@ func getLastCommitDateFromGoGit(path string) time.Time {
 	}
 	return commit.Author.When
 }
+
+/*
+Return the absolute paths of the files that differ between the working tree and
+the point where 'ref' and HEAD diverged. Untracked files that are not ignored
+are included as well, since they are also "changed" from the point of view of
+a push. An error is returned if git cannot compute the changes, because a
+silently empty result would make us skip every resource.
+*/
+func getGitChangedFiles(ref string) ([]string, error) {
+	base := ref
+	out, err := exec.Command("git", "merge-base", ref, "HEAD").Output()
+	if err == nil {
+		base = strings.TrimSpace(string(out))
+	}
+
+	diffOut, err := exec.Command(
+		"git", "diff", "--name-only", "--relative", "-z", base, "--",
+	).Output()
+	if err != nil {
+		return nil, fmt.Errorf("could not find files changed since '%s': %w", ref, err)
+	}
+	untrackedOut, err := exec.Command(
+		"git", "ls-files", "--others", "--exclude-standard", "-z",
+	).Output()
+	if err != nil {
+		return nil, fmt.Errorf("could not find untracked files: %w", err)
+	}
+
+	var result []string
+	for _, out := range [][]byte{diffOut, untrackedOut} {
+		for _, path := range strings.Split(string(out), "\x00") {
+			if path == "" {
+				continue
+			}
+			absPath, err := filepath.Abs(path)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, absPath)
+		}
+	}
+	return result, nil
+}
+
+// Return the names of the local git branches
+func getGitBranches() ([]string, error) {
+	out, err := exec.Command(
+		"git", "for-each-ref", "--format=%(refname:short)", "refs/heads",
+	).Output()
+	if err != nil {
+		return nil, fmt.Errorf("could not list git branches: %w", err)
+	}
+	return strings.Fields(string(out)), nil
+}
+
+// Return the names of the local git branches and the commits they point to,
+// with a single git call
+func getGitBranchHeads() ([]string, map[string]string, error) {
+	out, err := exec.Command(
+		"git", "for-each-ref", "--format=%(objectname) %(refname:short)",
+		"refs/heads",
+	).Output()
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not list git branches: %w", err)
+	}
+	var branches []string
+	heads := make(map[string]string)
+	for _, line := range strings.Split(string(out), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		branches = append(branches, fields[1])
+		heads[fields[1]] = fields[0]
+	}
+	return branches, heads, nil
+}
+
+// Return the names of the branches that exist on a git remote, asking the
+// remote itself rather than relying on the last fetch
+func getGitRemoteBranches(remote string) ([]string, error) {
+	out, err := exec.Command("git", "ls-remote", "--heads", remote).Output()
+	if err != nil {
+		return nil, fmt.Errorf(
+			"could not list the branches of git remote '%s': %w", remote, err,
+		)
+	}
+	var result []string
+	for _, line := range strings.Split(string(out), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		result = append(result, strings.TrimPrefix(fields[1], "refs/heads/"))
+	}
+	return result, nil
+}
+
+/*
+Return the local branches, other than 'current', that HEAD was branched off,
+nearest first: the fewer commits of HEAD that aren't on a branch, the nearer it
+is. Branches that were branched off HEAD, and so contain it, are left out.
+*/
+func getGitAncestorBranches(current string) ([]string, error) {
+	out, err := exec.Command("git", "rev-parse", "HEAD").Output()
+	if err != nil {
+		return nil, fmt.Errorf("could not find the current git commit: %w", err)
+	}
+	head := strings.TrimSpace(string(out))
+	branches, branchHeads, err := getGitBranchHeads()
+	if err != nil {
+		return nil, err
+	}
+
+	// Branches often share merge-bases, eg all the ones based on the main
+	// branch, so the commits up to each merge-base are only counted once
+	counts := map[string]int{head: 0}
+	distances := make(map[string]int)
+	var result []string
+	for _, branch := range branches {
+		if branch == current {
+			continue
+		}
+		mergeBase := branchHeads[branch]
+		if mergeBase != head {
+			out, err := exec.Command(
+				"git", "merge-base", "HEAD", mergeBase,
+			).Output()
+			if err != nil {
+				// Unrelated history
+				continue
+			}
+			mergeBase = strings.TrimSpace(string(out))
+			if mergeBase == head {
+				// Branched off HEAD
+				continue
+			}
+		}
+		distance, exists := counts[mergeBase]
+		if !exists {
+			out, err := exec.Command(
+				"git", "rev-list", "--count", mergeBase+"..HEAD",
+			).Output()
+			if err != nil {
+				return nil, err
+			}
+			distance, err = strconv.Atoi(strings.TrimSpace(string(out)))
+			if err != nil {
+				return nil, err
+			}
+			counts[mergeBase] = distance
+		}
+		distances[branch] = distance
+		result = append(result, branch)
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return distances[result[i]] < distances[result[j]]
+	})
+	return result, nil
+}
@@ -0,0 +1,101 @@
+package txlib
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"github.com/transifex/cli/internal/txlib/config"
+)
+
+/*
+Run a hook command through the system's shell. Information about what the hook
+is run for is passed in the TX_RESOURCE, TX_LANGUAGE and TX_FILE environment
+variables. If the command fails, the returned error contains the last line of
+its output.
+*/
+func runHook(name, command, resourceId, languageCode, filePath string) error {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", command)
+	} else {
+		cmd = exec.Command("sh", "-c", command)
+	}
+	cmd.Env = append(
+		os.Environ(),
+		"TX_HOOK="+name,
+		"TX_RESOURCE="+resourceId,
+		"TX_LANGUAGE="+languageCode,
+		"TX_FILE="+filePath,
+	)
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+
+	err := cmd.Run()
+	if err != nil {
+		lines := splitLines(strings.TrimSpace(output.String()))
+		if len(lines) > 0 {
+			return fmt.Errorf(
+				"%s hook failed: %w: %s", name, err, lines[len(lines)-1],
+			)
+		}
+		return fmt.Errorf("%s hook failed: %w", name, err)
+	}
+	return nil
+}
+
+/*
+Worker pool task that runs a 'post_pull' or 'post_pull_file' hook after the
+files it concerns have been moved into place.
+*/
+type HookTask struct {
+	name         string
+	command      string
+	cfgResource  *config.Resource
+	languageCode string
+	filePath     string
+	skip         bool
+	silent       bool
+}
+
+func (task *HookTask) Run(send func(string), abort func()) {
+	sendMessage := func(body string, force bool) {
+		if task.silent && !force {
+			return
+		}
+		message := fmt.Sprintf(
+			"%s.%s",
+			task.cfgResource.ProjectSlug,
+			task.cfgResource.ResourceSlug,
+		)
+		if task.filePath != "" {
+			message += " " + task.filePath
+		}
+		message += " - " + body
+		if !task.silent {
+			message = truncateMessage(message)
+		}
+		send(message)
+	}
+	sendMessage(fmt.Sprintf("Running %s hook", task.name), false)
+
+	err := runHook(
+		task.name,
+		task.command,
+		task.cfgResource.GetAPv3Id(),
+		task.languageCode,
+		task.filePath,
+	)
+	if err != nil {
+		sendMessage(err.Error(), true)
+		if !task.skip {
+			abort()
+		}
+		return
+	}
+	sendMessage("Done", false)
+}
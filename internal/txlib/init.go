@@ -1,6 +1,7 @@
 package txlib
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -36,6 +37,23 @@ func InitCommand() error {
 		}
 	}
 
+	err := writeInitialConfig(configFolder, configName, nil)
+	if err != nil {
+		return err
+	}
+
+	// Everything is great! Continue!
+	green := color.New(color.FgGreen).SprintFunc()
+	msg := green(fmt.Sprintf("Successful creation of '%s' file", configName))
+
+	fmt.Println(msg)
+	return nil
+}
+
+// Create '.tx/config' with the default host and the given resources
+func writeInitialConfig(
+	configFolder, configName string, resources []config.Resource,
+) error {
 	// Create the .tx folder in a given path
 	// In case something goes wrong abort and return error
 	if _, err := os.Stat(configFolder); os.IsNotExist(err) {
@@ -60,8 +78,9 @@ func InitCommand() error {
 	}
 
 	cfg := config.LocalConfig{
-		Path: configName,
-		Host: "https://app.transifex.com",
+		Path:      configName,
+		Host:      "https://app.transifex.com",
+		Resources: resources,
 	}
 
 	err = cfg.Save()
@@ -70,10 +89,100 @@ func InitCommand() error {
 		return fmt.Errorf("we could not add data to config: %w", err)
 	}
 
-	// Everything is great! Continue!
-	green := color.New(color.FgGreen).SprintFunc()
-	msg := green(fmt.Sprintf("Successful creation of '%s' file", configName))
+	return nil
+}
 
-	fmt.Println(msg)
+type InitCommandArguments struct {
+	OrganizationSlug string
+	ProjectSlug      string
+	// Language code of the files that hold the source strings, where the
+	// layout doesn't tell
+	SourceLanguage string
+	// Write the config without asking for confirmation
+	Yes bool
+	// Replace an existing config
+	Force bool
+}
+
+/*
+Create '.tx/config' with a resource for each set of localization files found in
+the repository, see scanRepository, so that 'tx push' and 'tx pull' work right
+away.
+*/
+func InitProjectCommand(arguments *InitCommandArguments) error {
+	if arguments.OrganizationSlug == "" || arguments.ProjectSlug == "" {
+		return errors.New("both the organization and the project are needed")
+	}
+	configFolder := filepath.Join("./", ".tx")
+	configName := filepath.Join(configFolder, "config")
+	_, err := os.Stat(configName)
+	configExists := err == nil
+	if configExists && !arguments.Force {
+		return fmt.Errorf(
+			"'%s' already exists; use '--force' to replace it", configName,
+		)
+	}
+
+	sourceLanguage := arguments.SourceLanguage
+	if sourceLanguage == "" {
+		sourceLanguage = "en"
+	}
+	resources, err := scanRepository(
+		".", arguments.OrganizationSlug, arguments.ProjectSlug, sourceLanguage,
+	)
+	if err != nil {
+		return err
+	}
+
+	if len(resources) == 0 {
+		if configExists {
+			return fmt.Errorf(
+				"no localization files found; '%s' was left as it is",
+				configName,
+			)
+		}
+		fmt.Println("No localization files found; use 'tx add' to add " +
+			"resources to the config")
+	} else {
+		fmt.Printf("Found %d resource(s):\n", len(resources))
+		for _, resource := range resources {
+			fmt.Printf(
+				"  - %s.%s (%s)\n", resource.ProjectSlug, resource.ResourceSlug,
+				resource.Type,
+			)
+			fmt.Printf("      source_file: %s\n", resource.SourceFile)
+			fmt.Printf("      file_filter: %s\n", resource.FileFilter)
+		}
+	}
+	fmt.Println()
+
+	if !arguments.Yes {
+		label := fmt.Sprintf("Write %d resource(s) to '%s'", len(resources), configName)
+		if configExists {
+			label = fmt.Sprintf(
+				"Replace '%s' with %d resource(s)", configName, len(resources),
+			)
+		}
+		confirmed, err := confirm(label)
+		if err != nil {
+			return err
+		}
+		if !confirmed {
+			fmt.Println("Init was cancelled!")
+			return nil
+		}
+	}
+
+	err = writeInitialConfig(configFolder, configName, resources)
+	if err != nil {
+		return err
+	}
+
+	green := color.New(color.FgGreen).SprintFunc()
+	fmt.Println(green(fmt.Sprintf(
+		"Successful creation of '%s' file with %d resource(s)",
+		configName,
+		len(resources),
+	)))
 	return nil
 }
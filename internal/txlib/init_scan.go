@@ -0,0 +1,249 @@
+package txlib
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/gosimple/slug"
+	"github.com/transifex/cli/internal/txlib/config"
+)
+
+/*
+A common way of laying out localization files. 'pattern' matches the paths of
+the files, relative to the root of the repository and with '/' as separator,
+with the groups:
+
+  - 'prefix': the directories above the layout's own
+  - 'lang': the language code; empty for files that hold the source strings
+    regardless of the source language, like Android's 'values/strings.xml'
+  - 'name': what the files are called, if it varies
+
+The other templates are expanded with these groups. 'otherPattern', if set,
+matches files that fit the file filter without being in a language, with the
+same groups except 'lang'; their directories are excluded from the resource.
+*/
+type repositoryLayout struct {
+	pattern      *regexp.Regexp
+	otherPattern *regexp.Regexp
+	fileFilter   string
+	// Where files with the source strings may be, besides the file of the
+	// source language; tried in order
+	sourceFiles        []string
+	resourceName       string
+	i18nType           string
+	languageConvention string
+}
+
+var repositoryLayouts = []repositoryLayout{
+	{
+		pattern: regexp.MustCompile(
+			`^(?P<prefix>(?:.*/)?)locale/(?P<lang>[^/]+)/LC_MESSAGES/` +
+				`(?P<name>[^/]+)\.po$`,
+		),
+		fileFilter:         "${prefix}locale/<lang>/LC_MESSAGES/${name}.po",
+		sourceFiles:        []string{"${prefix}locale/${name}.pot"},
+		resourceName:       "${prefix}${name}",
+		i18nType:           "PO",
+		languageConvention: "posix",
+	},
+	{
+		// Other qualifiers, like 'values-night' or 'values-v21', are not
+		// languages
+		pattern: regexp.MustCompile(
+			`^(?P<prefix>(?:.*/)?)res/values` +
+				`(?:-(?P<lang>[a-z]{2,3}(?:-r[A-Z]{2})?|b\+[A-Za-z0-9+]+))?` +
+				`/strings\.xml$`,
+		),
+		otherPattern: regexp.MustCompile(
+			`^(?P<prefix>(?:.*/)?)res/values-[^/]+/strings\.xml$`,
+		),
+		fileFilter:         "${prefix}res/values-<lang>/strings.xml",
+		sourceFiles:        []string{"${prefix}res/values/strings.xml"},
+		resourceName:       "${prefix}strings",
+		i18nType:           "ANDROID",
+		languageConvention: "android",
+	},
+	{
+		// 'Base.lproj' is not a language, but may hold the source strings
+		pattern: regexp.MustCompile(
+			`^(?P<prefix>(?:.*/)?)(?P<lang>[a-z]{2,3}(?:[-_][A-Za-z0-9]+)*)` +
+				`\.lproj/(?P<name>[^/]+)\.strings$`,
+		),
+		otherPattern: regexp.MustCompile(
+			`^(?P<prefix>(?:.*/)?)[^/]+\.lproj/(?P<name>[^/]+)\.strings$`,
+		),
+		fileFilter:         "${prefix}<lang>.lproj/${name}.strings",
+		sourceFiles:        []string{"${prefix}Base.lproj/${name}.strings"},
+		resourceName:       "${prefix}${name}",
+		i18nType:           "STRINGS",
+		languageConvention: "apple",
+	},
+	{
+		pattern: regexp.MustCompile(
+			`^(?P<prefix>(?:.*/)?)i18n/(?P<lang>[^/]+)\.json$`,
+		),
+		fileFilter:   "${prefix}i18n/<lang>.json",
+		resourceName: "${prefix}i18n",
+		i18nType:     "KEYVALUEJSON",
+	},
+}
+
+// Directories that never hold the project's own localization files
+var skippedScanDirectories = map[string]bool{
+	"node_modules": true,
+	"vendor":       true,
+}
+
+func expandLayoutTemplate(
+	layout repositoryLayout, template string, match []int, path string,
+) string {
+	return string(layout.pattern.ExpandString(nil, template, path, match))
+}
+
+func expandOtherLayoutTemplate(
+	layout repositoryLayout, template string, match []int, path string,
+) string {
+	return string(layout.otherPattern.ExpandString(nil, template, path, match))
+}
+
+/*
+Look for files laid out in one of 'repositoryLayouts' under 'root' and propose
+a resource for each set of them, ie for each file filter. Sets without a source
+file are left out.
+*/
+func scanRepository(
+	root, organizationSlug, projectSlug, sourceLanguage string,
+) ([]config.Resource, error) {
+	type fileSet struct {
+		layout repositoryLayout
+		// The first match, to expand the templates with
+		path  string
+		match []int
+		// Language code -> path
+		files map[string]string
+	}
+	sets := make(map[string]*fileSet)
+	// File filter -> directories that match it without being in a language
+	excludes := make(map[string][]string)
+
+	err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		name := entry.Name()
+		if entry.IsDir() {
+			if path != root &&
+				(strings.HasPrefix(name, ".") || skippedScanDirectories[name]) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		relativePath, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		relativePath = filepath.ToSlash(relativePath)
+		for _, layout := range repositoryLayouts {
+			match := layout.pattern.FindStringSubmatchIndex(relativePath)
+			if match == nil {
+				if layout.otherPattern == nil {
+					continue
+				}
+				match = layout.otherPattern.FindStringSubmatchIndex(relativePath)
+				if match == nil {
+					continue
+				}
+				fileFilter := expandOtherLayoutTemplate(
+					layout, layout.fileFilter, match, relativePath,
+				)
+				excludes[fileFilter] = append(
+					excludes[fileFilter],
+					relativePath[:strings.LastIndex(relativePath, "/")],
+				)
+				break
+			}
+			fileFilter := expandLayoutTemplate(
+				layout, layout.fileFilter, match, relativePath,
+			)
+			set, exists := sets[fileFilter]
+			if !exists {
+				set = &fileSet{
+					layout: layout,
+					path:   relativePath,
+					match:  match,
+					files:  make(map[string]string),
+				}
+				sets[fileFilter] = set
+			}
+			language := expandLayoutTemplate(layout, "${lang}", match, relativePath)
+			set.files[language] = relativePath
+			break
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var fileFilters []string
+	for fileFilter := range sets {
+		fileFilters = append(fileFilters, fileFilter)
+	}
+	sort.Strings(fileFilters)
+
+	var result []config.Resource
+	usedSlugs := make(map[string]bool)
+	for _, fileFilter := range fileFilters {
+		set := sets[fileFilter]
+		layout := set.layout
+		sourceFile := ""
+		for _, template := range layout.sourceFiles {
+			candidate := expandLayoutTemplate(layout, template, set.match, set.path)
+			if _, err := os.Stat(filepath.Join(root, candidate)); err == nil {
+				sourceFile = candidate
+				break
+			}
+		}
+		if sourceFile == "" {
+			sourceFile = set.files[toLocalLanguageCode(
+				layout.languageConvention, sourceLanguage,
+			)]
+		}
+		if sourceFile == "" {
+			fmt.Printf(
+				"Skipping '%s', no source file for '%s' found\n",
+				fileFilter,
+				sourceLanguage,
+			)
+			continue
+		}
+
+		resourceName := strings.Trim(expandLayoutTemplate(
+			layout, layout.resourceName, set.match, set.path,
+		), "/")
+		resourceSlug := slug.Make(resourceName)
+		for i := 2; usedSlugs[resourceSlug]; i++ {
+			resourceSlug = fmt.Sprintf("%s-%d", slug.Make(resourceName), i)
+		}
+		usedSlugs[resourceSlug] = true
+
+		result = append(result, config.Resource{
+			OrganizationSlug:   organizationSlug,
+			ProjectSlug:        projectSlug,
+			ResourceSlug:       resourceSlug,
+			ResourceName:       resourceName,
+			FileFilter:         filepath.FromSlash(fileFilter),
+			SourceFile:         filepath.FromSlash(sourceFile),
+			SourceLanguage:     sourceLanguage,
+			Type:               layout.i18nType,
+			LanguageConvention: layout.languageConvention,
+			FileFilterExclude:  excludes[fileFilter],
+		})
+	}
+	return result, nil
+}
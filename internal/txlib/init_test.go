@@ -98,7 +98,7 @@ func TestDoesNotChangeConfigWhenAbort(t *testing.T) {
 		RType:            "type",
 		SourceFile:       "aaa.json",
 	}
-	err = AddCommand(&cfg, &args)
+	err = AddCommand(&cfg, nil, &args)
 	if err != nil {
 		t.Error(err)
 	}
@@ -125,3 +125,172 @@ func TestDoesNotChangeConfigWhenAbort(t *testing.T) {
 			expected.Resources, cfg.Local.Resources)
 	}
 }
+
+func TestInitProjectScansRepository(t *testing.T) {
+	var pkgDir, tmpDir = beforeInitTest(t)
+	defer afterTest(pkgDir, tmpDir)
+
+	for _, path := range []string{
+		"locale/django.pot",
+		"locale/en/LC_MESSAGES/django.po",
+		"locale/pt_BR/LC_MESSAGES/django.po",
+		"app/src/main/res/values/strings.xml",
+		"app/src/main/res/values-el/strings.xml",
+		"app/src/main/res/values-pt-rBR/strings.xml",
+		// Qualifiers that are not languages
+		"app/src/main/res/values-night/strings.xml",
+		"app/src/main/res/values-v21/strings.xml",
+		"ios/Base.lproj/Localizable.strings",
+		"ios/en.lproj/Localizable.strings",
+		"ios/fr.lproj/Localizable.strings",
+		"web/i18n/en.json",
+		"web/i18n/de.json",
+		// No source file
+		"other/i18n/de.json",
+		"node_modules/lib/i18n/en.json",
+	} {
+		err := os.MkdirAll(filepath.Dir(path), 0755)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = os.WriteFile(path, []byte(""), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err := InitProjectCommand(&InitCommandArguments{
+		OrganizationSlug: "org",
+		ProjectSlug:      "proj",
+		Yes:              true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := config.LoadFromPaths("", filepath.Join(tmpDir, ".tx", "config"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resources := make(map[string]config.Resource)
+	for _, resource := range cfg.Local.Resources {
+		resources[resource.ResourceSlug] = resource
+	}
+	assert.Equal(t, len(resources), 4)
+
+	resource := resources["django"]
+	assert.Equal(t, resource.SourceFile, "locale/django.pot")
+	assert.Equal(t, resource.FileFilter, "locale/<lang>/LC_MESSAGES/django.po")
+	assert.Equal(t, resource.Type, "PO")
+	assert.Equal(t, resource.LanguageConvention, "posix")
+
+	resource = resources["app-src-main-strings"]
+	assert.Equal(t, resource.SourceFile, "app/src/main/res/values/strings.xml")
+	assert.Equal(
+		t, resource.FileFilter, "app/src/main/res/values-<lang>/strings.xml",
+	)
+	assert.Equal(t, resource.Type, "ANDROID")
+	languages := searchFileFilter(
+		".", resource.FileFilter, resource.FileFilterExclude...,
+	)
+	assert.Equal(t, len(languages), 2)
+	assert.True(t, languages["el"] != "")
+	assert.True(t, languages["pt-rBR"] != "")
+
+	resource = resources["ios-localizable"]
+	assert.Equal(t, resource.SourceFile, "ios/Base.lproj/Localizable.strings")
+	assert.Equal(t, resource.FileFilter, "ios/<lang>.lproj/Localizable.strings")
+	assert.Equal(t, resource.Type, "STRINGS")
+	languages = searchFileFilter(
+		".", resource.FileFilter, resource.FileFilterExclude...,
+	)
+	assert.Equal(t, len(languages), 2)
+	assert.True(t, languages["Base"] == "")
+
+	resource = resources["web-i18n"]
+	assert.Equal(t, resource.SourceFile, "web/i18n/en.json")
+	assert.Equal(t, resource.FileFilter, "web/i18n/<lang>.json")
+	assert.Equal(t, resource.SourceLanguage, "en")
+}
+
+func TestInitProjectCancelled(t *testing.T) {
+	var pkgDir, tmpDir = beforeInitTest(t)
+	defer afterTest(pkgDir, tmpDir)
+
+	defer func(original func(string) (bool, error)) {
+		confirm = original
+	}(confirm)
+	confirm = func(label string) (bool, error) {
+		return false, nil
+	}
+
+	err := InitProjectCommand(&InitCommandArguments{
+		OrganizationSlug: "org",
+		ProjectSlug:      "proj",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = os.Stat(filepath.Join(tmpDir, ".tx", "config"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestInitProjectExistingConfig(t *testing.T) {
+	var pkgDir, tmpDir = beforeInitTest(t)
+	defer afterTest(pkgDir, tmpDir)
+
+	configPath := filepath.Join(tmpDir, ".tx", "config")
+	err := os.MkdirAll(filepath.Dir(configPath), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	existing := "[main]\nhost = https://app.transifex.com\n\n" +
+		"[o:org:p:proj:r:res]\nsource_file = aaa.json\n" +
+		"file_filter = aaa-<lang>.json\ntype = KEYVALUEJSON\n"
+	err = os.WriteFile(configPath, []byte(existing), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkUnchanged := func() {
+		data, err := os.ReadFile(configPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, string(data), existing)
+	}
+
+	// Not without '--force'
+	err = InitProjectCommand(&InitCommandArguments{
+		OrganizationSlug: "org", ProjectSlug: "proj", Yes: true,
+	})
+	assert.True(t, err != nil)
+	checkUnchanged()
+
+	// Never with an empty config
+	err = InitProjectCommand(&InitCommandArguments{
+		OrganizationSlug: "org", ProjectSlug: "proj", Yes: true, Force: true,
+	})
+	assert.True(t, err != nil)
+	checkUnchanged()
+
+	err = os.MkdirAll("i18n", 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(filepath.Join("i18n", "en.json"), []byte("{}"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = InitProjectCommand(&InitCommandArguments{
+		OrganizationSlug: "org", ProjectSlug: "proj", Yes: true, Force: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := config.LoadFromPaths("", configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, len(cfg.Local.Resources), 1)
+	assert.Equal(t, cfg.Local.Resources[0].ResourceSlug, "i18n")
+}
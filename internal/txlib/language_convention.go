@@ -0,0 +1,162 @@
+package txlib
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/transifex/cli/internal/txlib/config"
+)
+
+// POSIX locales write scripts as modifiers, eg 'sr_RS@latin'
+var posixScriptModifiers = map[string]string{
+	"Latn": "latin",
+	"Cyrl": "cyrillic",
+	"Arab": "arabic",
+	"Deva": "devanagari",
+}
+
+/*
+Convert a Transifex language code, like 'pt_BR' or 'sr_Latn', to the way it is
+written locally according to 'convention':
+
+  - android: 'pt-rBR', 'b+sr+Latn'
+  - apple and bcp47: 'pt-BR', 'sr-Latn'
+  - posix: 'pt_BR', 'sr@latin'
+*/
+func toLocalLanguageCode(convention, remoteLanguageCode string) string {
+	parts := strings.Split(remoteLanguageCode, "_")
+	switch convention {
+	case "android":
+		if len(parts) == 1 {
+			return remoteLanguageCode
+		}
+		if len(parts) == 2 && len(parts[1]) == 2 {
+			return parts[0] + "-r" + parts[1]
+		}
+		return "b+" + strings.Join(parts, "+")
+	case "apple", "bcp47":
+		return strings.Join(parts, "-")
+	case "posix":
+		var language, script, region []string
+		for i, part := range parts {
+			if i == 0 {
+				language = append(language, part)
+			} else if modifier, exists := posixScriptModifiers[part]; exists {
+				script = append(script, modifier)
+			} else {
+				region = append(region, part)
+			}
+		}
+		if len(script) != 1 {
+			return remoteLanguageCode
+		}
+		return strings.Join(append(language, region...), "_") + "@" + script[0]
+	}
+	return remoteLanguageCode
+}
+
+// Reverse 'toLocalLanguageCode'
+func toRemoteLanguageCode(convention, localLanguageCode string) string {
+	switch convention {
+	case "android":
+		if strings.HasPrefix(localLanguageCode, "b+") {
+			return strings.ReplaceAll(localLanguageCode[2:], "+", "_")
+		}
+		parts := strings.Split(localLanguageCode, "-r")
+		if len(parts) == 2 {
+			return parts[0] + "_" + parts[1]
+		}
+	case "apple", "bcp47":
+		return strings.ReplaceAll(localLanguageCode, "-", "_")
+	case "posix":
+		// Drop the character set, eg 'pt_BR.UTF-8'
+		code := strings.SplitN(localLanguageCode, ".", 2)[0]
+		parts := strings.SplitN(code, "@", 2)
+		if len(parts) == 1 {
+			return code
+		}
+		for script, modifier := range posixScriptModifiers {
+			if modifier != parts[1] {
+				continue
+			}
+			language := strings.SplitN(parts[0], "_", 2)
+			result := language[0] + "_" + script
+			if len(language) == 2 {
+				result += "_" + language[1]
+			}
+			return result
+		}
+		return code
+	}
+	return localLanguageCode
+}
+
+/*
+Add the language mappings implied by the resource's language convention for
+'localLanguageCodes', without touching the explicit ones. 'localToRemote' and
+'remoteToLocal' are updated in place.
+*/
+func addConventionLanguageMappings(
+	cfg config.Config, cfgResource config.Resource,
+	localLanguageCodes []string,
+	localToRemote, remoteToLocal map[string]string,
+) {
+	convention := cfg.Local.GetLanguageConvention(&cfgResource)
+	if convention == "" {
+		return
+	}
+	for _, localLanguageCode := range localLanguageCodes {
+		if _, exists := localToRemote[localLanguageCode]; exists {
+			continue
+		}
+		remoteLanguageCode := toRemoteLanguageCode(convention, localLanguageCode)
+		if remoteLanguageCode == localLanguageCode {
+			continue
+		}
+		if _, exists := remoteToLocal[remoteLanguageCode]; exists {
+			continue
+		}
+		localToRemote[localLanguageCode] = remoteLanguageCode
+		remoteToLocal[remoteLanguageCode] = localLanguageCode
+	}
+}
+
+// The language codes of 'localFiles', which maps local language codes to paths,
+// sorted so that the mappings they add don't depend on map order
+func getLocalLanguageCodes(localFiles map[string]string) []string {
+	var result []string
+	for localLanguageCode := range localFiles {
+		result = append(result, localLanguageCode)
+	}
+	sort.Strings(result)
+	return result
+}
+
+/*
+Like 'addConventionLanguageMappings', but for language codes found on
+Transifex, so that new files are named according to the convention
+*/
+func addConventionRemoteLanguageMappings(
+	cfg config.Config, cfgResource config.Resource,
+	remoteLanguageCodes []string,
+	localToRemote, remoteToLocal map[string]string,
+) {
+	convention := cfg.Local.GetLanguageConvention(&cfgResource)
+	if convention == "" {
+		return
+	}
+	for _, remoteLanguageCode := range remoteLanguageCodes {
+		if _, exists := remoteToLocal[remoteLanguageCode]; exists {
+			continue
+		}
+		localLanguageCode := toLocalLanguageCode(convention, remoteLanguageCode)
+		if localLanguageCode == remoteLanguageCode {
+			continue
+		}
+		if _, exists := localToRemote[localLanguageCode]; exists {
+			continue
+		}
+		localToRemote[localLanguageCode] = remoteLanguageCode
+		remoteToLocal[remoteLanguageCode] = localLanguageCode
+	}
+}
@@ -0,0 +1,70 @@
+package txlib
+
+import (
+	"testing"
+
+	"github.com/transifex/cli/pkg/assert"
+)
+
+func TestLanguageConventions(t *testing.T) {
+	for _, test := range []struct {
+		convention, remote, local string
+	}{
+		{"android", "fr", "fr"},
+		{"android", "pt_BR", "pt-rBR"},
+		{"android", "sr_Latn", "b+sr+Latn"},
+		{"android", "es_419", "b+es+419"},
+		{"apple", "zh_Hans", "zh-Hans"},
+		{"bcp47", "pt_BR", "pt-BR"},
+		{"posix", "pt_BR", "pt_BR"},
+		{"posix", "sr_Latn", "sr@latin"},
+		{"posix", "sr_Latn_RS", "sr_RS@latin"},
+		{"", "pt_BR", "pt_BR"},
+	} {
+		assert.Equal(t, toLocalLanguageCode(test.convention, test.remote), test.local)
+		assert.Equal(t, toRemoteLanguageCode(test.convention, test.local), test.remote)
+	}
+	assert.Equal(t, toRemoteLanguageCode("posix", "pt_BR.UTF-8"), "pt_BR")
+}
+
+func TestAddConventionLanguageMappings(t *testing.T) {
+	cfg := getStandardConfig()
+	cfg.Local.LanguageConvention = "android"
+	cfgResource := cfg.Local.Resources[0]
+	cfgResource.LanguageMappings = map[string]string{"sr_Latn": "b+sr+Latn-custom"}
+	localFiles := map[string]string{
+		"pt-rBR":           "aaa-pt-rBR.json",
+		"b+sr+Latn":        "aaa-b+sr+Latn.json",
+		"b+sr+Latn-custom": "aaa-b+sr+Latn-custom.json",
+		"el":               "aaa-el.json",
+	}
+
+	mappings := makeLocalToRemoteLanguageMappings(*cfg, cfgResource)
+	remoteToLocal := makeRemoteToLocalLanguageMappings(mappings)
+	addConventionLanguageMappings(
+		*cfg, cfgResource, getLocalLanguageCodes(localFiles),
+		mappings, remoteToLocal,
+	)
+	assert.Equal(t, mappings["pt-rBR"], "pt_BR")
+	// The explicit mapping wins
+	assert.Equal(t, mappings["b+sr+Latn-custom"], "sr_Latn")
+	_, exists := mappings["b+sr+Latn"]
+	assert.Equal(t, exists, false)
+	_, exists = mappings["el"]
+	assert.Equal(t, exists, false)
+
+	addConventionRemoteLanguageMappings(
+		*cfg, cfgResource, []string{"de_AT", "pt_BR"}, mappings, remoteToLocal,
+	)
+	assert.Equal(t, remoteToLocal["de_AT"], "de-rAT")
+	assert.Equal(t, remoteToLocal["pt_BR"], "pt-rBR")
+
+	// The resource's convention overrides the main section's
+	cfgResource.LanguageConvention = "bcp47"
+	mappings = makeLocalToRemoteLanguageMappings(*cfg, cfgResource)
+	addConventionLanguageMappings(
+		*cfg, cfgResource, getLocalLanguageCodes(localFiles),
+		mappings, makeRemoteToLocalLanguageMappings(mappings),
+	)
+	assert.Equal(t, mappings["pt-rBR"], "pt_rBR")
+}
@@ -6,6 +6,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/fatih/color"
 	"github.com/transifex/cli/internal/txlib/config"
 	"github.com/transifex/cli/pkg/jsonapi"
 	"github.com/transifex/cli/pkg/txapi"
@@ -13,37 +14,228 @@ import (
 )
 
 type MergeCommandArguments struct {
-	ResourceId         string
+	// Resource ids or glob patterns; if empty, all the resources of the
+	// configuration are merged
+	ResourceIds        []string
 	Branch             string
 	ConflictResolution string
 	Force              bool
 	Skip               bool
 	Silent             bool
+	Workers            int
+	// How long to wait for each merge to complete; 0 means wait forever
+	Timeout time.Duration
+	// Only list the differing strings of each resource, without merging
+	Preview bool
+	// Ask how to resolve each differing string
+	Interactive bool
+	// File with per-string resolutions to apply, as saved by
+	// SaveDecisionsFile
+	DecisionsFile string
+	// Save the interactive resolutions to this file instead of merging
+	SaveDecisionsFile string
 }
 
+// How the merge of a resource turned out
+const (
+	mergeNotAttempted = ""
+	mergeMerged       = "merged"
+	mergeConflicted   = "conflicted"
+	mergeFailed       = "failed"
+)
+
 func MergeCommand(
 	cfg *config.Config,
 	api jsonapi.Connection,
 	args MergeCommandArguments,
 ) error {
-	args.Branch = figureOutBranch(args.Branch)
+	if !isValidResolutionPolicy(args.ConflictResolution) {
+		return fmt.Errorf("invalid resolution policy %s", args.ConflictResolution)
+	}
 
-	cfgResources, err := figureOutResources([]string{args.ResourceId}, cfg)
+	var err error
+	requestedBranch := args.Branch
+	args.Branch, err = figureOutBranch(args.Branch)
 	if err != nil {
 		return err
 	}
+	if args.Branch == "" {
+		return errors.New("a branch is needed to merge; use '--branch'")
+	}
+
+	cfgResources, err := figureOutResources(args.ResourceIds, cfg)
+	if err != nil {
+		return err
+	}
+	if len(cfgResources) == 0 {
+		return errors.New("no resources found in config file")
+	}
+
+	if args.SaveDecisionsFile != "" && !args.Interactive {
+		return errors.New("saving decisions requires the interactive mode")
+	}
+	// Keeping the branch's text of some strings only works if the merge
+	// keeps the branch's text; see checkMergeDecisions
+	if args.Interactive && args.SaveDecisionsFile == "" &&
+		args.ConflictResolution != "USE_HEAD" {
+		return errors.New(
+			"the interactive mode requires '--conflict-resolution USE_HEAD'",
+		)
+	}
 
-	applyBranchToResources(cfgResources, args.Branch)
+	// Remember the resources without the branch, as the base of each merge
+	// and to refer to resources in decisions files
+	resourceNames := make([]string, len(cfgResources))
+	baseIds := make([]string, len(cfgResources))
+	for i, cfgResource := range cfgResources {
+		resourceNames[i] = fmt.Sprintf(
+			"%s.%s", cfgResource.ProjectSlug, cfgResource.ResourceSlug,
+		)
+		baseIds[i] = cfgResource.GetAPv3Id()
+	}
 
-	cfgResource := cfgResources[0]
+	applyBranchToResources(
+		&api, cfg, cfgResources, args.Branch,
+		getLegacyBranch(requestedBranch, args.Branch),
+	)
 
-	err = mergeResource(&api, cfgResource, args)
+	conflicts := make([][]*mergeConflict, len(cfgResources))
+	decisions := make(map[string]string)
+	if args.Preview || args.Interactive || args.DecisionsFile != "" {
+		if !args.Silent {
+			fmt.Println("# Looking for differing strings")
+		}
+		for i, cfgResource := range cfgResources {
+			resourceName := resourceNames[i]
+			conflicts[i], err = findMergeConflicts(
+				&api, resourceName, cfgResource.GetAPv3Id(), baseIds[i],
+				func(msg string) {
+					if !args.Silent {
+						fmt.Printf("%s - %s\n", resourceName, msg)
+					}
+				},
+			)
+			if err != nil {
+				return fmt.Errorf("%s: %w", resourceNames[i], err)
+			}
+			if args.Preview {
+				printMergeConflicts(resourceNames[i], conflicts[i])
+			}
+		}
+	}
+	if args.Preview {
+		return nil
+	}
+
+	if args.DecisionsFile != "" {
+		decisions, err = loadMergeDecisions(args.DecisionsFile)
+		if err != nil {
+			return err
+		}
+	}
+	if args.Interactive {
+		for i := range cfgResources {
+			for _, conflict := range conflicts[i] {
+				resolution, err := promptMergeResolution(conflict)
+				if err != nil {
+					return err
+				}
+				decisions[getMergeDecisionKey(
+					conflict.Resource, conflict.Key, conflict.Context,
+				)] = resolution
+			}
+		}
+	}
+	if args.SaveDecisionsFile != "" {
+		var allConflicts []*mergeConflict
+		for i := range cfgResources {
+			allConflicts = append(allConflicts, conflicts[i]...)
+		}
+		saved, err := saveMergeDecisions(
+			args.SaveDecisionsFile, allConflicts, decisions,
+		)
+		if err != nil {
+			return err
+		}
+		fmt.Printf(
+			"Saved %d decision(s) to '%s'\n",
+			saved,
+			args.SaveDecisionsFile,
+		)
+		return nil
+	}
+
+	for i := range cfgResources {
+		err = checkMergeDecisions(conflicts[i], decisions, args.ConflictResolution)
+		if err != nil {
+			return err
+		}
+	}
+
+	workers := args.Workers
+	if workers < 1 {
+		workers = 1
+	}
+	outcomes := make([]string, len(cfgResources))
+	pool := worker_pool.New(workers, len(cfgResources), args.Silent)
+	for i, cfgResource := range cfgResources {
+		pool.Add(&MergeResourceTask{
+			&api, cfgResource, args, conflicts[i], decisions, &outcomes[i],
+		})
+	}
+	pool.Start()
+	<-pool.Wait()
+
+	summary := make(map[string][]string)
+	for i, cfgResource := range cfgResources {
+		summary[outcomes[i]] = append(summary[outcomes[i]], fmt.Sprintf(
+			"%s.%s", cfgResource.ProjectSlug, cfgResource.ResourceSlug,
+		))
+	}
+	fmt.Printf(
+		"\n# Merged %d of %d resource(s)\n",
+		len(summary[mergeMerged]),
+		len(cfgResources),
+	)
+	printMergeSummary("Merged", summary[mergeMerged], color.GreenString)
+	printMergeSummary("Conflicted", summary[mergeConflicted], color.YellowString)
+	printMergeSummary("Failed", summary[mergeFailed], color.RedString)
+	printMergeSummary(
+		"Not attempted", summary[mergeNotAttempted], color.YellowString,
+	)
+
+	if pool.IsAborted {
+		return errors.New("Aborted")
+	}
+	if len(summary[mergeMerged]) < len(cfgResources) {
+		return fmt.Errorf(
+			"%d of %d merge(s) did not complete",
+			len(cfgResources)-len(summary[mergeMerged]),
+			len(cfgResources),
+		)
+	}
+	return nil
+}
 
-	return err
+func printMergeSummary(
+	title string,
+	resourceIds []string,
+	colorize func(string, ...interface{}) string,
+) {
+	if len(resourceIds) == 0 {
+		return
+	}
+	fmt.Println(colorize("%s:", title))
+	for _, resourceId := range resourceIds {
+		fmt.Printf("  - %s\n", resourceId)
+	}
 }
 
 func mergeResource(
-	api *jsonapi.Connection, cfgResource *config.Resource, args MergeCommandArguments,
+	api *jsonapi.Connection,
+	cfgResource *config.Resource,
+	args MergeCommandArguments,
+	send func(string),
 ) error {
 	isValidPolicy := isValidResolutionPolicy(args.ConflictResolution)
 	if !isValidPolicy {
@@ -79,52 +271,88 @@ func mergeResource(
 		return err
 	}
 
-	pool := worker_pool.New(1, 1, args.Silent)
-	pool.Add(&MergeResourcePollTask{merge, args})
-	pool.Start()
-	<-pool.Wait()
-	if pool.IsAborted {
-		return errors.New("Aborted")
-	}
+	return handleRetry(
+		func() error {
+			return txapi.PollResourceMerge(
+				merge,
+				time.Second,
+				args.Timeout,
+			)
+		},
+		"Polling merge task status",
+		send,
+	)
+}
 
-	return nil
+// Whether a merge failed because of conflicting changes, either when it was
+// created or while it was running
+func isMergeConflict(err error) bool {
+	var mergeAttributes *txapi.ResourceMergeAttributes
+	if errors.As(err, &mergeAttributes) {
+		return mergeAttributes.IsConflict()
+	}
+	var apiError *jsonapi.Error
+	if errors.As(err, &apiError) {
+		return apiError.StatusCode == 409 ||
+			strings.Contains(strings.ToLower(apiError.Error()), "diverge")
+	}
+	return false
 }
 
-type MergeResourcePollTask struct {
-	merge *jsonapi.Resource
-	args  MergeCommandArguments
+type MergeResourceTask struct {
+	api         *jsonapi.Connection
+	cfgResource *config.Resource
+	args        MergeCommandArguments
+	conflicts   []*mergeConflict
+	decisions   map[string]string
+	outcome     *string
 }
 
-func (task *MergeResourcePollTask) Run(send func(string), abort func()) {
-	merge := task.merge
+func (task *MergeResourceTask) Run(send func(string), abort func()) {
+	api := task.api
+	cfgResource := task.cfgResource
 	args := task.args
 
-	parts := strings.Split(merge.Relationships["base"].DataSingular.Id, ":")
 	sendMessage := func(body string, force bool) {
 		if args.Silent && !force {
 			return
 		}
 		send(fmt.Sprintf(
-			"%s.%s - %s", parts[3], parts[5], body,
+			"%s.%s - %s", cfgResource.ProjectSlug, cfgResource.ResourceSlug, body,
 		))
 	}
 
-	err := handleRetry(
-		func() error {
-			return txapi.PollResourceMerge(
-				merge,
-				time.Second,
-			)
-		},
-		"Polling merge task status",
-		func(msg string) { sendMessage(msg, false) },
+	applied, err := applyMergeDecisions(task.conflicts, task.decisions)
+	if err != nil {
+		*task.outcome = mergeFailed
+		sendMessage(err.Error(), true)
+		if !args.Skip {
+			abort()
+		}
+		return
+	}
+	if applied > 0 {
+		sendMessage(
+			fmt.Sprintf("Resolved %d differing string(s)", applied), false,
+		)
+	}
+
+	sendMessage("Merging", false)
+	err = mergeResource(
+		api, cfgResource, args, func(msg string) { sendMessage(msg, false) },
 	)
 	if err != nil {
+		if isMergeConflict(err) {
+			*task.outcome = mergeConflicted
+		} else {
+			*task.outcome = mergeFailed
+		}
 		sendMessage(err.Error(), true)
 		if !args.Skip {
 			abort()
 		}
 		return
 	}
+	*task.outcome = mergeMerged
 	sendMessage("Done", false)
 }
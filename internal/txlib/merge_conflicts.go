@@ -0,0 +1,349 @@
+package txlib
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/manifoldco/promptui"
+	"github.com/transifex/cli/pkg/jsonapi"
+	"github.com/transifex/cli/pkg/txapi"
+)
+
+/*
+A source string whose text differs between a branch resource (head) and the
+resource it will be merged into (base). The API doesn't expose the common
+ancestor of the two, so a string edited only on the branch can't be told apart
+from one edited on both sides; these are "differing" strings rather than
+conflicts, and the user decides which of them matter.
+*/
+type mergeConflict struct {
+	// "<project_slug>.<resource_slug>" of the resource without the branch
+	Resource string
+	Key      string
+	Context  string
+	Base     map[string]string
+	Head     map[string]string
+
+	headString *jsonapi.Resource
+}
+
+// How to resolve one differing string; what a decisions file holds a list of
+type mergeDecision struct {
+	Resource   string `json:"resource"`
+	Key        string `json:"key"`
+	Context    string `json:"context,omitempty"`
+	Resolution string `json:"resolution"`
+}
+
+func getMergeDecisionKey(resource, key, context string) string {
+	return strings.Join([]string{resource, key, context}, "\x00")
+}
+
+/*
+Find the differing strings of the branch resource 'headId'. The base is the
+resource the branch was created from, or 'defaultBaseId' if the API doesn't
+say. Throttled requests are retried, telling the user through 'send'.
+*/
+func findMergeConflicts(
+	api *jsonapi.Connection,
+	resourceName, headId, defaultBaseId string,
+	send func(string),
+) ([]*mergeConflict, error) {
+	var head *jsonapi.Resource
+	err := handleRetry(func() error {
+		var err error
+		head, err = txapi.GetResourceById(api, headId)
+		return err
+	}, "", send)
+	if err != nil {
+		return nil, err
+	}
+	if head == nil {
+		return nil, fmt.Errorf("resource '%s' not found", headId)
+	}
+	baseId := defaultBaseId
+	baseRelationship, exists := head.Relationships["base"]
+	if exists && baseRelationship.DataSingular != nil {
+		baseId = baseRelationship.DataSingular.Id
+	}
+	var base *jsonapi.Resource
+	err = handleRetry(func() error {
+		var err error
+		base, err = txapi.GetResourceById(api, baseId)
+		return err
+	}, "", send)
+	if err != nil {
+		return nil, err
+	}
+	if base == nil {
+		return nil, fmt.Errorf("resource '%s' not found", baseId)
+	}
+
+	var baseStrings, headStrings []*jsonapi.Resource
+	err = handleRetry(func() error {
+		var err error
+		baseStrings, err = txapi.GetResourceStrings(api, base)
+		return err
+	}, "", send)
+	if err != nil {
+		return nil, err
+	}
+	err = handleRetry(func() error {
+		var err error
+		headStrings, err = txapi.GetResourceStrings(api, head)
+		return err
+	}, "", send)
+	if err != nil {
+		return nil, err
+	}
+
+	baseAttributes := make(map[string]txapi.ResourceStringAttributes)
+	for _, baseString := range baseStrings {
+		var attributes txapi.ResourceStringAttributes
+		err := baseString.MapAttributes(&attributes)
+		if err != nil {
+			return nil, err
+		}
+		key := getMergeDecisionKey(
+			resourceName, attributes.Key, attributes.Context,
+		)
+		baseAttributes[key] = attributes
+	}
+
+	var result []*mergeConflict
+	for _, headString := range headStrings {
+		var attributes txapi.ResourceStringAttributes
+		err := headString.MapAttributes(&attributes)
+		if err != nil {
+			return nil, err
+		}
+		key := getMergeDecisionKey(
+			resourceName, attributes.Key, attributes.Context,
+		)
+		baseAttribute, exists := baseAttributes[key]
+		if !exists || stringMapsEqual(baseAttribute.Strings, attributes.Strings) {
+			continue
+		}
+		result = append(result, &mergeConflict{
+			Resource:   resourceName,
+			Key:        attributes.Key,
+			Context:    attributes.Context,
+			Base:       baseAttribute.Strings,
+			Head:       attributes.Strings,
+			headString: headString,
+		})
+	}
+	return result, nil
+}
+
+func stringMapsEqual(left, right map[string]string) bool {
+	if len(left) != len(right) {
+		return false
+	}
+	for key, leftValue := range left {
+		rightValue, exists := right[key]
+		if !exists || leftValue != rightValue {
+			return false
+		}
+	}
+	return true
+}
+
+// Show the text of a string on one line; plural forms are listed in order
+func formatStringText(text map[string]string) string {
+	if len(text) == 1 {
+		if value, exists := text["other"]; exists {
+			return value
+		}
+	}
+	var forms []string
+	for form := range text {
+		forms = append(forms, form)
+	}
+	order := map[string]int{
+		"zero": 0, "one": 1, "two": 2, "few": 3, "many": 4, "other": 5,
+	}
+	sort.Slice(forms, func(i, j int) bool {
+		return order[forms[i]] < order[forms[j]]
+	})
+	var parts []string
+	for _, form := range forms {
+		parts = append(parts, fmt.Sprintf("%s: %s", form, text[form]))
+	}
+	return strings.Join(parts, " | ")
+}
+
+func describeMergeConflict(conflict *mergeConflict) string {
+	if conflict.Context != "" {
+		return fmt.Sprintf("%s (context: %s)", conflict.Key, conflict.Context)
+	}
+	return conflict.Key
+}
+
+func printMergeConflicts(resourceName string, conflicts []*mergeConflict) {
+	if len(conflicts) == 0 {
+		fmt.Printf("%s: no differing strings\n", resourceName)
+		return
+	}
+	fmt.Printf(
+		"%s: %d differing string(s)\n", resourceName, len(conflicts),
+	)
+	for _, conflict := range conflicts {
+		fmt.Printf("  %s\n", describeMergeConflict(conflict))
+		fmt.Printf(
+			"    %s %s\n",
+			color.RedString("base:"),
+			formatStringText(conflict.Base),
+		)
+		fmt.Printf(
+			"    %s %s\n",
+			color.GreenString("head:"),
+			formatStringText(conflict.Head),
+		)
+	}
+}
+
+// Ask which side of a conflict to keep; replaced in tests
+var promptMergeResolution = func(conflict *mergeConflict) (string, error) {
+	type selectItem struct {
+		Name  string
+		Value string
+	}
+	items := []selectItem{
+		{"Keep the branch's text (USE_HEAD)", formatStringText(conflict.Head)},
+		{"Keep the base's text (USE_BASE)", formatStringText(conflict.Base)},
+	}
+	prompt := promptui.Select{
+		Label: fmt.Sprintf(
+			"%s: %s", conflict.Resource, describeMergeConflict(conflict),
+		),
+		Items:     items,
+		Templates: getSelectTemplate("Resolution"),
+	}
+	idx, _, err := prompt.Run()
+	if err != nil {
+		return "", err
+	}
+	if idx == 0 {
+		return "USE_HEAD", nil
+	}
+	return "USE_BASE", nil
+}
+
+func loadMergeDecisions(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var decisions []mergeDecision
+	err = json.Unmarshal(data, &decisions)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse '%s': %w", path, err)
+	}
+	result := make(map[string]string)
+	for _, decision := range decisions {
+		if !isValidResolutionPolicy(decision.Resolution) {
+			return nil, fmt.Errorf(
+				"invalid resolution '%s' for '%s' in '%s'",
+				decision.Resolution,
+				decision.Key,
+				path,
+			)
+		}
+		key := getMergeDecisionKey(
+			decision.Resource, decision.Key, decision.Context,
+		)
+		result[key] = decision.Resolution
+	}
+	return result, nil
+}
+
+// Save the decisions about 'conflicts' to 'path', returning how many there were
+func saveMergeDecisions(
+	path string, conflicts []*mergeConflict, decisions map[string]string,
+) (int, error) {
+	result := []mergeDecision{}
+	for _, conflict := range conflicts {
+		resolution, exists := decisions[getMergeDecisionKey(
+			conflict.Resource, conflict.Key, conflict.Context,
+		)]
+		if !exists {
+			continue
+		}
+		result = append(result, mergeDecision{
+			Resource:   conflict.Resource,
+			Key:        conflict.Key,
+			Context:    conflict.Context,
+			Resolution: resolution,
+		})
+	}
+	data, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return 0, err
+	}
+	err = os.WriteFile(path, append(data, '\n'), 0644)
+	if err != nil {
+		return 0, err
+	}
+	return len(result), nil
+}
+
+/*
+Check that the decisions can be carried out with 'policy'. Only the strings of
+the branch are ever changed, never the ones of the base, so that nothing
+outside the branch is touched before the merge succeeds. This means that
+keeping the branch's text of a string is only possible when the merge itself
+keeps the branch's text, ie with USE_HEAD.
+*/
+func checkMergeDecisions(
+	conflicts []*mergeConflict, decisions map[string]string, policy string,
+) error {
+	for _, conflict := range conflicts {
+		resolution := decisions[getMergeDecisionKey(
+			conflict.Resource, conflict.Key, conflict.Context,
+		)]
+		if resolution == "USE_HEAD" && policy != "USE_HEAD" {
+			return fmt.Errorf(
+				"'%s' of %s is decided as USE_HEAD, which needs "+
+					"'--conflict-resolution USE_HEAD'",
+				describeMergeConflict(conflict),
+				conflict.Resource,
+			)
+		}
+	}
+	return nil
+}
+
+/*
+Make the merge resolve each decided conflict the way it was decided. The merge
+itself applies a single policy to all conflicts, so strings decided as
+USE_BASE when merging with USE_HEAD get the base's text on the branch
+beforehand. The decisions must have passed 'checkMergeDecisions'.
+*/
+func applyMergeDecisions(
+	conflicts []*mergeConflict, decisions map[string]string,
+) (int, error) {
+	applied := 0
+	for _, conflict := range conflicts {
+		resolution, exists := decisions[getMergeDecisionKey(
+			conflict.Resource, conflict.Key, conflict.Context,
+		)]
+		if !exists || resolution != "USE_BASE" {
+			continue
+		}
+		err := txapi.UpdateResourceString(conflict.headString, conflict.Base)
+		if err != nil {
+			return applied, fmt.Errorf(
+				"could not resolve '%s': %w",
+				describeMergeConflict(conflict),
+				err,
+			)
+		}
+		applied++
+	}
+	return applied, nil
+}
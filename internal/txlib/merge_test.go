@@ -1,6 +1,13 @@
 package txlib
 
 import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -15,22 +22,111 @@ const (
 func TestMergeSuccess(t *testing.T) {
 	mockData := getMockedDataForResourceMerge()
 	api := jsonapi.GetTestConnection(mockData)
-	commandArgs := MergeCommandArguments{"projslug.resslug", "the_branch", "USE_HEAD", false, false, false}
+	commandArgs := MergeCommandArguments{
+		ResourceIds:        []string{"projslug.resslug"},
+		Branch:             "the_branch",
+		ConflictResolution: "USE_HEAD",
+	}
 	resource := getStandardConfigMerge().FindResource("projslug.resslug")
-	err := mergeResource(&api, resource, commandArgs)
+	err := mergeResource(&api, resource, commandArgs, func(string) {})
 	assert.Nil(t, err)
 }
 
 func TestMergeInvalidPolicy(t *testing.T) {
 	mockData := getMockedDataForResourceMerge()
 	api := jsonapi.GetTestConnection(mockData)
-	commandArgs := MergeCommandArguments{"projslug.resslug", "the_branch", "INVALID_POLICY", false, false, false}
+	commandArgs := MergeCommandArguments{
+		ResourceIds:        []string{"projslug.resslug"},
+		Branch:             "the_branch",
+		ConflictResolution: "INVALID_POLICY",
+	}
 	resource := getStandardConfigMerge().FindResource("projslug.resslug")
-	err := mergeResource(&api, resource, commandArgs)
+	err := mergeResource(&api, resource, commandArgs, func(string) {})
 	assert.NotNil(t, err)
 
 }
 
+func TestMergeMultipleResources(t *testing.T) {
+	resourceResponse := func(slug string) *jsonapi.MockEndpoint {
+		return jsonapi.GetMockTextResponse(fmt.Sprintf(
+			`{"data": {"type": "resources",
+			           "id": "o:orgslug:p:projslug:r:%[1]s",
+			           "attributes": {"slug": "%[1]s"}}}`,
+			slug,
+		))
+	}
+	mergeResponse := func(id, status, errors string) jsonapi.MockRequest {
+		return jsonapi.MockRequest{Response: jsonapi.MockResponse{
+			Text: fmt.Sprintf(
+				`{"data": {"type": "resource_async_merges",
+				           "id": "%[1]s",
+				           "attributes": {"status": "%[2]s",
+				                          "errors": %[3]s},
+				           "links": {"self": "/resource_async_merges/%[1]s"}}}`,
+				id, status, errors,
+			),
+		}}
+	}
+	mockData := jsonapi.MockData{
+		"/resources/o:orgslug:p:projslug:r:feature--resslug":  resourceResponse("feature--resslug"),
+		"/resources/o:orgslug:p:projslug:r:feature--resslug1": resourceResponse("feature--resslug1"),
+		"/resource_async_merges": &jsonapi.MockEndpoint{
+			Requests: []jsonapi.MockRequest{
+				mergeResponse("uuid1", "CREATED", "[]"),
+				mergeResponse("uuid2", "CREATED", "[]"),
+			},
+		},
+		"/resource_async_merges/uuid1": &jsonapi.MockEndpoint{
+			Requests: []jsonapi.MockRequest{
+				mergeResponse("uuid1", "COMPLETED", "[]"),
+			},
+		},
+		"/resource_async_merges/uuid2": &jsonapi.MockEndpoint{
+			Requests: []jsonapi.MockRequest{
+				mergeResponse(
+					"uuid2",
+					"FAILED",
+					`[{"code": "conflict", "detail": "Conflicting changes"}]`,
+				),
+			},
+		},
+	}
+	api := jsonapi.GetTestConnection(mockData)
+	cfg := getStandardConfigMerge()
+	cfg.Local.Resources = append(cfg.Local.Resources, config.Resource{
+		OrganizationSlug: "orgslug",
+		ProjectSlug:      "projslug",
+		ResourceSlug:     "resslug1",
+		Type:             "I18N_TYPE",
+		SourceFile:       "bbb.json",
+		FileFilter:       "bbb-<lang>.json",
+	})
+
+	rescueStdout := os.Stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+
+	err := MergeCommand(cfg, api, MergeCommandArguments{
+		ResourceIds:        []string{"projslug.*", "projslug.resslug"},
+		Branch:             "feature",
+		ConflictResolution: "USE_HEAD",
+		Skip:               true,
+		Silent:             true,
+		Workers:            1,
+	})
+
+	w.Close()
+	out, _ := ioutil.ReadAll(r)
+	os.Stdout = rescueStdout
+
+	assert.NotNil(t, err)
+	assert.Equal(t, "1 of 2 merge(s) did not complete", err.Error())
+	result := string(out)
+	assert.True(t, strings.Contains(result, "# Merged 1 of 2 resource(s)"))
+	assert.True(t, strings.Contains(result, "Conflicted:"))
+	assert.False(t, strings.Contains(result, "Failed:"))
+}
+
 func getStandardConfigMerge() *config.Config {
 	return &config.Config{
 		Local: &config.LocalConfig{
@@ -177,3 +273,226 @@ func getMockedDataForResourceMerge() jsonapi.MockData {
 		"/resource_async_merges/some_uuid": mergePollingEndpoint(),
 	}
 }
+
+func getMergeConflictsMockData() jsonapi.MockData {
+	resourceStringsUrl := func(resourceId string) string {
+		return fmt.Sprintf(
+			"/resource_strings?%s=%s",
+			url.QueryEscape("filter[resource]"),
+			url.QueryEscape(resourceId),
+		)
+	}
+	resourceString := func(id, key, text string) string {
+		return fmt.Sprintf(
+			`{"type": "resource_strings",
+			  "id": "%s",
+			  "attributes": {"key": "%s", "strings": {"other": "%s"}}}`,
+			id, key, text,
+		)
+	}
+	return jsonapi.MockData{
+		"/resources/o:orgslug:p:projslug:r:feature--resslug": jsonapi.GetMockTextResponse(
+			`{"data": {"type": "resources",
+			           "id": "o:orgslug:p:projslug:r:feature--resslug",
+			           "attributes": {"slug": "feature--resslug"},
+			           "relationships": {"base": {"data": {
+			             "type": "resources",
+			             "id": "o:orgslug:p:projslug:r:resslug"}}}}}`,
+		),
+		"/resources/o:orgslug:p:projslug:r:resslug": jsonapi.GetMockTextResponse(
+			`{"data": {"type": "resources",
+			           "id": "o:orgslug:p:projslug:r:resslug",
+			           "attributes": {"slug": "resslug"}}}`,
+		),
+		resourceStringsUrl("o:orgslug:p:projslug:r:resslug"): jsonapi.GetMockTextResponse(
+			fmt.Sprintf(
+				`{"data": [%s, %s]}`,
+				resourceString("base_hello", "hello", "Hello"),
+				resourceString("base_bye", "bye", "Bye"),
+			),
+		),
+		resourceStringsUrl("o:orgslug:p:projslug:r:feature--resslug"): jsonapi.GetMockTextResponse(
+			fmt.Sprintf(
+				`{"data": [%s, %s, %s]}`,
+				resourceString("head_hello", "hello", "Hello world"),
+				resourceString("head_bye", "bye", "Bye"),
+				resourceString("head_new", "new", "New"),
+			),
+		),
+	}
+}
+
+func TestMergePreview(t *testing.T) {
+	api := jsonapi.GetTestConnection(getMergeConflictsMockData())
+
+	rescueStdout := os.Stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+
+	err := MergeCommand(getStandardConfigMerge(), api, MergeCommandArguments{
+		Branch:             "feature",
+		ConflictResolution: "USE_HEAD",
+		Preview:            true,
+	})
+
+	w.Close()
+	out, _ := ioutil.ReadAll(r)
+	os.Stdout = rescueStdout
+
+	assert.Nil(t, err)
+	result := string(out)
+	assert.True(t, strings.Contains(
+		result, "projslug.resslug: 1 differing string(s)",
+	))
+	assert.True(t, strings.Contains(result, "base: Hello\n"))
+	assert.True(t, strings.Contains(result, "head: Hello world\n"))
+	assert.False(t, strings.Contains(result, "bye"))
+}
+
+func TestMergeSaveAndApplyDecisions(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+	decisionsPath := filepath.Join(tmpDir, "decisions.json")
+
+	defer func(original func(*mergeConflict) (string, error)) {
+		promptMergeResolution = original
+	}(promptMergeResolution)
+	promptMergeResolution = func(conflict *mergeConflict) (string, error) {
+		return "USE_BASE", nil
+	}
+
+	api := jsonapi.GetTestConnection(getMergeConflictsMockData())
+	err = MergeCommand(getStandardConfigMerge(), api, MergeCommandArguments{
+		Branch:             "feature",
+		ConflictResolution: "USE_HEAD",
+		Interactive:        true,
+		SaveDecisionsFile:  decisionsPath,
+		Silent:             true,
+	})
+	assert.Nil(t, err)
+
+	data, err := os.ReadFile(decisionsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decisions []mergeDecision
+	err = json.Unmarshal(data, &decisions)
+	assert.Nil(t, err)
+	assert.Equal(t, []mergeDecision{{
+		Resource:   "projslug.resslug",
+		Key:        "hello",
+		Resolution: "USE_BASE",
+	}}, decisions)
+
+	// Applying the decisions makes the branch's string match the base before
+	// merging with USE_HEAD
+	mockData := getMergeConflictsMockData()
+	mockData["/resources/o:orgslug:p:projslug:r:feature--resslug"].Requests = append(
+		mockData["/resources/o:orgslug:p:projslug:r:feature--resslug"].Requests,
+		mockData["/resources/o:orgslug:p:projslug:r:feature--resslug"].Requests[0],
+	)
+	mockData["/resource_strings/head_hello"] = jsonapi.GetMockTextResponse(
+		`{"data": {"type": "resource_strings", "id": "head_hello",
+		           "attributes": {"key": "hello",
+		                          "strings": {"other": "Hello"}}}}`,
+	)
+	mockData["/resource_async_merges"] = mergeEndpoint()
+	mockData["/resource_async_merges/some_uuid"] = mergePollingEndpoint()
+	api = jsonapi.GetTestConnection(mockData)
+
+	rescueStdout := os.Stdout
+	_, w, _ := os.Pipe()
+	os.Stdout = w
+	err = MergeCommand(getStandardConfigMerge(), api, MergeCommandArguments{
+		Branch:             "feature",
+		ConflictResolution: "USE_HEAD",
+		DecisionsFile:      decisionsPath,
+		Silent:             true,
+		Workers:            1,
+	})
+	w.Close()
+	os.Stdout = rescueStdout
+	assert.Nil(t, err)
+
+	request := mockData["/resource_strings/head_hello"].Requests[0].Request
+	assert.Equal(t, "PATCH", request.Method)
+	var payload struct {
+		Data struct {
+			Attributes map[string]map[string]string `json:"attributes"`
+		} `json:"data"`
+	}
+	err = json.Unmarshal(request.Payload, &payload)
+	assert.Nil(t, err)
+	assert.Equal(t, "Hello", payload.Data.Attributes["strings"]["other"])
+}
+
+func TestMergeDecisionsAgainstPolicy(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+	decisionsPath := filepath.Join(tmpDir, "decisions.json")
+	err = os.WriteFile(
+		decisionsPath,
+		[]byte(`[{"resource": "projslug.resslug", "key": "hello",
+		          "resolution": "USE_HEAD"}]`),
+		0644,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The base's strings are never changed, so keeping the branch's text of a
+	// string can't be combined with a USE_BASE merge
+	mockData := getMergeConflictsMockData()
+	api := jsonapi.GetTestConnection(mockData)
+	err = MergeCommand(getStandardConfigMerge(), api, MergeCommandArguments{
+		Branch:             "feature",
+		ConflictResolution: "USE_BASE",
+		DecisionsFile:      decisionsPath,
+		Silent:             true,
+	})
+	assert.NotNil(t, err)
+	assert.True(t, strings.Contains(err.Error(), "USE_HEAD"))
+	_, exists := mockData["/resource_async_merges"]
+	assert.False(t, exists)
+	for url, endpoint := range mockData {
+		for _, request := range endpoint.Requests {
+			if request.Request.Method == "PATCH" {
+				t.Errorf("Unexpected PATCH to %s", url)
+			}
+		}
+	}
+
+	err = MergeCommand(getStandardConfigMerge(), api, MergeCommandArguments{
+		Branch:             "feature",
+		ConflictResolution: "USE_BASE",
+		Interactive:        true,
+	})
+	assert.NotNil(t, err)
+}
+
+func TestSaveMergeDecisionsCount(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	conflicts := []*mergeConflict{
+		{Resource: "projslug.resslug", Key: "hello"},
+		{Resource: "projslug.resslug", Key: "bye"},
+	}
+	decisions := map[string]string{
+		getMergeDecisionKey("projslug.resslug", "hello", ""): "USE_BASE",
+	}
+	saved, err := saveMergeDecisions(
+		filepath.Join(tmpDir, "decisions.json"), conflicts, decisions,
+	)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, saved)
+}
@@ -4,7 +4,6 @@ import (
 	"fmt"
 	"io/ioutil"
 	"path/filepath"
-	"strings"
 	"time"
 
 	"github.com/transifex/cli/pkg/txapi"
@@ -114,8 +113,8 @@ func MigrateLegacyConfigFile(
 				if resource.SourceFile == "" &&
 					resource.SourceLanguage != "" &&
 					resource.FileFilter != "" {
-					resource.SourceFile = strings.ReplaceAll(
-						resource.FileFilter, "<lang>", resource.SourceLanguage,
+					resource.SourceFile = expandFileFilter(
+						resource.FileFilter, resource.SourceLanguage,
 					)
 				}
 				resources[i] = resource
@@ -0,0 +1,247 @@
+package txlib
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/transifex/cli/internal/txlib/config"
+	"github.com/transifex/cli/pkg/txapi"
+)
+
+var (
+	utf8Bom    = []byte{0xEF, 0xBB, 0xBF}
+	utf16LeBom = []byte{0xFF, 0xFE}
+	utf16BeBom = []byte{0xFE, 0xFF}
+)
+
+// How a text file is stored on disk
+type textFormat struct {
+	// "utf-8", "utf-16le" or "utf-16be"
+	encoding string
+	bom      bool
+	// "lf", "crlf", "mixed" or "" if the file has no line breaks
+	eol string
+}
+
+/*
+Decode the contents of a text file to UTF-8 without a byte order mark.
+UTF-16 files are recognized by their byte order mark or, if 'encoding' (the
+resource's configured encoding) is UTF-16, by not looking like UTF-8;
+everything else is expected to be UTF-8.
+*/
+func decodeText(data []byte, encoding string) ([]byte, textFormat, error) {
+	var format textFormat
+	var text []byte
+	switch {
+	case bytes.HasPrefix(data, utf8Bom):
+		format = textFormat{encoding: "utf-8", bom: true}
+		text = data[len(utf8Bom):]
+	case bytes.HasPrefix(data, utf16LeBom):
+		format = textFormat{encoding: "utf-16le", bom: true}
+		text = decodeUtf16(data[len(utf16LeBom):], binary.LittleEndian)
+	case bytes.HasPrefix(data, utf16BeBom):
+		format = textFormat{encoding: "utf-16be", bom: true}
+		text = decodeUtf16(data[len(utf16BeBom):], binary.BigEndian)
+	case isUtf16Encoding(encoding) && looksLikeUtf16(data):
+		format = textFormat{encoding: normalizeEncoding(encoding)}
+		var order binary.ByteOrder = binary.LittleEndian
+		if format.encoding == "utf-16be" {
+			order = binary.BigEndian
+		}
+		text = decodeUtf16(data, order)
+	default:
+		format = textFormat{encoding: "utf-8"}
+		text = data
+	}
+	if !utf8.Valid(text) {
+		return nil, format, fmt.Errorf("file is not valid %s", format.encoding)
+	}
+	format.eol = detectEol(text)
+	return text, format, nil
+}
+
+func isUtf16Encoding(encoding string) bool {
+	return strings.HasPrefix(encoding, "utf-16")
+}
+
+// "utf-16" is stored as little endian
+func normalizeEncoding(encoding string) string {
+	if encoding == "utf-16" {
+		return "utf-16le"
+	}
+	return encoding
+}
+
+// Text without a byte order mark that can't be UTF-8: UTF-8 text never has NUL
+// bytes, which UTF-16 has for every ASCII character
+func looksLikeUtf16(data []byte) bool {
+	return len(data)%2 == 0 &&
+		(bytes.IndexByte(data, 0) != -1 || !utf8.Valid(data))
+}
+
+func decodeUtf16(data []byte, order binary.ByteOrder) []byte {
+	units := make([]uint16, len(data)/2)
+	for i := range units {
+		units[i] = order.Uint16(data[2*i:])
+	}
+	return []byte(string(utf16.Decode(units)))
+}
+
+func detectEol(text []byte) string {
+	crlf := bytes.Count(text, []byte("\r\n"))
+	lf := bytes.Count(text, []byte("\n"))
+	switch {
+	case lf == 0:
+		return ""
+	case crlf == 0:
+		return "lf"
+	case crlf == lf:
+		return "crlf"
+	default:
+		return "mixed"
+	}
+}
+
+// Encode UTF-8 'text' using 'format'. An empty 'eol' leaves line breaks as
+// they are
+func encodeText(text []byte, format textFormat) []byte {
+	switch format.eol {
+	case "lf":
+		text = bytes.ReplaceAll(text, []byte("\r\n"), []byte("\n"))
+	case "crlf":
+		text = bytes.ReplaceAll(text, []byte("\r\n"), []byte("\n"))
+		text = bytes.ReplaceAll(text, []byte("\n"), []byte("\r\n"))
+	}
+
+	var result []byte
+	switch format.encoding {
+	case "utf-16le", "utf-16be":
+		var order binary.ByteOrder = binary.LittleEndian
+		bom := utf16LeBom
+		if format.encoding == "utf-16be" {
+			order = binary.BigEndian
+			bom = utf16BeBom
+		}
+		if format.bom {
+			result = append(result, bom...)
+		}
+		unitBytes := make([]byte, 2)
+		for _, unit := range utf16.Encode([]rune(string(text))) {
+			order.PutUint16(unitBytes, unit)
+			result = append(result, unitBytes...)
+		}
+	default:
+		if format.bom {
+			result = append(result, utf8Bom...)
+		}
+		result = append(result, text...)
+	}
+	return result
+}
+
+func hasTextFormat(cfgResource *config.Resource) bool {
+	return cfgResource.Eol != "" ||
+		cfgResource.Bom != nil ||
+		cfgResource.Encoding != ""
+}
+
+// Work out how a file should be stored according to the resource's
+// configuration, keeping 'current' for whatever is not configured
+func getTextFormat(
+	cfgResource *config.Resource, current textFormat,
+) textFormat {
+	result := current
+	if cfgResource.Encoding != "" {
+		result.encoding = normalizeEncoding(cfgResource.Encoding)
+		if result.encoding != current.encoding {
+			// A UTF-16 file without a byte order mark is hard to recognize,
+			// so add one unless told otherwise
+			result.bom = result.encoding != "utf-8"
+		}
+	}
+	if cfgResource.Bom != nil {
+		result.bom = *cfgResource.Bom
+	}
+	if cfgResource.Eol != "" {
+		result.eol = cfgResource.Eol
+	} else {
+		result.eol = ""
+	}
+	return result
+}
+
+// Convert 'data' to the line endings, byte order mark and encoding configured
+// for the resource
+func normalizeText(cfgResource *config.Resource, data []byte) ([]byte, error) {
+	if !hasTextFormat(cfgResource) {
+		return data, nil
+	}
+	text, format, err := decodeText(data, cfgResource.Encoding)
+	if err != nil {
+		return nil, err
+	}
+	return encodeText(text, getTextFormat(cfgResource, format)), nil
+}
+
+// Rewrite the file at 'path' if it doesn't follow the resource's text format
+func normalizeFile(cfgResource *config.Resource, path string) error {
+	if !hasTextFormat(cfgResource) {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	normalized, err := normalizeText(cfgResource, data)
+	if err != nil {
+		return fmt.Errorf("could not normalize '%s': %w", path, err)
+	}
+	if bytes.Equal(data, normalized) {
+		return nil
+	}
+	return txapi.WriteFileAtomically(path, normalized)
+}
+
+// Describe the ways the file at 'path' doesn't follow the resource's text
+// format
+func checkTextFormat(cfgResource *config.Resource, path string) []string {
+	if !hasTextFormat(cfgResource) {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	_, format, err := decodeText(data, cfgResource.Encoding)
+	if err != nil {
+		return []string{err.Error()}
+	}
+	var problems []string
+	if cfgResource.Encoding != "" {
+		expected := normalizeEncoding(cfgResource.Encoding)
+		if format.encoding != expected {
+			problems = append(problems, fmt.Sprintf(
+				"encoding is %s instead of %s", format.encoding, expected,
+			))
+		}
+	}
+	if cfgResource.Bom != nil && format.bom != *cfgResource.Bom {
+		if format.bom {
+			problems = append(problems, "file has a byte order mark")
+		} else {
+			problems = append(problems, "file has no byte order mark")
+		}
+	}
+	if cfgResource.Eol != "" && format.eol != "" &&
+		format.eol != cfgResource.Eol {
+		problems = append(problems, fmt.Sprintf(
+			"line endings are %s instead of %s", format.eol, cfgResource.Eol,
+		))
+	}
+	return problems
+}
@@ -0,0 +1,105 @@
+package txlib
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/transifex/cli/internal/txlib/config"
+	"github.com/transifex/cli/pkg/assert"
+)
+
+func TestDecodeText(t *testing.T) {
+	text, format, err := decodeText([]byte("\xef\xbb\xbfa\r\nb\r\n"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, string(text), "a\r\nb\r\n")
+	assert.Equal(t, format, textFormat{encoding: "utf-8", bom: true, eol: "crlf"})
+
+	text, format, err = decodeText([]byte("\xff\xfea\x00\n\x00"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, string(text), "a\n")
+	assert.Equal(t, format, textFormat{encoding: "utf-16le", bom: true, eol: "lf"})
+
+	text, format, err = decodeText([]byte("\xfe\xff\x00a\x00\r\x00\n\x00b\x00\n"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, string(text), "a\r\nb\n")
+	assert.Equal(t, format, textFormat{encoding: "utf-16be", bom: true, eol: "mixed"})
+
+	// UTF-16 without a byte order mark is only expected if configured
+	text, format, err = decodeText([]byte("a\x00\n\x00"), "utf-16")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, string(text), "a\n")
+	assert.Equal(t, format, textFormat{encoding: "utf-16le", eol: "lf"})
+	text, format, err = decodeText([]byte("a\n"), "utf-16be")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, string(text), "a\n")
+	assert.Equal(t, format, textFormat{encoding: "utf-8", eol: "lf"})
+
+	_, _, err = decodeText([]byte("\xff\x00"), "")
+	if err == nil {
+		t.Error("Expected an error for invalid UTF-8")
+	}
+}
+
+func TestNormalizeText(t *testing.T) {
+	bom := false
+	cfgResource := &config.Resource{Eol: "lf", Bom: &bom, Encoding: "utf-8"}
+	result, err := normalizeText(cfgResource, []byte("\xff\xfea\x00\r\x00\n\x00"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, string(result), "a\n")
+
+	cfgResource = &config.Resource{Eol: "crlf", Encoding: "utf-16"}
+	result, err = normalizeText(cfgResource, []byte("a\nb\r\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, string(result), "\xff\xfea\x00\r\x00\n\x00b\x00\r\x00\n\x00")
+
+	bom = true
+	cfgResource = &config.Resource{Bom: &bom}
+	result, err = normalizeText(cfgResource, []byte("a\r\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, string(result), "\xef\xbb\xbfa\r\n")
+
+	data := []byte("\xef\xbb\xbfa\r\n")
+	result, err = normalizeText(&config.Resource{}, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, string(result), string(data))
+}
+
+func TestCheckTextFormat(t *testing.T) {
+	afterTest := beforeTest(t, nil, nil)
+	defer afterTest()
+
+	err := os.WriteFile("aaa.json", []byte("\xef\xbb\xbfa\r\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bom := false
+	cfgResource := &config.Resource{Eol: "lf", Bom: &bom, Encoding: "utf-8"}
+	problems := checkTextFormat(cfgResource, "aaa.json")
+	assert.Equal(
+		t,
+		strings.Join(problems, ", "),
+		"file has a byte order mark, line endings are crlf instead of lf",
+	)
+
+	assert.Equal(t, len(checkTextFormat(&config.Resource{}, "aaa.json")), 0)
+}
@@ -0,0 +1,471 @@
+package txlib
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/fatih/color"
+	"github.com/transifex/cli/internal/txlib/config"
+	"gopkg.in/yaml.v3"
+)
+
+type PseudoCommandArguments struct {
+	ResourceIds []string
+	Languages   []string
+	// Any of "accents", "expand", "brackets" and "rtl"
+	Strategies []string
+	// Percentage to make strings longer by, with the "expand" strategy
+	Expansion int
+	Silent    bool
+}
+
+var PseudoStrategies = []string{"accents", "expand", "brackets", "rtl"}
+
+/*
+Generate pseudo-localized files from the local source files, without talking to
+Transifex. The files are saved where 'tx pull --pseudo' would save them, ie the
+file filter with '_pseudo' added after the language, eg '<lang>_pseudo'.
+*/
+func PseudoCommand(cfg *config.Config, args *PseudoCommandArguments) error {
+	for _, strategy := range args.Strategies {
+		if !stringSliceContains(PseudoStrategies, strategy) {
+			return fmt.Errorf(
+				"unknown pseudo-localization strategy '%s'; use any of %s",
+				strategy, strings.Join(PseudoStrategies, ", "),
+			)
+		}
+	}
+	options := pseudoOptions{
+		accents:  stringSliceContains(args.Strategies, "accents"),
+		brackets: stringSliceContains(args.Strategies, "brackets"),
+		rtl:      stringSliceContains(args.Strategies, "rtl"),
+	}
+	if stringSliceContains(args.Strategies, "expand") {
+		options.expansion = args.Expansion
+	}
+
+	cfgResources, err := figureOutResources(args.ResourceIds, cfg)
+	if err != nil {
+		return err
+	}
+	sort.Slice(cfgResources, func(i, j int) bool {
+		return cfgResources[i].GetAPv3Id() < cfgResources[j].GetAPv3Id()
+	})
+
+	var names []string
+	for _, cfgResource := range cfgResources {
+		prefix := fmt.Sprintf(
+			"%s.%s", cfgResource.ProjectSlug, cfgResource.ResourceSlug,
+		)
+		if !supportsPseudo(cfgResource.Type) {
+			if !args.Silent {
+				fmt.Printf(
+					"%s - Local pseudo-localization is not supported for "+
+						"'%s' files, skipping\n",
+					prefix, cfgResource.Type,
+				)
+			}
+			continue
+		}
+		err := checkFileFilter(cfgResource.FileFilter)
+		if err != nil {
+			return fmt.Errorf("%s: %w", prefix, err)
+		}
+		if hasFileFilterWildcards(cfgResource.FileFilter) {
+			return fmt.Errorf(
+				"%s: cannot create files from a file filter with wildcards",
+				prefix,
+			)
+		}
+
+		languages := args.Languages
+		if len(languages) == 0 {
+			localFiles := searchFileFilter(
+				".", cfgResource.FileFilter, cfgResource.FileFilterExclude...,
+			)
+			for languageCode := range localFiles {
+				if !strings.HasSuffix(languageCode, "_pseudo") {
+					languages = append(languages, languageCode)
+				}
+			}
+			sort.Strings(languages)
+		}
+		if len(languages) == 0 {
+			if !args.Silent {
+				fmt.Printf(
+					"%s - No local translation files found, use "+
+						"'--languages' to choose which files to create\n",
+					prefix,
+				)
+			}
+			continue
+		}
+
+		source, err := os.ReadFile(cfgResource.SourceFile)
+		if err != nil {
+			return fmt.Errorf("%s: %w", prefix, err)
+		}
+		content, err := pseudoLocalizeFile(cfgResource.Type, source, options)
+		if err != nil {
+			return fmt.Errorf(
+				"%s: could not pseudo-localize '%s': %w",
+				prefix, cfgResource.SourceFile, err,
+			)
+		}
+
+		for _, languageCode := range languages {
+			filePath := expandFileFilter(
+				pseudoFileFilter(cfgResource.FileFilter), languageCode,
+			)
+			err = os.MkdirAll(filepath.Dir(filePath), os.ModePerm)
+			if err != nil {
+				return err
+			}
+			err = os.WriteFile(filePath, content, 0644)
+			if err != nil {
+				return err
+			}
+			if !args.Silent {
+				fmt.Printf(
+					"%s %s - Saved '%s'\n",
+					prefix,
+					color.New(color.FgCyan).Sprint("["+languageCode+"]"),
+					filePath,
+				)
+			}
+			names = append(names, filePath)
+		}
+	}
+	if args.Silent && len(names) > 0 {
+		fmt.Printf("Saved pseudo-localized files: %s\n", strings.Join(names, ", "))
+	}
+	return nil
+}
+
+func supportsPseudo(fileType string) bool {
+	return supportsFallbacks(fileType)
+}
+
+type pseudoOptions struct {
+	accents   bool
+	expansion int
+	brackets  bool
+	rtl       bool
+}
+
+var pseudoAccents = map[rune]rune{
+	'a': 'á', 'b': 'ƀ', 'c': 'ç', 'd': 'ď', 'e': 'é', 'f': 'ƒ', 'g': 'ğ',
+	'h': 'ĥ', 'i': 'í', 'j': 'ĵ', 'k': 'ķ', 'l': 'ĺ', 'm': 'ɱ', 'n': 'ñ',
+	'o': 'ó', 'p': 'þ', 'q': 'ǫ', 'r': 'ŕ', 's': 'š', 't': 'ţ', 'u': 'ú',
+	'v': 'ṽ', 'w': 'ŵ', 'x': 'ẋ', 'y': 'ý', 'z': 'ž',
+	'A': 'Á', 'B': 'Ɓ', 'C': 'Ç', 'D': 'Ď', 'E': 'É', 'F': 'Ƒ', 'G': 'Ğ',
+	'H': 'Ĥ', 'I': 'Í', 'J': 'Ĵ', 'K': 'Ķ', 'L': 'Ĺ', 'M': 'Ṁ', 'N': 'Ñ',
+	'O': 'Ó', 'P': 'Þ', 'Q': 'Ǫ', 'R': 'Ŕ', 'S': 'Š', 'T': 'Ţ', 'U': 'Ú',
+	'V': 'Ṽ', 'W': 'Ŵ', 'X': 'Ẋ', 'Y': 'Ý', 'Z': 'Ž',
+}
+
+// Placeholders that must be kept as they are: printf-style ('%s', '%1$d',
+// '%(name)s'), HTML tags and entities, backslash escapes and, with the ICU
+// plural syntax, '#'. The expression is anchored as it is matched at every
+// position of the string
+var pseudoPlaceholderRegex = regexp.MustCompile(
+	`^(?:%(?:\([^)]+\)|\d+\$)?[-+ 0#]*(?:\d+|\*)?(?:\.\d+)?[hlLqjzt]*` +
+		`[diouxXeEfFgGaAcspn@%]` +
+		`|</?[a-zA-Z][^<>]*>` +
+		`|&(?:[a-zA-Z]+|#\d+|#x[0-9a-fA-F]+);` +
+		`|\\.)`,
+)
+
+var icuArgumentRegex = regexp.MustCompile(
+	`^\s*[\w.]+\s*,\s*(plural|select|selectordinal)\s*,`,
+)
+
+const rightToLeftOverride = "\u202e"
+const popDirectionalFormatting = "\u202c"
+
+/*
+Pseudo-localize a single string. Placeholders are kept intact; the text around
+them is accented, wrapped in right-to-left markers and padded as requested, and
+the whole string is finally put in brackets.
+*/
+func pseudoLocalize(text string, options pseudoOptions) string {
+	if text == "" {
+		return text
+	}
+	result, textLength := pseudoLocalizeMessage(text, options, false)
+	if options.expansion > 0 && textLength > 0 {
+		padding := (textLength*options.expansion + 99) / 100
+		result += " " + strings.Repeat("~", padding)
+	}
+	if options.brackets {
+		result = "[" + result + "]"
+	}
+	return result
+}
+
+/*
+Pseudo-localize 'text', keeping placeholders and the structure of ICU messages
+intact. Return the result along with the number of characters of actual text
+that were found, so that the caller can figure out the expansion.
+*/
+func pseudoLocalizeMessage(
+	text string, options pseudoOptions, inPlural bool,
+) (string, int) {
+	var builder strings.Builder
+	textLength := 0
+
+	var segment strings.Builder
+	flush := func() {
+		if segment.Len() == 0 {
+			return
+		}
+		value := segment.String()
+		segment.Reset()
+		textLength += utf8.RuneCountInString(strings.TrimSpace(value))
+		if options.accents {
+			value = strings.Map(func(r rune) rune {
+				accented, exists := pseudoAccents[r]
+				if exists {
+					return accented
+				}
+				return r
+			}, value)
+		}
+		if options.rtl && strings.TrimSpace(value) != "" {
+			value = rightToLeftOverride + value + popDirectionalFormatting
+		}
+		builder.WriteString(value)
+	}
+
+	for position := 0; position < len(text); {
+		if text[position] == '{' {
+			end := findClosingBrace(text, position)
+			if end != -1 {
+				flush()
+				inner := text[position+1 : end]
+				if icuArgumentRegex.MatchString(inner) {
+					message, length := pseudoLocalizeIcu(inner, options)
+					builder.WriteString("{" + message + "}")
+					textLength += length
+				} else {
+					builder.WriteString(text[position : end+1])
+				}
+				position = end + 1
+				continue
+			}
+		}
+		if inPlural && text[position] == '#' {
+			flush()
+			builder.WriteByte('#')
+			position++
+			continue
+		}
+		location := pseudoPlaceholderRegex.FindStringIndex(text[position:])
+		if location != nil {
+			flush()
+			builder.WriteString(text[position : position+location[1]])
+			position += location[1]
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(text[position:])
+		segment.WriteRune(r)
+		position += size
+	}
+	flush()
+	return builder.String(), textLength
+}
+
+/*
+Pseudo-localize the inside of an ICU argument like
+'count, plural, one {# item} other {# items}': the sub-messages are
+pseudo-localized, their selectors are kept.
+*/
+func pseudoLocalizeIcu(inner string, options pseudoOptions) (string, int) {
+	match := icuArgumentRegex.FindStringSubmatch(inner)
+	header := match[0]
+	// '#' stands for the number in plural sub-messages only
+	inPlural := match[1] != "select"
+	var builder strings.Builder
+	builder.WriteString(header)
+	textLength := 0
+	rest := inner[len(header):]
+	for position := 0; position < len(rest); {
+		if rest[position] == '{' {
+			end := findClosingBrace(rest, position)
+			if end == -1 {
+				builder.WriteString(rest[position:])
+				break
+			}
+			message, length := pseudoLocalizeMessage(
+				rest[position+1:end], options, inPlural,
+			)
+			builder.WriteString("{" + message + "}")
+			if length > textLength {
+				textLength = length
+			}
+			position = end + 1
+			continue
+		}
+		builder.WriteByte(rest[position])
+		position++
+	}
+	return builder.String(), textLength
+}
+
+func findClosingBrace(text string, start int) int {
+	depth := 0
+	for position := start; position < len(text); position++ {
+		switch text[position] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return position
+			}
+		}
+	}
+	return -1
+}
+
+/*
+Return a pseudo-localized copy of a source file of type 'fileType'.
+*/
+func pseudoLocalizeFile(
+	fileType string, data []byte, options pseudoOptions,
+) ([]byte, error) {
+	switch fileType {
+	case "KEYVALUEJSON", "STRUCTURED_JSON":
+		value, err := decodeOrderedJson(data)
+		if err != nil {
+			return nil, err
+		}
+		pseudoLocalizeJson(value, options, fileType == "STRUCTURED_JSON", "")
+		var buffer bytes.Buffer
+		encodeOrderedJson(&buffer, value, detectJsonIndent(data), "")
+		buffer.WriteString("\n")
+		return buffer.Bytes(), nil
+	case "YML", "YML_KEY", "YAML_GENERIC":
+		var document yaml.Node
+		err := yaml.Unmarshal(data, &document)
+		if err != nil {
+			return nil, err
+		}
+		if document.Kind != yaml.DocumentNode || len(document.Content) == 0 {
+			return nil, errors.New("YAML file is empty")
+		}
+		pseudoLocalizeYaml(document.Content[0], options)
+		var buffer bytes.Buffer
+		encoder := yaml.NewEncoder(&buffer)
+		encoder.SetIndent(2)
+		err = encoder.Encode(&document)
+		if err != nil {
+			return nil, err
+		}
+		err = encoder.Close()
+		if err != nil {
+			return nil, err
+		}
+		return buffer.Bytes(), nil
+	case "ANDROID":
+		return []byte(pseudoLocalizeAndroid(string(data), options)), nil
+	}
+	return nil, fmt.Errorf(
+		"local pseudo-localization is not supported for '%s' files", fileType,
+	)
+}
+
+func pseudoLocalizeJson(
+	value interface{}, options pseudoOptions, structured bool, key string,
+) interface{} {
+	switch typed := value.(type) {
+	case *jsonObject:
+		for _, childKey := range typed.keys {
+			typed.values[childKey] = pseudoLocalizeJson(
+				typed.values[childKey], options, structured, childKey,
+			)
+		}
+	case []interface{}:
+		for index, item := range typed {
+			typed[index] = pseudoLocalizeJson(item, options, structured, key)
+		}
+	case string:
+		// In STRUCTURED_JSON, only the 'string' field holds the text, the
+		// rest are metadata like 'context' and 'developer_comment'
+		if !structured || key == "string" {
+			return pseudoLocalize(typed, options)
+		}
+	}
+	return value
+}
+
+func pseudoLocalizeYaml(node *yaml.Node, options pseudoOptions) {
+	switch node.Kind {
+	case yaml.MappingNode:
+		// Only values are pseudo-localized, keys are kept as they are
+		for index := 1; index < len(node.Content); index += 2 {
+			pseudoLocalizeYaml(node.Content[index], options)
+		}
+	case yaml.SequenceNode:
+		for _, child := range node.Content {
+			pseudoLocalizeYaml(child, options)
+		}
+	case yaml.ScalarNode:
+		if node.Tag == "!!str" {
+			node.Value = pseudoLocalize(node.Value, options)
+		}
+	}
+}
+
+var androidItemRegex = regexp.MustCompile(`(?s)(<item\b[^>]*>)(.*?)(</item>)`)
+
+func pseudoLocalizeAndroid(data string, options pseudoOptions) string {
+	var builder strings.Builder
+	last := 0
+	for _, element := range findAndroidElements(data) {
+		text := element.text(data)
+		if element.isEmpty() || strings.Contains(text, `translatable="false"`) {
+			continue
+		}
+		builder.WriteString(data[last:element.start])
+		contentStart := element.start + strings.Index(text, ">") + 1
+		contentEnd := contentStart + len(element.content)
+		builder.WriteString(data[element.start:contentStart])
+		if element.kind == "string" {
+			builder.WriteString(pseudoLocalizeAndroidText(element.content, options))
+		} else {
+			builder.WriteString(androidItemRegex.ReplaceAllStringFunc(
+				element.content,
+				func(item string) string {
+					parts := androidItemRegex.FindStringSubmatch(item)
+					return parts[1] +
+						pseudoLocalizeAndroidText(parts[2], options) +
+						parts[3]
+				},
+			))
+		}
+		builder.WriteString(data[contentEnd:element.end])
+		last = element.end
+	}
+	builder.WriteString(data[last:])
+	return builder.String()
+}
+
+/*
+Pseudo-localize the content of an Android string, which may be wrapped in
+double quotes to preserve whitespace.
+*/
+func pseudoLocalizeAndroidText(content string, options pseudoOptions) string {
+	if len(content) >= 2 && strings.HasPrefix(content, `"`) &&
+		strings.HasSuffix(content, `"`) {
+		return `"` + pseudoLocalize(content[1:len(content)-1], options) + `"`
+	}
+	return pseudoLocalize(content, options)
+}
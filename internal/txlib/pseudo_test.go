@@ -0,0 +1,133 @@
+package txlib
+
+import (
+	"os"
+	"testing"
+
+	"github.com/transifex/cli/pkg/assert"
+)
+
+func TestPseudoLocalizeAccentsAndBrackets(t *testing.T) {
+	options := pseudoOptions{accents: true, brackets: true}
+	assert.Equal(t, pseudoLocalize("Hello world", options), "[Ĥéĺĺó ŵóŕĺď]")
+}
+
+func TestPseudoLocalizeKeepsPlaceholders(t *testing.T) {
+	options := pseudoOptions{accents: true}
+	assert.Equal(
+		t,
+		pseudoLocalize("Hi %s, you have %1$d <b>new</b> &amp; {name}", options),
+		"Ĥí %s, ýóú ĥáṽé %1$d <b>ñéŵ</b> &amp; {name}",
+	)
+	assert.Equal(
+		t,
+		pseudoLocalize("Hello %(name)s\\n", options),
+		"Ĥéĺĺó %(name)s\\n",
+	)
+}
+
+func TestPseudoLocalizeIcu(t *testing.T) {
+	options := pseudoOptions{accents: true}
+	assert.Equal(
+		t,
+		pseudoLocalize(
+			"{count, plural, one {# item} other {# items for {user}}}", options,
+		),
+		"{count, plural, one {# íţéɱ} other {# íţéɱš ƒóŕ {user}}}",
+	)
+	assert.Equal(
+		t,
+		pseudoLocalize("{gender, select, male {He} other {#They}}", options),
+		"{gender, select, male {Ĥé} other {#Ţĥéý}}",
+	)
+}
+
+func TestPseudoLocalizeExpansion(t *testing.T) {
+	options := pseudoOptions{expansion: 50}
+	assert.Equal(t, pseudoLocalize("abcd %s", options), "abcd %s ~~")
+	assert.Equal(t, pseudoLocalize("%s", options), "%s")
+}
+
+func TestPseudoLocalizeRtl(t *testing.T) {
+	options := pseudoOptions{rtl: true}
+	assert.Equal(
+		t,
+		pseudoLocalize("Hi %s", options),
+		rightToLeftOverride+"Hi "+popDirectionalFormatting+"%s",
+	)
+}
+
+func TestPseudoLocalizeStructuredJson(t *testing.T) {
+	source := `{
+  "hello": {"string": "Hello", "context": "greeting"}
+}`
+	result, err := pseudoLocalizeFile(
+		"STRUCTURED_JSON", []byte(source), pseudoOptions{brackets: true},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, string(result), `{
+  "hello": {
+    "string": "[Hello]",
+    "context": "greeting"
+  }
+}
+`)
+}
+
+func TestPseudoLocalizeAndroid(t *testing.T) {
+	source := `<resources>
+    <string name="app_id" translatable="false">com.example</string>
+    <string name="hello">Hello <xliff:g id="name">%s</xliff:g></string>
+    <plurals name="items">
+        <item quantity="one">One item</item>
+    </plurals>
+</resources>
+`
+	result, err := pseudoLocalizeFile(
+		"ANDROID", []byte(source), pseudoOptions{brackets: true},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, string(result), `<resources>
+    <string name="app_id" translatable="false">com.example</string>
+    <string name="hello">[Hello <xliff:g id="name">%s</xliff:g>]</string>
+    <plurals name="items">
+        <item quantity="one">[One item]</item>
+    </plurals>
+</resources>
+`)
+}
+
+func TestPseudoCommand(t *testing.T) {
+	afterTest := beforeTest(t, []string{"el", "fr"}, nil)
+	defer afterTest()
+
+	err := os.WriteFile("aaa.json", []byte(`{"hello": "Hello"}`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := getStandardConfig()
+	cfg.Local.Resources[0].Type = "KEYVALUEJSON"
+
+	err = PseudoCommand(cfg, &PseudoCommandArguments{
+		Strategies: []string{"accents", "brackets"},
+		Silent:     true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "{\n  \"hello\": \"[Ĥéĺĺó]\"\n}"
+	assertFileContent(t, "aaa-el_pseudo.json", expected)
+	assertFileContent(t, "aaa-fr_pseudo.json", expected)
+
+	err = PseudoCommand(cfg, &PseudoCommandArguments{
+		Strategies: []string{"mirror"},
+	})
+	if err == nil {
+		t.Error("Expected an error for an unknown strategy")
+	}
+}
@@ -1,9 +1,11 @@
 package txlib
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 	"sort"
 	"strings"
 	"time"
@@ -34,19 +36,37 @@ type PullCommandArguments struct {
 	Workers           int
 	Silent            bool
 	Pseudo            bool
+	Atomic            bool
+	Diff              bool
+	DiffStat          bool
+	Archive           string
+	// "strings" or "words"; if empty, the resource's setting is used
+	MinimumPercentageUnit string
 }
 
+// Returned by PullCommand in diff mode when the downloaded files differ from
+// the local ones
+var ErrPullDiffChanges = errors.New("pulled files differ from the local files")
+
 func PullCommand(
 	cfg *config.Config,
 	api *jsonapi.Connection,
 	args *PullCommandArguments,
 ) error {
-	args.Branch = figureOutBranch(args.Branch)
+	var err error
+	requestedBranch := args.Branch
+	args.Branch, err = figureOutBranch(args.Branch)
+	if err != nil {
+		return err
+	}
 	cfgResources, err := figureOutResources(args.ResourceIds, cfg)
 	if err != nil {
 		return err
 	}
-	applyBranchToResources(cfgResources, args.Branch)
+	applyBranchToResources(
+		api, cfg, cfgResources, args.Branch,
+		getLegacyBranch(requestedBranch, args.Branch),
+	)
 	sort.Slice(cfgResources, func(i, j int) bool {
 		return cfgResources[i].GetAPv3Id() < cfgResources[j].GetAPv3Id()
 	})
@@ -55,11 +75,38 @@ func PullCommand(
 		fmt.Print("# Getting info about resources\n\n")
 	}
 
+	var staging *pullStaging
+	if args.Archive != "" {
+		_, err = getArchiveFormat(args.Archive)
+		if err != nil {
+			return err
+		}
+		// The working tree is not touched, so local timestamps are irrelevant
+		args.Force = true
+		stagingDir, err := os.MkdirTemp("", "tx-pull-archive-")
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(stagingDir)
+		staging = newPullStagingInDir(stagingDir)
+	} else if args.Diff {
+		stagingDir, err := os.MkdirTemp("", "tx-pull-diff-")
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(stagingDir)
+		staging = newPullStagingInDir(stagingDir)
+	} else if args.Atomic {
+		staging = newPullStaging()
+	}
+
 	filePullTaskChannel := make(chan *FilePullTask)
 	var filePullTasks []*FilePullTask
 	pool := worker_pool.New(args.Workers, len(cfgResources), args.Silent)
 	for _, cfgResource := range cfgResources {
-		pool.Add(&ResourcePullTask{cfgResource, api, args, filePullTaskChannel, cfg})
+		pool.Add(&ResourcePullTask{
+			cfgResource, api, args, filePullTaskChannel, cfg, staging,
+		})
 	}
 	pool.Start()
 
@@ -110,8 +157,32 @@ func PullCommand(
 		<-pool.Wait()
 
 		if pool.IsAborted {
+			if staging != nil {
+				staging.rollback()
+				if args.Atomic && !args.Silent {
+					fmt.Print("\nNo local files were changed\n")
+				}
+			}
 			return errors.New("Aborted")
 		}
+		if staging != nil && !args.Diff && args.Archive == "" {
+			err = staging.commit()
+			if err != nil {
+				return err
+			}
+		}
+		if !args.Pseudo && args.FileType == "default" {
+			err = applyLanguageFallbacks(cfg, filePullTasks, staging, args)
+			if err != nil {
+				return err
+			}
+		}
+		if !args.Diff && args.Archive == "" {
+			err = runPostPullHooks(cfg, filePullTasks, args)
+			if err != nil {
+				return err
+			}
+		}
 		if args.Silent {
 			var names []string
 			for _, filePullTask := range filePullTasks {
@@ -131,15 +202,318 @@ func PullCommand(
 		}
 	}
 
+	if args.Archive != "" {
+		count, err := writePullArchive(staging, args.Archive)
+		if err != nil {
+			return err
+		}
+		if !args.Silent {
+			fmt.Printf("\nWrote %d file(s) to '%s'\n", count, args.Archive)
+		}
+		return nil
+	}
+
+	if args.Diff {
+		return printPullDiff(staging, args)
+	}
+
 	return nil
 }
 
+/*
+Fill the missing or empty translations of every pulled file whose language has
+a fallback chain configured, from the files of the fallback languages. In diff
+and archive mode the files are read from and written to the staging area.
+*/
+func applyLanguageFallbacks(
+	cfg *config.Config, filePullTasks []*FilePullTask, staging *pullStaging,
+	args *PullCommandArguments,
+) error {
+	stagedFiles := make(map[string]string)
+	if staging != nil && (args.Diff || args.Archive != "") {
+		for filePath, stagedPath := range staging.stagedFiles() {
+			stagedFiles[filepath.Clean(filePath)] = stagedPath
+		}
+	}
+	resolve := func(filePath string) string {
+		stagedPath, exists := stagedFiles[filepath.Clean(filePath)]
+		if exists {
+			return stagedPath
+		}
+		return filePath
+	}
+
+	printedHeader := false
+	var names []string
+	for _, task := range filePullTasks {
+		if task.pulledFile == "" || task.languageCode == "" {
+			continue
+		}
+		cfgResource := task.cfgResource
+		localLanguageCode, exists := task.remoteToLocalLanguageMappings[task.languageCode]
+		if !exists {
+			localLanguageCode = task.languageCode
+		}
+		chain := cfg.Local.GetFallbacks(cfgResource, localLanguageCode)
+		if len(chain) == 0 {
+			chain = cfg.Local.GetFallbacks(cfgResource, task.languageCode)
+		}
+		if len(chain) == 0 {
+			continue
+		}
+
+		if !args.Silent && !printedHeader {
+			fmt.Print("\n# Applying language fallbacks\n\n")
+			printedHeader = true
+		}
+		prefix := fmt.Sprintf(
+			"%s.%s %s",
+			cfgResource.ProjectSlug,
+			cfgResource.ResourceSlug,
+			color.New(color.FgCyan).Sprint("["+localLanguageCode+"]"),
+		)
+
+		if !supportsFallbacks(cfgResource.Type) {
+			if !args.Silent {
+				fmt.Printf(
+					"%s - Language fallbacks are not supported for '%s' "+
+						"files, skipping\n",
+					prefix, cfgResource.Type,
+				)
+			}
+			continue
+		}
+
+		var fallbacks [][]byte
+		var used []string
+		for _, languageCode := range chain {
+			var fallbackPath string
+			if languageCode == "source" ||
+				languageCode == cfgResource.SourceLanguage {
+				fallbackPath = cfgResource.SourceFile
+			} else if override, exists := cfgResource.Overrides[languageCode]; exists {
+				fallbackPath = override
+			} else if hasFileFilterWildcards(cfgResource.FileFilter) {
+				fallbackPath = searchFileFilter(
+					".", cfgResource.FileFilter, cfgResource.FileFilterExclude...,
+				)[languageCode]
+				if fallbackPath == "" {
+					continue
+				}
+			} else {
+				fallbackPath = expandFileFilter(
+					cfgResource.FileFilter, languageCode,
+				)
+			}
+			data, err := os.ReadFile(resolve(fallbackPath))
+			if os.IsNotExist(err) {
+				continue
+			} else if err != nil {
+				return err
+			}
+			data, _, err = decodeText(data, cfgResource.Encoding)
+			if err != nil {
+				return fmt.Errorf("could not read '%s': %w", fallbackPath, err)
+			}
+			if len(bytes.TrimSpace(data)) == 0 {
+				continue
+			}
+			fallbacks = append(fallbacks, data)
+			used = append(used, languageCode)
+		}
+		if len(fallbacks) == 0 {
+			continue
+		}
+
+		targetPath := resolve(task.pulledFile)
+		filled, err := func() (int, error) {
+			data, err := os.ReadFile(targetPath)
+			if err != nil {
+				return 0, err
+			}
+			text, format, err := decodeText(data, cfgResource.Encoding)
+			if err != nil {
+				return 0, err
+			}
+			text, filled, err := fillFromFallbacks(cfgResource.Type, text, fallbacks)
+			if err != nil || filled == 0 {
+				return 0, err
+			}
+			data, err = normalizeText(cfgResource, encodeText(text, format))
+			if err != nil {
+				return 0, err
+			}
+			return filled, os.WriteFile(targetPath, data, 0644)
+		}()
+		if err != nil {
+			err = fmt.Errorf(
+				"could not apply language fallbacks to '%s': %w",
+				task.pulledFile, err,
+			)
+			if !args.Skip {
+				return err
+			}
+			fmt.Printf("%s - %s\n", prefix, err)
+			continue
+		}
+
+		if !args.Silent {
+			fmt.Printf(
+				"%s - Filled %d key(s) from %s\n",
+				prefix, filled, strings.Join(used, ", "),
+			)
+		}
+		names = append(names, fmt.Sprintf(
+			"%s: %s (%d)", cfgResource.ResourceSlug, localLanguageCode, filled,
+		))
+	}
+	if args.Silent && len(names) > 0 {
+		fmt.Printf("Filled keys from fallbacks: %s\n", strings.Join(names, ", "))
+	}
+	return nil
+}
+
+/*
+Run the 'post_pull_file' hook for every file that was pulled and then the
+'post_pull' hook for every resource that had at least one file pulled. Hooks
+only run once the files are in their final place.
+*/
+func runPostPullHooks(
+	cfg *config.Config, filePullTasks []*FilePullTask, args *PullCommandArguments,
+) error {
+	var fileHookTasks, resourceHookTasks []*HookTask
+	resourcesSeen := make(map[string]bool)
+	for _, filePullTask := range filePullTasks {
+		if filePullTask.pulledFile == "" {
+			continue
+		}
+		cfgResource := filePullTask.cfgResource
+		hooks := cfg.Local.GetHooks(cfgResource)
+		languageCode := filePullTask.languageCode
+		if languageCode == "" {
+			languageCode = getStatsLanguageCode(filePullTask.stats)
+		}
+		if hooks.PostPullFile != "" {
+			fileHookTasks = append(fileHookTasks, &HookTask{
+				"post_pull_file",
+				hooks.PostPullFile,
+				cfgResource,
+				languageCode,
+				filepath.Clean(filePullTask.pulledFile),
+				args.Skip,
+				args.Silent,
+			})
+		}
+		if hooks.PostPull != "" && !resourcesSeen[cfgResource.GetAPv3Id()] {
+			resourcesSeen[cfgResource.GetAPv3Id()] = true
+			resourceHookTasks = append(resourceHookTasks, &HookTask{
+				"post_pull", hooks.PostPull, cfgResource, "", "",
+				args.Skip, args.Silent,
+			})
+		}
+	}
+
+	if len(fileHookTasks) > 0 || len(resourceHookTasks) > 0 {
+		if !args.Silent {
+			fmt.Print("\n# Running hooks\n\n")
+		}
+	}
+	for _, hookTasks := range [][]*HookTask{fileHookTasks, resourceHookTasks} {
+		if len(hookTasks) == 0 {
+			continue
+		}
+		pool := worker_pool.New(args.Workers, len(hookTasks), args.Silent)
+		for _, hookTask := range hookTasks {
+			pool.Add(hookTask)
+		}
+		pool.Start()
+		<-pool.Wait()
+		if pool.IsAborted {
+			return errors.New("Aborted")
+		}
+	}
+	return nil
+}
+
+/*
+Compare the files downloaded in the staging area against the local files and
+print either a unified diff or, if 'args.DiffStat' is set, a summary of the
+changed lines per file. Return ErrPullDiffChanges if there are any changes.
+*/
+func printPullDiff(staging *pullStaging, args *PullCommandArguments) error {
+	stagedFiles := staging.stagedFiles()
+	var filePaths []string
+	for filePath := range stagedFiles {
+		filePaths = append(filePaths, filePath)
+	}
+	sort.Strings(filePaths)
+
+	if !args.Silent {
+		fmt.Print("\n# Changes\n\n")
+	}
+
+	changedFiles, totalAdded, totalRemoved := 0, 0, 0
+	for _, filePath := range filePaths {
+		newContent, err := os.ReadFile(stagedFiles[filePath])
+		if err != nil {
+			return err
+		}
+		name := filepath.ToSlash(filepath.Clean(filePath))
+		leftName := "a/" + name
+		oldContent, err := os.ReadFile(filePath)
+		if err != nil {
+			if !os.IsNotExist(err) {
+				return err
+			}
+			leftName = "/dev/null"
+		}
+		if err == nil && bytes.Equal(oldContent, newContent) {
+			continue
+		}
+
+		diff, added, removed := unifiedDiff(
+			leftName, "b/"+name, string(oldContent), string(newContent), 3,
+		)
+		changedFiles++
+		totalAdded += added
+		totalRemoved += removed
+		if args.DiffStat {
+			fmt.Printf(
+				" %s | %s %s\n",
+				name,
+				color.GreenString("+%d", added),
+				color.RedString("-%d", removed),
+			)
+		} else if diff == "" {
+			fmt.Printf("--- %s\n+++ b/%s\n(only whitespace at the end of the "+
+				"file differs)\n", leftName, name)
+		} else {
+			fmt.Print(colorizeDiff(diff))
+		}
+	}
+
+	if changedFiles == 0 {
+		if !args.Silent {
+			fmt.Println("No changes")
+		}
+		return nil
+	}
+	if args.DiffStat {
+		fmt.Printf(
+			" %d file(s) changed, %d insertion(s)(+), %d deletion(s)(-)\n",
+			changedFiles, totalAdded, totalRemoved,
+		)
+	}
+	return ErrPullDiffChanges
+}
+
 type ResourcePullTask struct {
 	cfgResource         *config.Resource
 	api                 *jsonapi.Connection
 	args                *PullCommandArguments
 	filePullTaskChannel chan *FilePullTask
 	cfg                 *config.Config
+	staging             *pullStaging
 }
 
 func (task *ResourcePullTask) Run(send func(string), abort func()) {
@@ -148,6 +522,7 @@ func (task *ResourcePullTask) Run(send func(string), abort func()) {
 	args := task.args
 	filePullTaskChannel := task.filePullTaskChannel
 	cfg := task.cfg
+	staging := task.staging
 
 	sendMessage := func(body string, force bool) {
 		if args.Silent && !force {
@@ -241,6 +616,18 @@ func (task *ResourcePullTask) Run(send func(string), abort func()) {
 		return
 	}
 
+	var remoteLanguageCodes []string
+	for languageId := range stats {
+		remoteLanguageCodes = append(
+			remoteLanguageCodes, strings.TrimPrefix(languageId, "l:"),
+		)
+	}
+	sort.Strings(remoteLanguageCodes)
+	addConventionRemoteLanguageMappings(
+		*cfg, *cfgResource, remoteLanguageCodes,
+		localToRemoteLanguageMappings, remoteToLocalLanguageMappings,
+	)
+
 	if args.Source {
 		filePullTaskChannel <- &FilePullTask{
 			cfgResource,
@@ -251,6 +638,8 @@ func (task *ResourcePullTask) Run(send func(string), abort func()) {
 			stats[sourceLanguage.Id],
 			"",
 			remoteToLocalLanguageMappings,
+			staging,
+			"",
 		}
 	}
 
@@ -271,14 +660,20 @@ func (task *ResourcePullTask) Run(send func(string), abort func()) {
 		}
 		fileFilter := setFileTypeExtensions(args.FileType, cfgResource.FileFilter)
 		if args.Pseudo {
-			fileFilter = strings.Replace(
-				fileFilter,
-				"<lang>",
-				"<lang>_pseudo",
-				-1,
-			)
+			fileFilter = pseudoFileFilter(fileFilter)
+		}
+		localFiles := searchFileFilter(
+			".", fileFilter, cfgResource.FileFilterExclude...,
+		)
+		localLanguageCodes := getLocalLanguageCodes(localFiles)
+		for localLanguageCode := range cfgResource.Overrides {
+			localLanguageCodes = append(localLanguageCodes, localLanguageCode)
 		}
-		localFiles := searchFileFilter(".", fileFilter)
+		sort.Strings(localLanguageCodes)
+		addConventionLanguageMappings(
+			*cfg, *cfgResource, localLanguageCodes,
+			localToRemoteLanguageMappings, remoteToLocalLanguageMappings,
+		)
 
 		for localLanguageCode, filePath := range cfgResource.Overrides {
 			filePath = setFileTypeExtensions(args.FileType, filePath)
@@ -350,6 +745,8 @@ func (task *ResourcePullTask) Run(send func(string), abort func()) {
 				info.stats,
 				info.filePath,
 				remoteToLocalLanguageMappings,
+				staging,
+				"",
 			}
 		}
 	}
@@ -365,6 +762,11 @@ type FilePullTask struct {
 	stats                         *jsonapi.Resource
 	filePath                      string
 	remoteToLocalLanguageMappings map[string]string
+	// If set, files are downloaded into the staging area instead of their
+	// destination
+	staging *pullStaging
+	// Set to the destination of the file once it has been downloaded
+	pulledFile string
 }
 
 func (task *FilePullTask) Run(send func(string), abort func()) {
@@ -376,6 +778,7 @@ func (task *FilePullTask) Run(send func(string), abort func()) {
 	stats := task.stats
 	filePath := task.filePath
 	remoteToLocalLanguageMapping := task.remoteToLocalLanguageMappings
+	staging := task.staging
 
 	sendMessage := func(body string, force bool) {
 		if args.Silent && !force {
@@ -466,9 +869,16 @@ func (task *FilePullTask) Run(send func(string), abort func()) {
 
 		// Polling
 
+		downloadPath := sourceFile
+		if staging != nil {
+			downloadPath = staging.stage(sourceFile)
+			staging.describe(sourceFile, makeStagedFileInfo(
+				cfgResource, getStatsLanguageCode(stats), "source", stats,
+			))
+		}
 		err = handleRetry(
 			func() error {
-				return txapi.PollResourceStringsDownload(download, sourceFile)
+				return txapi.PollResourceStringsDownload(download, downloadPath)
 			},
 			"",
 			func(msg string) { sendMessage(msg, false) },
@@ -480,6 +890,15 @@ func (task *FilePullTask) Run(send func(string), abort func()) {
 			}
 			return
 		}
+		err = normalizeFile(cfgResource, downloadPath)
+		if err != nil {
+			sendMessage(err.Error(), true)
+			if !args.Skip {
+				abort()
+			}
+			return
+		}
+		task.pulledFile = sourceFile
 	} else {
 		if filePath != "" {
 			// Remote language file exists and so does local
@@ -504,30 +923,35 @@ func (task *FilePullTask) Run(send func(string), abort func()) {
 				sendMessage("File was not found locally, skipping", false)
 				return
 			}
-			pseudo_postfix := ""
+			fileFilter := cfgResource.FileFilter
+			if hasFileFilterWildcards(fileFilter) {
+				sendMessage(
+					"File was not found locally and the file filter has "+
+						"wildcards, skipping",
+					false,
+				)
+				return
+			}
 			if args.Pseudo {
-				pseudo_postfix = "_pseudo"
+				fileFilter = pseudoFileFilter(fileFilter)
 			}
-			filePath = strings.Replace(
-				cfgResource.FileFilter,
-				"<lang>",
-				localLanguageCode+pseudo_postfix,
-				-1,
-			)
+			filePath = expandFileFilter(fileFilter, localLanguageCode)
 			filePath = setFileTypeExtensions(args.FileType, filePath)
 		}
-		minimumPerc := args.MinimumPercentage
-		if minimumPerc == -1 {
-			if cfgResource.MinimumPercentage > -1 {
-				minimumPerc = cfgResource.MinimumPercentage
-			}
+		localLanguageCode, exists := remoteToLocalLanguageMapping[languageCode]
+		if !exists {
+			localLanguageCode = languageCode
 		}
+		mode, minimumPerc, useWords := getPullPolicy(
+			args, cfgResource, localLanguageCode, languageCode,
+		)
 		shouldSkip, feedbackMessage, err := shouldSkipDownload(
 			filePath,
 			stats,
 			args.UseGitTimestamps,
-			args.Mode,
+			mode,
 			minimumPerc,
+			useWords,
 			args.Force,
 		)
 		if err != nil {
@@ -563,7 +987,7 @@ func (task *FilePullTask) Run(send func(string), abort func()) {
 						languageCode,
 						args.ContentEncoding,
 						args.FileType,
-						args.Mode,
+						mode,
 					)
 				}
 				return err
@@ -581,9 +1005,16 @@ func (task *FilePullTask) Run(send func(string), abort func()) {
 
 		// Polling
 
+		downloadPath := filePath
+		if staging != nil {
+			downloadPath = staging.stage(filePath)
+			staging.describe(filePath, makeStagedFileInfo(
+				cfgResource, languageCode, mode, stats,
+			))
+		}
 		err = handleRetry(
 			func() error {
-				return txapi.PollTranslationDownload(download, filePath)
+				return txapi.PollTranslationDownload(download, downloadPath)
 			},
 			"",
 			func(msg string) { sendMessage(msg, false) },
@@ -595,13 +1026,101 @@ func (task *FilePullTask) Run(send func(string), abort func()) {
 			}
 			return
 		}
+		err = normalizeFile(cfgResource, downloadPath)
+		if err != nil {
+			sendMessage(err.Error(), true)
+			if !args.Skip {
+				abort()
+			}
+			return
+		}
+		task.pulledFile = filePath
 	}
 	sendMessage("Done", false)
 }
 
+func makeStagedFileInfo(
+	cfgResource *config.Resource, languageCode, mode string,
+	stats *jsonapi.Resource,
+) stagedFileInfo {
+	info := stagedFileInfo{
+		ResourceId:   cfgResource.GetAPv3Id(),
+		LanguageCode: languageCode,
+		Mode:         mode,
+	}
+	if stats != nil {
+		var attributes txapi.ResourceLanguageStatsAttributes
+		err := stats.MapAttributes(&attributes)
+		if err == nil {
+			info.Stats = &attributes
+		}
+	}
+	return info
+}
+
+func getStatsLanguageCode(stats *jsonapi.Resource) string {
+	if stats == nil {
+		return ""
+	}
+	relationship, exists := stats.Relationships["language"]
+	if !exists || relationship == nil || relationship.DataSingular == nil {
+		return ""
+	}
+	return strings.TrimPrefix(relationship.DataSingular.Id, "l:")
+}
+
+/*
+Figure out the translation mode and minimum completion percentage to pull a
+language of a resource with, and whether the percentage is computed on words
+instead of strings. The most specific setting wins: command line arguments,
+then the resource's per-language settings (eg 'mode.de'), using either the
+local or the remote language code, then the resource's settings.
+*/
+func getPullPolicy(
+	args *PullCommandArguments, cfgResource *config.Resource,
+	localLanguageCode, remoteLanguageCode string,
+) (string, int, bool) {
+	mode := args.Mode
+	if mode == "" {
+		languageMode, exists := cfgResource.LanguageModes[localLanguageCode]
+		if !exists {
+			languageMode, exists = cfgResource.LanguageModes[remoteLanguageCode]
+		}
+		if exists {
+			mode = languageMode
+		} else if cfgResource.Mode != "" {
+			mode = cfgResource.Mode
+		} else {
+			mode = "default"
+		}
+	}
+
+	minimumPerc := args.MinimumPercentage
+	if minimumPerc == -1 {
+		languageMinimumPerc, exists :=
+			cfgResource.LanguageMinimumPercentages[localLanguageCode]
+		if !exists {
+			languageMinimumPerc, exists =
+				cfgResource.LanguageMinimumPercentages[remoteLanguageCode]
+		}
+		if exists {
+			minimumPerc = languageMinimumPerc
+		} else if cfgResource.MinimumPercentage > -1 {
+			minimumPerc = cfgResource.MinimumPercentage
+		}
+	}
+
+	unit := args.MinimumPercentageUnit
+	if unit == "" {
+		unit = cfgResource.MinimumPercentageUnit
+	}
+
+	return mode, minimumPerc, unit == "words"
+}
+
 func shouldSkipDownload(
 	path string, remoteStat *jsonapi.Resource, useGitTimestamps bool,
-	mode string, minimum_perc int, force bool,
+	mode string, minimum_perc int, useWords bool, force bool,
 ) (bool, string, error) {
 	var localTime time.Time
 	var feedbackMessage = ""
@@ -612,15 +1131,9 @@ func shouldSkipDownload(
 	}
 
 	if minimum_perc > 0 {
-		actedOnStrings := remoteStatAttributes.TranslatedStrings
-		switch mode {
-		case "reviewed", "onlyreviewed":
-			actedOnStrings = remoteStatAttributes.ReviewedStrings
-		case "proofread", "onlyproofread":
-			actedOnStrings = remoteStatAttributes.ProofreadStrings
-		}
-
-		totalStrings := remoteStatAttributes.TotalStrings
+		actedOnStrings, totalStrings := getActedOnStrings(
+			&remoteStatAttributes, mode, useWords,
+		)
 
 		skipDueToStringPercentage := shouldSkipDueToStringPercentage(
 			minimum_perc, actedOnStrings, totalStrings,
@@ -637,7 +1150,7 @@ func shouldSkipDownload(
 			localTime = getLastCommitDate(path)
 			if localTime == (time.Time{}) {
 				return shouldSkipDownload(path, remoteStat,
-					false, mode, minimum_perc, force)
+					false, mode, minimum_perc, useWords, force)
 			}
 		} else {
 			localStat, err := os.Stat(path)
@@ -666,6 +1179,32 @@ func shouldSkipDownload(
 	return false, feedbackMessage, nil
 }
 
+// Count the strings (or words) that 'mode' cares about, along with the total
+func getActedOnStrings(
+	attributes *txapi.ResourceLanguageStatsAttributes,
+	mode string,
+	useWords bool,
+) (int, int) {
+	translated := attributes.TranslatedStrings
+	reviewed := attributes.ReviewedStrings
+	proofread := attributes.ProofreadStrings
+	totalStrings := attributes.TotalStrings
+	if useWords {
+		translated = attributes.TranslatedWords
+		reviewed = attributes.ReviewedWords
+		proofread = attributes.ProofreadWords
+		totalStrings = attributes.TotalWords
+	}
+
+	switch mode {
+	case "reviewed", "onlyreviewed":
+		return reviewed, totalStrings
+	case "proofread", "onlyproofread":
+		return proofread, totalStrings
+	}
+	return translated, totalStrings
+}
+
 func shouldSkipDueToStringPercentage(
 	minimum_perc int,
 	actedOnStrings int,
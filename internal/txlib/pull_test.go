@@ -1,12 +1,16 @@
 package txlib
 
 import (
+	"archive/zip"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"os"
+	"runtime"
 	"strings"
 	"testing"
 
+	"github.com/transifex/cli/internal/txlib/config"
 	"github.com/transifex/cli/pkg/assert"
 	"github.com/transifex/cli/pkg/jsonapi"
 )
@@ -31,6 +35,7 @@ func TestPullCommandResourceExists(t *testing.T) {
 	api := jsonapi.GetTestConnection(mockData)
 
 	arguments := PullCommandArguments{
+		Branch:            "-1",
 		FileType:          "default",
 		Mode:              "default",
 		Force:             true,
@@ -74,6 +79,7 @@ func TestPullCommandFileExists(t *testing.T) {
 	api := jsonapi.GetTestConnection(mockData)
 
 	arguments := PullCommandArguments{
+		Branch:            "-1",
 		FileType:          "default",
 		Mode:              "default",
 		Force:             true,
@@ -119,6 +125,7 @@ func TestPullCommandDownloadSource(t *testing.T) {
 		cfg,
 		&api,
 		&PullCommandArguments{
+			Branch:            "-1",
 			FileType:          "default",
 			Mode:              "default",
 			Force:             true,
@@ -168,6 +175,7 @@ func TestPullCommandSkipOnTranslatedMinPerc(t *testing.T) {
 	api := jsonapi.GetTestConnection(mockData)
 
 	arguments := PullCommandArguments{
+		Branch:            "-1",
 		FileType:          "default",
 		Mode:              "default",
 		All:               true,
@@ -218,6 +226,7 @@ func TestPullCommandProceedOnEqualTranslatedMinPerc(t *testing.T) {
 	api := jsonapi.GetTestConnection(mockData)
 
 	arguments := PullCommandArguments{
+		Branch:            "-1",
 		FileType:          "default",
 		Mode:              "default",
 		Force:             true,
@@ -262,6 +271,7 @@ func TestPullCommandOverrides(t *testing.T) {
 	api := jsonapi.GetTestConnection(mockData)
 
 	arguments := PullCommandArguments{
+		Branch:            "-1",
 		FileType:          "default",
 		Mode:              "default",
 		Force:             true,
@@ -306,6 +316,7 @@ func TestPullCommandMultipleLangParameters(t *testing.T) {
 	api := jsonapi.GetTestConnection(mockData)
 
 	arguments := PullCommandArguments{
+		Branch:            "-1",
 		FileType:          "default",
 		Mode:              "default",
 		Force:             true,
@@ -356,6 +367,7 @@ func TestPullCommandSkipOnReviewedMinPerc(t *testing.T) {
 	api := jsonapi.GetTestConnection(mockData)
 
 	arguments := PullCommandArguments{
+		Branch:            "-1",
 		FileType:          "default",
 		Mode:              "reviewed",
 		All:               true,
@@ -401,6 +413,7 @@ func TestPullCommandSkipOnProofreadMinPerc(t *testing.T) {
 	api := jsonapi.GetTestConnection(mockData)
 
 	arguments := PullCommandArguments{
+		Branch:            "-1",
 		FileType:          "default",
 		Mode:              "proofread",
 		All:               true,
@@ -447,6 +460,7 @@ func TestPullCommandPercentageArgumentShouldWinOverResource(t *testing.T) {
 	api := jsonapi.GetTestConnection(mockData)
 
 	arguments := PullCommandArguments{
+		Branch:            "-1",
 		FileType:          "default",
 		Mode:              "default",
 		All:               true,
@@ -465,6 +479,148 @@ func TestPullCommandPercentageArgumentShouldWinOverResource(t *testing.T) {
 	testSimpleGet(t, mockData, statsUrlAllLanguages)
 }
 
+func TestPullCommandSkipOnLanguagePolicy(t *testing.T) {
+	afterTest := beforeTest(t, nil, nil)
+	defer afterTest()
+
+	cfg := getStandardConfig()
+	cfg.Local.Resources[0].MinimumPercentage = 10
+	cfg.Local.Resources[0].LanguageModes = map[string]string{"el": "reviewed"}
+	cfg.Local.Resources[0].LanguageMinimumPercentages = map[string]int{"el": 40}
+
+	mockData := jsonapi.MockData{
+		resourceUrl: getResourceEndpoint(),
+		projectUrl:  getProjectEndpoint(),
+		statsUrlAllLanguages: jsonapi.GetMockTextResponse(fmt.Sprintf(
+			`{"data": [{"type": "resource_language_stats",
+			            "id": "%s:l:en",
+						"relationships": {"language": {"data": {"type": "languages",
+						                                        "id": "l:en"}}}},
+			           {"type": "resource_language_stats",
+					    "id": "%s:l:el",
+						"attributes": {"translated_strings": 100,
+						               "reviewed_strings": 30,
+						               "total_strings": 100},
+						"relationships": {"language": {"data": {"type": "languages",
+						                                        "id": "l:el"}}}}]}`,
+			resourceId,
+			resourceId,
+		)),
+	}
+
+	api := jsonapi.GetTestConnection(mockData)
+
+	arguments := PullCommandArguments{
+		Branch:            "-1",
+		FileType:          "default",
+		All:               true,
+		MinimumPercentage: -1,
+		Workers:           1,
+	}
+
+	// No download endpoints are mocked, so this fails if the file is not
+	// skipped
+	err := PullCommand(cfg, &api, &arguments)
+	if err != nil {
+		t.Errorf("%s", err)
+	}
+
+	testSimpleGet(t, mockData, statsUrlAllLanguages)
+}
+
+func TestPullCommandSkipOnWordsMinPerc(t *testing.T) {
+	afterTest := beforeTest(t, nil, nil)
+	defer afterTest()
+
+	cfg := getStandardConfig()
+	cfg.Local.Resources[0].MinimumPercentageUnit = "words"
+
+	mockData := jsonapi.MockData{
+		resourceUrl: getResourceEndpoint(),
+		projectUrl:  getProjectEndpoint(),
+		statsUrlAllLanguages: jsonapi.GetMockTextResponse(fmt.Sprintf(
+			`{"data": [{"type": "resource_language_stats",
+			            "id": "%s:l:en",
+						"relationships": {"language": {"data": {"type": "languages",
+						                                        "id": "l:en"}}}},
+			           {"type": "resource_language_stats",
+					    "id": "%s:l:el",
+						"attributes": {"translated_strings": 90,
+						               "total_strings": 100,
+						               "translated_words": 10,
+						               "total_words": 100},
+						"relationships": {"language": {"data": {"type": "languages",
+						                                        "id": "l:el"}}}}]}`,
+			resourceId,
+			resourceId,
+		)),
+	}
+
+	api := jsonapi.GetTestConnection(mockData)
+
+	arguments := PullCommandArguments{
+		Branch:            "-1",
+		FileType:          "default",
+		Mode:              "default",
+		All:               true,
+		MinimumPercentage: 50,
+		Workers:           1,
+	}
+
+	err := PullCommand(cfg, &api, &arguments)
+	if err != nil {
+		t.Errorf("%s", err)
+	}
+
+	testSimpleGet(t, mockData, statsUrlAllLanguages)
+}
+
+func TestGetPullPolicy(t *testing.T) {
+	cfgResource := &config.Resource{
+		Mode:                       "translator",
+		MinimumPercentage:          20,
+		LanguageModes:              map[string]string{"de": "reviewed"},
+		LanguageMinimumPercentages: map[string]int{"ja": 80},
+		MinimumPercentageUnit:      "words",
+	}
+
+	mode, minimumPerc, useWords := getPullPolicy(
+		&PullCommandArguments{MinimumPercentage: -1}, cfgResource, "de", "de",
+	)
+	assert.Equal(t, mode, "reviewed")
+	assert.Equal(t, minimumPerc, 20)
+	assert.Equal(t, useWords, true)
+
+	// Per-language settings can use the remote language code too
+	mode, minimumPerc, _ = getPullPolicy(
+		&PullCommandArguments{MinimumPercentage: -1}, cfgResource, "ja_JP", "ja",
+	)
+	assert.Equal(t, mode, "translator")
+	assert.Equal(t, minimumPerc, 80)
+
+	// Command line arguments win
+	mode, minimumPerc, useWords = getPullPolicy(
+		&PullCommandArguments{
+			Branch:                "-1",
+			Mode:                  "proofread",
+			MinimumPercentage:     50,
+			MinimumPercentageUnit: "strings",
+		},
+		cfgResource, "ja", "ja",
+	)
+	assert.Equal(t, mode, "proofread")
+	assert.Equal(t, minimumPerc, 50)
+	assert.Equal(t, useWords, false)
+
+	mode, minimumPerc, useWords = getPullPolicy(
+		&PullCommandArguments{MinimumPercentage: -1},
+		&config.Resource{MinimumPercentage: -1}, "fr", "fr",
+	)
+	assert.Equal(t, mode, "default")
+	assert.Equal(t, minimumPerc, -1)
+	assert.Equal(t, useWords, false)
+}
+
 func TestPercentageWinsOverForce(t *testing.T) {
 	afterTest := beforeTest(t, nil, nil)
 	defer afterTest()
@@ -492,6 +648,7 @@ func TestPercentageWinsOverForce(t *testing.T) {
 	api := jsonapi.GetTestConnection(mockData)
 
 	arguments := PullCommandArguments{
+		Branch:            "-1",
 		FileType:          "default",
 		Mode:              "default",
 		All:               true,
@@ -569,6 +726,7 @@ func TestDownloadPseudoTranslations(t *testing.T) {
 	api := jsonapi.GetTestConnection(mockData)
 
 	arguments := PullCommandArguments{
+		Branch:            "-1",
 		FileType:          "default",
 		Mode:              "default",
 		Force:             true,
@@ -615,6 +773,7 @@ func TestKeepNewFilesSource(t *testing.T) {
 		cfg,
 		&api,
 		&PullCommandArguments{
+			Branch:            "-1",
 			FileType:          "default",
 			Mode:              "default",
 			Force:             true,
@@ -663,6 +822,7 @@ func TestKeepNewFilesSourceOnlyWithDisableOverride(t *testing.T) {
 		cfg,
 		&api,
 		&PullCommandArguments{
+			Branch:            "-1",
 			FileType:          "default",
 			Mode:              "default",
 			Force:             true,
@@ -710,6 +870,7 @@ func TestKeepNewFilesTranslation(t *testing.T) {
 	api := jsonapi.GetTestConnection(mockData)
 
 	arguments := PullCommandArguments{
+		Branch:            "-1",
 		FileType:          "default",
 		Mode:              "default",
 		Force:             true,
@@ -735,6 +896,368 @@ func TestKeepNewFilesTranslation(t *testing.T) {
 	assertFileContent(t, "aaa-el.json.new", "This is the content")
 }
 
+func TestPullAtomic(t *testing.T) {
+	afterTest := beforeTest(t, []string{"el"}, nil)
+	defer afterTest()
+
+	ts := getNewTestServer("This is the content")
+	defer ts.Close()
+
+	mockData := jsonapi.MockData{
+		resourceUrl:             getResourceEndpoint(),
+		projectUrl:              getProjectEndpoint(),
+		statsUrlAllLanguages:    getStatsEndpointAllLanguages(),
+		translationDownloadsUrl: getTranslationDownloadsEndpoint(),
+		translationDownloadUrl:  getDownloadEndpoint(ts.URL),
+	}
+	api := jsonapi.GetTestConnection(mockData)
+
+	err := PullCommand(getStandardConfig(), &api, &PullCommandArguments{
+		Branch:            "-1",
+		FileType:          "default",
+		Mode:              "default",
+		Force:             true,
+		MinimumPercentage: -1,
+		Workers:           1,
+		Atomic:            true,
+	})
+	if err != nil {
+		t.Error(err)
+	}
+
+	assertFileContent(t, "aaa-el.json", "This is the content")
+	_, err = os.Stat("aaa-el.json" + stagedSuffix)
+	if !os.IsNotExist(err) {
+		t.Error("Staged file was not cleaned up")
+	}
+}
+
+func TestPullAtomicRollback(t *testing.T) {
+	afterTest := beforeTest(t, []string{"el", "fr"}, nil)
+	defer afterTest()
+
+	ts := getNewTestServer("This is the content")
+	defer ts.Close()
+
+	mockData := jsonapi.MockData{
+		resourceUrl: getResourceEndpoint(),
+		projectUrl:  getProjectEndpoint(),
+		statsUrlAllLanguages: jsonapi.GetMockTextResponse(fmt.Sprintf(
+			`{"data": [{"type": "resource_language_stats",
+			            "id": "%[1]s:l:el",
+			            "relationships": {"language": {"data": {"type": "languages",
+			                                                    "id": "l:el"}}}},
+			           {"type": "resource_language_stats",
+			            "id": "%[1]s:l:fr",
+			            "relationships": {"language": {"data": {"type": "languages",
+			                                                    "id": "l:fr"}}}}]}`,
+			resourceId,
+		)),
+		// Only one download can be created, so the second one will fail
+		translationDownloadsUrl: getTranslationDownloadsEndpoint(),
+		translationDownloadUrl:  getDownloadEndpoint(ts.URL),
+	}
+	api := jsonapi.GetTestConnection(mockData)
+
+	err := PullCommand(getStandardConfig(), &api, &PullCommandArguments{
+		Branch:            "-1",
+		FileType:          "default",
+		Mode:              "default",
+		Force:             true,
+		MinimumPercentage: -1,
+		Workers:           1,
+		Atomic:            true,
+	})
+	if err == nil {
+		t.Error("Expected error")
+	}
+
+	assertFileContent(t, "aaa-el.json", `{"hello": "world"}`)
+	assertFileContent(t, "aaa-fr.json", `{"hello": "world"}`)
+	for _, path := range []string{"aaa-el.json", "aaa-fr.json"} {
+		_, err = os.Stat(path + stagedSuffix)
+		if !os.IsNotExist(err) {
+			t.Errorf("Staged file for '%s' was not cleaned up", path)
+		}
+	}
+}
+
+func TestPullDiff(t *testing.T) {
+	afterTest := beforeTest(t, []string{"el"}, nil)
+	defer afterTest()
+
+	ts := getNewTestServer("This is the content")
+	defer ts.Close()
+
+	mockData := jsonapi.MockData{
+		resourceUrl:             getResourceEndpoint(),
+		projectUrl:              getProjectEndpoint(),
+		statsUrlAllLanguages:    getStatsEndpointAllLanguages(),
+		translationDownloadsUrl: getTranslationDownloadsEndpoint(),
+		translationDownloadUrl:  getDownloadEndpoint(ts.URL),
+	}
+	api := jsonapi.GetTestConnection(mockData)
+
+	rescueStdout := os.Stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+
+	err := PullCommand(getStandardConfig(), &api, &PullCommandArguments{
+		Branch:            "-1",
+		FileType:          "default",
+		Mode:              "default",
+		Force:             true,
+		MinimumPercentage: -1,
+		Workers:           1,
+		Silent:            true,
+		Diff:              true,
+	})
+
+	w.Close()
+	out, _ := ioutil.ReadAll(r)
+	os.Stdout = rescueStdout
+
+	if err != ErrPullDiffChanges {
+		t.Errorf("Expected ErrPullDiffChanges, got %v", err)
+	}
+	result := string(out)
+	assert.True(t, strings.Contains(result, "-{\"hello\": \"world\"}"), "%s", result)
+	assert.True(t, strings.Contains(result, "+This is the content"), "%s", result)
+
+	// The local file should be untouched
+	assertFileContent(t, "aaa-el.json", `{"hello": "world"}`)
+}
+
+func TestPullArchive(t *testing.T) {
+	afterTest := beforeTest(t, []string{"el"}, nil)
+	defer afterTest()
+
+	ts := getNewTestServer("This is the content")
+	defer ts.Close()
+
+	mockData := jsonapi.MockData{
+		resourceUrl:             getResourceEndpoint(),
+		projectUrl:              getProjectEndpoint(),
+		statsUrlAllLanguages:    getStatsEndpointAllLanguages(),
+		translationDownloadsUrl: getTranslationDownloadsEndpoint(),
+		translationDownloadUrl:  getDownloadEndpoint(ts.URL),
+	}
+	api := jsonapi.GetTestConnection(mockData)
+
+	err := PullCommand(getStandardConfig(), &api, &PullCommandArguments{
+		Branch:            "-1",
+		FileType:          "default",
+		Mode:              "default",
+		MinimumPercentage: -1,
+		Workers:           1,
+		Silent:            true,
+		Archive:           "out/translations.zip",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The local file should be untouched
+	assertFileContent(t, "aaa-el.json", `{"hello": "world"}`)
+
+	reader, err := zip.OpenReader("out/translations.zip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+
+	contents := make(map[string]string)
+	for _, file := range reader.File {
+		opened, err := file.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := ioutil.ReadAll(opened)
+		opened.Close()
+		contents[file.Name] = string(data)
+	}
+	assert.Equal(t, len(contents), 2)
+	assert.Equal(t, strings.TrimSpace(contents["aaa-el.json"]), "This is the content")
+
+	var manifest archiveManifest
+	err = json.Unmarshal([]byte(contents[archiveManifestName]), &manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, len(manifest.Files), 1)
+	entry := manifest.Files[0]
+	assert.Equal(t, entry.Path, "aaa-el.json")
+	assert.Equal(t, entry.Resource, "o:orgslug:p:projslug:r:resslug")
+	assert.Equal(t, entry.Language, "el")
+	assert.Equal(t, entry.Mode, "default")
+}
+
+func TestPullHooks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hook commands in this test need a POSIX shell")
+	}
+	afterTest := beforeTest(t, []string{"el"}, nil)
+	defer afterTest()
+
+	ts := getNewTestServer("This is the content")
+	defer ts.Close()
+
+	mockData := jsonapi.MockData{
+		resourceUrl:             getResourceEndpoint(),
+		projectUrl:              getProjectEndpoint(),
+		statsUrlAllLanguages:    getStatsEndpointAllLanguages(),
+		translationDownloadsUrl: getTranslationDownloadsEndpoint(),
+		translationDownloadUrl:  getDownloadEndpoint(ts.URL),
+	}
+	api := jsonapi.GetTestConnection(mockData)
+
+	cfg := getStandardConfig()
+	cfg.Local.Hooks.PostPullFile = `echo "$TX_LANGUAGE $TX_FILE" >> hooks.log`
+	cfg.Local.Resources[0].Hooks.PostPull = `echo "done $TX_RESOURCE" >> hooks.log`
+	err := PullCommand(cfg, &api, &PullCommandArguments{
+		Branch:            "-1",
+		FileType:          "default",
+		Mode:              "default",
+		Force:             true,
+		MinimumPercentage: -1,
+		Workers:           1,
+		Silent:            true,
+	})
+	if err != nil {
+		t.Error(err)
+	}
+
+	assertFileContent(
+		t, "hooks.log", "el aaa-el.json\ndone o:orgslug:p:projslug:r:resslug",
+	)
+}
+
+func TestPullHookFails(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hook commands in this test need a POSIX shell")
+	}
+	afterTest := beforeTest(t, []string{"el"}, nil)
+	defer afterTest()
+
+	ts := getNewTestServer("This is the content")
+	defer ts.Close()
+
+	mockData := jsonapi.MockData{
+		resourceUrl:             getResourceEndpoint(),
+		projectUrl:              getProjectEndpoint(),
+		statsUrlAllLanguages:    getStatsEndpointAllLanguages(),
+		translationDownloadsUrl: getTranslationDownloadsEndpoint(),
+		translationDownloadUrl:  getDownloadEndpoint(ts.URL),
+	}
+	api := jsonapi.GetTestConnection(mockData)
+
+	cfg := getStandardConfig()
+	cfg.Local.Hooks.PostPullFile = "exit 1"
+	err := PullCommand(cfg, &api, &PullCommandArguments{
+		Branch:            "-1",
+		FileType:          "default",
+		Mode:              "default",
+		Force:             true,
+		MinimumPercentage: -1,
+		Workers:           1,
+		Silent:            true,
+	})
+	if err == nil {
+		t.Error("Expected the pull to fail")
+	}
+}
+
+func TestPullLanguageFallbacks(t *testing.T) {
+	afterTest := beforeTest(t, []string{"el"}, nil)
+	defer afterTest()
+
+	ts := getNewTestServer(`{"hello": "", "bye": "Αντίο"}`)
+	defer ts.Close()
+
+	mockData := jsonapi.MockData{
+		resourceUrl:             getResourceEndpoint(),
+		projectUrl:              getProjectEndpoint(),
+		statsUrlAllLanguages:    getStatsEndpointAllLanguages(),
+		translationDownloadsUrl: getTranslationDownloadsEndpoint(),
+		translationDownloadUrl:  getDownloadEndpoint(ts.URL),
+	}
+	api := jsonapi.GetTestConnection(mockData)
+
+	cfg := getStandardConfig()
+	cfg.Local.Resources[0].Type = "KEYVALUEJSON"
+	cfg.Local.Resources[0].SourceLanguage = "en"
+	cfg.Local.Fallbacks = map[string][]string{"el": {"el_CY", "en"}}
+	err := os.WriteFile("aaa.json", []byte(`{"hello": "world"}`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = PullCommand(cfg, &api, &PullCommandArguments{
+		Branch:            "-1",
+		FileType:          "default",
+		Mode:              "default",
+		Force:             true,
+		MinimumPercentage: -1,
+		Workers:           1,
+		Silent:            true,
+	})
+	if err != nil {
+		t.Error(err)
+	}
+
+	assertFileContent(
+		t, "aaa-el.json", "{\n  \"hello\": \"world\",\n  \"bye\": \"Αντίο\"\n}",
+	)
+}
+
+func TestPullNormalizesTextFormat(t *testing.T) {
+	afterTest := beforeTest(t, []string{"el"}, nil)
+	defer afterTest()
+
+	ts := getNewTestServer("\ufeff\"hello\" = \"Γειά\";\r\n\"bye\" = \"Αντίο\";")
+	defer ts.Close()
+
+	mockData := jsonapi.MockData{
+		resourceUrl:             getResourceEndpoint(),
+		projectUrl:              getProjectEndpoint(),
+		statsUrlAllLanguages:    getStatsEndpointAllLanguages(),
+		translationDownloadsUrl: getTranslationDownloadsEndpoint(),
+		translationDownloadUrl:  getDownloadEndpoint(ts.URL),
+	}
+	api := jsonapi.GetTestConnection(mockData)
+
+	cfg := getStandardConfig()
+	bom := false
+	cfg.Local.Resources[0].Eol = "lf"
+	cfg.Local.Resources[0].Bom = &bom
+	cfg.Local.Resources[0].Encoding = "utf-8"
+	err := PullCommand(cfg, &api, &PullCommandArguments{
+		Branch:            "-1",
+		FileType:          "default",
+		Mode:              "default",
+		Force:             true,
+		MinimumPercentage: -1,
+		Workers:           1,
+		Silent:            true,
+	})
+	if err != nil {
+		t.Error(err)
+	}
+
+	data, err := os.ReadFile("aaa-el.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(
+		t, string(data), "\"hello\" = \"Γειά\";\n\"bye\" = \"Αντίο\";\n",
+	)
+}
+
+func TestGetArchiveEntryPath(t *testing.T) {
+	assert.Equal(t, getArchiveEntryPath("locale/fr.po"), "locale/fr.po")
+	assert.Equal(t, getArchiveEntryPath("./locale/../fr.po"), "fr.po")
+	assert.Equal(t, getArchiveEntryPath("../../locale/fr.po"), "locale/fr.po")
+}
+
 func assertFileContent(t *testing.T, expectedPath, expectedContent string) {
 	data, err := os.ReadFile(expectedPath)
 	if err != nil {
@@ -3,6 +3,7 @@ package txlib
 import (
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"sort"
@@ -21,7 +22,7 @@ type PushCommandArguments struct {
 	Translation          bool
 	Force                bool
 	Skip                 bool
-	Xliff                bool
+	FileType             string
 	Languages            []string
 	ResourceIds          []string
 	UseGitTimestamps     bool
@@ -32,6 +33,23 @@ type PushCommandArguments struct {
 	Silent               bool
 	ReplaceEditedStrings bool
 	KeepTranslations     bool
+	Since                string
+	Changed              bool
+	SourceFile           string
+	TranslationFile      string
+	// Fail instead of skipping local files whose language codes are not
+	// known to Transifex
+	ValidateLanguages bool
+
+	// Absolute paths of the files changed according to git; nil unless
+	// 'Since' or 'Changed' is set
+	changedPaths map[string]bool
+	// With "auto" as 'Base', the git branches that HEAD was branched off,
+	// nearest first
+	baseCandidates []string
+	// The name older versions gave to the detected branch, see
+	// getLegacyBranch
+	legacyBranch string
 }
 
 func PushCommand(
@@ -39,14 +57,95 @@ func PushCommand(
 	api jsonapi.Connection,
 	args PushCommandArguments,
 ) error {
-	args.Branch = figureOutBranch(args.Branch)
+	var err error
+	requestedBranch := args.Branch
+	args.Branch, err = figureOutBranch(args.Branch)
+	if err != nil {
+		return err
+	}
+	args.legacyBranch = getLegacyBranch(requestedBranch, args.Branch)
+	if args.Base == "auto" {
+		if args.Branch == "" {
+			return errors.New("'--base auto' can only be used with '--branch'")
+		}
+		args.baseCandidates, err = getGitAncestorBranches(args.Branch)
+		if err != nil {
+			return err
+		}
+	}
 
 	cfgResources, err := figureOutResources(args.ResourceIds, cfg)
 	if err != nil {
 		return err
 	}
 
-	applyBranchToResources(cfgResources, args.Branch)
+	if args.SourceFile != "" || args.TranslationFile != "" {
+		if len(cfgResources) != 1 {
+			return errors.New(
+				"a custom source or translation file can only be pushed for " +
+					"exactly one resource",
+			)
+		}
+		if args.TranslationFile != "" && len(args.Languages) != 1 {
+			return errors.New(
+				"a custom translation file can only be pushed for exactly " +
+					"one language",
+			)
+		}
+		if args.SourceFile == "-" && args.TranslationFile == "-" {
+			return errors.New(
+				"only one of the source and translation files can be read " +
+					"from the standard input",
+			)
+		}
+		if args.SourceFile == "-" || args.TranslationFile == "-" {
+			path, err := copyStdinToTempFile()
+			if err != nil {
+				return err
+			}
+			defer os.Remove(path)
+			if args.SourceFile == "-" {
+				args.SourceFile = path
+			} else {
+				args.TranslationFile = path
+			}
+		}
+	}
+
+	if args.Since != "" || args.Changed {
+		ref := args.Since
+		if ref == "" {
+			ref = "HEAD"
+		}
+		changedPaths, err := getGitChangedFiles(ref)
+		if err != nil {
+			return err
+		}
+		args.changedPaths = make(map[string]bool)
+		for _, path := range changedPaths {
+			args.changedPaths[path] = true
+		}
+		cfgResources, err = filterChangedResources(cfgResources, args)
+		if err != nil {
+			return err
+		}
+		if len(cfgResources) == 0 {
+			if !args.Silent {
+				fmt.Printf("No resources have changed since '%s'\n", ref)
+			}
+			return nil
+		}
+	}
+
+	// Remember the slugs without the branch, to find the bases of branch
+	// resources
+	mainResourceSlugs := make(map[*config.Resource]string)
+	for _, cfgResource := range cfgResources {
+		mainResourceSlugs[cfgResource] = cfgResource.ResourceSlug
+	}
+	applyBranchToResources(
+		&api, cfg, cfgResources, args.Branch, args.legacyBranch,
+	)
 
 	sort.Slice(cfgResources, func(i, j int) bool {
 		return cfgResources[i].GetAPv3Id() < cfgResources[j].GetAPv3Id()
@@ -72,6 +171,7 @@ func PushCommand(
 				&api,
 				args,
 				targetLanguagesChannel,
+				mainResourceSlugs[cfgResource],
 			},
 		)
 	}
@@ -264,6 +364,8 @@ type ResourcePushTask struct {
 	api                    *jsonapi.Connection
 	args                   PushCommandArguments
 	targetLanguagesChannel chan TargetLanguageMessage
+	// The resource's slug without the branch
+	mainResourceSlug string
 }
 
 func (task *ResourcePushTask) Run(send func(string), abort func()) {
@@ -291,6 +393,29 @@ func (task *ResourcePushTask) Run(send func(string), abort func()) {
 		send(message)
 	}
 
+	hooks := cfg.Local.GetHooks(cfgResource)
+	if hooks.PrePush != "" {
+		sendMessage("Running pre_push hook", false)
+		sourceFile := cfgResource.SourceFile
+		if args.SourceFile != "" {
+			sourceFile = args.SourceFile
+		}
+		err := runHook(
+			"pre_push",
+			hooks.PrePush,
+			cfgResource.GetAPv3Id(),
+			cfgResource.SourceLanguage,
+			sourceFile,
+		)
+		if err != nil {
+			sendMessage(err.Error(), true)
+			if !args.Skip {
+				abort()
+			}
+			return
+		}
+	}
+
 	var resource *jsonapi.Resource
 	err := handleRetry(
 		func() error {
@@ -309,6 +434,36 @@ func (task *ResourcePushTask) Run(send func(string), abort func()) {
 		return
 	}
 
+	base := args.Base
+	if args.Branch != "" && base == "auto" {
+		err = handleRetry(
+			func() error {
+				var err error
+				base, err = findAutoBase(
+					api, cfg, cfgResource, task.mainResourceSlug,
+					args.baseCandidates,
+				)
+				return err
+			},
+			"Finding base branch",
+			func(msg string) { sendMessage(msg, false) },
+		)
+		if err != nil {
+			sendMessage(
+				fmt.Sprintf("Error while finding base branch: %s", err), true,
+			)
+			if !args.Skip {
+				abort()
+			}
+			return
+		}
+		if base == "" {
+			sendMessage("Using the main resource as base", false)
+		} else {
+			sendMessage(fmt.Sprintf("Using branch '%s' as base", base), false)
+		}
+	}
+
 	resourceIsNew := resource == nil
 	if resourceIsNew {
 		if args.Translation && !args.Source {
@@ -336,13 +491,13 @@ func (task *ResourcePushTask) Run(send func(string), abort func()) {
 		if args.Branch == "" {
 			resourceName = cfgResource.GetName()
 		} else {
-			resourceName = fmt.Sprintf(
-				"%s (branch %s)",
-				cfgResource.GetName(),
-				args.Branch,
+			resourceName = getBranchResourceName(
+				cfgResource.GetName(), args.Branch,
 			)
 
-			baseResourceSlug := getBaseResourceSlug(cfgResource, args.Branch, args.Base)
+			baseResourceSlug := getBaseResourceSlug(
+				cfg.Local.BranchNaming, task.mainResourceSlug, base,
+			)
 
 			baseResourceId = fmt.Sprintf(
 				"o:%s:p:%s:r:%s",
@@ -369,7 +524,7 @@ func (task *ResourcePushTask) Run(send func(string), abort func()) {
 				}
 				return
 			}
-			if args.Base != "-1" {
+			if base != "-1" {
 				if baseResource == nil {
 					sendMessage(fmt.Sprintf("Base Resource does not exist: %s", baseResourceId), true)
 					if !args.Skip {
@@ -413,8 +568,20 @@ func (task *ResourcePushTask) Run(send func(string), abort func()) {
 			return
 		}
 	} else {
-		if args.Branch != "" && args.Base != "-1" {
-			baseResourceSlug := getBaseResourceSlug(cfgResource, args.Branch, args.Base)
+		err = checkBranchResourceName(
+			resource, args.Branch, args.legacyBranch,
+		)
+		if err != nil {
+			sendMessage(err.Error(), true)
+			if !args.Skip {
+				abort()
+			}
+			return
+		}
+		if args.Branch != "" && base != "-1" {
+			baseResourceSlug := getBaseResourceSlug(
+				cfg.Local.BranchNaming, task.mainResourceSlug, base,
+			)
 
 			baseResourceId := fmt.Sprintf(
 				"o:%s:p:%s:r:%s",
@@ -423,7 +590,6 @@ func (task *ResourcePushTask) Run(send func(string), abort func()) {
 				baseResourceSlug,
 			)
 
-			applyBranchToResources([]*config.Resource{cfgResource}, args.Branch)
 			resource.SetRelated("base", &jsonapi.Resource{Type: "resources", Id: baseResourceId})
 			err = resource.Save([]string{"base"})
 			if err != nil {
@@ -480,11 +646,17 @@ func (task *ResourcePushTask) Run(send func(string), abort func()) {
 		}
 		return
 	}
-	if args.Source || !args.Translation {
+	sourceFile := cfgResource.SourceFile
+	if args.SourceFile != "" {
+		sourceFile = args.SourceFile
+	}
+	if (args.Source || !args.Translation) &&
+		isChangedPath(args.changedPaths, sourceFile) {
+		warnAboutTextFormats(cfgResource, []string{sourceFile})
 		sourceTaskChannel <- &SourceFilePushTask{
 			api,
 			resource,
-			cfgResource.SourceFile,
+			sourceFile,
 			remoteStats[sourceLanguage.Id],
 			args,
 			resourceIsNew,
@@ -497,8 +669,6 @@ func (task *ResourcePushTask) Run(send func(string), abort func()) {
 			*cfg,
 			*cfgResource,
 		)
-		overrides := cfgResource.Overrides
-
 		sendMessage("Fetching remote languages", false)
 		curDir, err := os.Getwd()
 		if err != nil {
@@ -509,21 +679,21 @@ func (task *ResourcePushTask) Run(send func(string), abort func()) {
 			return
 		}
 		fileFilter := cfgResource.FileFilter
-		err = checkFileFilter(fileFilter)
-		if err != nil {
-			sendMessage(err.Error(), true)
-			if !args.Skip {
-				abort()
+		if args.TranslationFile == "" {
+			err = checkFileFilter(fileFilter)
+			if err != nil {
+				sendMessage(err.Error(), true)
+				if !args.Skip {
+					abort()
+				}
+				return
 			}
-			return
-		}
-		if args.Xliff {
-			fileFilter = fmt.Sprintf("%s.xlf", fileFilter)
 		}
+		fileFilter = setFileTypeExtensions(args.FileType, fileFilter)
 
 		paths, newLanguageCodes, err := getFilesToPush(
-			curDir, fileFilter, localToRemoteLanguageMappings,
-			remoteStats, overrides, args, resourceIsNew,
+			curDir, fileFilter, cfg, cfgResource,
+			localToRemoteLanguageMappings, remoteStats, args, resourceIsNew,
 		)
 		if err != nil {
 			sendMessage(err.Error(), true)
@@ -532,6 +702,16 @@ func (task *ResourcePushTask) Run(send func(string), abort func()) {
 			}
 			return
 		}
+		var languageCodes []string
+		for languageCode := range paths {
+			languageCodes = append(languageCodes, languageCode)
+		}
+		sort.Strings(languageCodes)
+		var translationPaths []string
+		for _, languageCode := range languageCodes {
+			translationPaths = append(translationPaths, paths[languageCode])
+		}
+		warnAboutTextFormats(cfgResource, translationPaths)
 
 		var allLanguages map[string]*jsonapi.Resource
 		err = handleRetry(
@@ -548,6 +728,27 @@ func (task *ResourcePushTask) Run(send func(string), abort func()) {
 			abort()
 			return
 		}
+		var unknownLanguageCodes []string
+		for languageCode := range paths {
+			if _, exists := allLanguages[languageCode]; !exists {
+				unknownLanguageCodes = append(unknownLanguageCodes, languageCode)
+			}
+		}
+		if len(unknownLanguageCodes) > 0 {
+			sort.Strings(unknownLanguageCodes)
+			message := fmt.Sprintf(
+				"the file filter matched unknown language codes: %s",
+				strings.Join(unknownLanguageCodes, ", "),
+			)
+			if args.ValidateLanguages {
+				sendMessage(message, true)
+				if !args.Skip {
+					abort()
+				}
+				return
+			}
+			sendMessage("Skipping files because "+message, false)
+		}
 		for _, languageCode := range newLanguageCodes {
 			_, exists := allLanguages[languageCode]
 			if !exists || fmt.Sprintf("l:%s", languageCode) == sourceLanguage.Id {
@@ -822,32 +1023,82 @@ func (task *TranslationFileTask) Run(send func(string), abort func()) {
 	sendMessage("Done", false)
 }
 
+/*
+Warn about the files being pushed for a resource that don't follow the line
+endings, byte order mark or encoding declared for it. The files are pushed regardless; the
+warnings are meant to catch editors or tools that rewrote them.
+*/
+func warnAboutTextFormats(cfgResource *config.Resource, paths []string) {
+	if !hasTextFormat(cfgResource) {
+		return
+	}
+	curDir, err := os.Getwd()
+	if err != nil {
+		return
+	}
+	for _, path := range paths {
+		problems := checkTextFormat(cfgResource, path)
+		if len(problems) == 0 {
+			continue
+		}
+		relativePath := path
+		if filepath.IsAbs(path) {
+			if rel, err := filepath.Rel(curDir, path); err == nil {
+				relativePath = rel
+			}
+		}
+		fmt.Fprintf(
+			os.Stderr,
+			"%s - Warning: '%s' does not follow the configured format: %s\n",
+			cfgResource.GetAPv3Id(),
+			relativePath,
+			strings.Join(problems, ", "),
+		)
+	}
+}
+
 func getFilesToPush(
 	curDir, fileFilter string,
+	cfg *config.Config,
+	cfgResource *config.Resource,
 	localToRemoteLanguageMappings map[string]string,
 	remoteStats map[string]*jsonapi.Resource,
-	overrides map[string]string,
 	args PushCommandArguments,
 	resourceIsNew bool,
 ) (map[string]string, []string, error) {
 	paths := make(map[string]string)
 	var newLanguageCodes []string
 
-	allLocalLanguages := searchFileFilter(curDir, fileFilter)
+	var allLocalLanguages map[string]string
+	if args.TranslationFile != "" {
+		// The file given in the command line replaces both the file filter
+		// and the overrides
+		allLocalLanguages = map[string]string{
+			args.Languages[0]: args.TranslationFile,
+		}
+	} else {
+		allLocalLanguages = searchFileFilter(
+			curDir, fileFilter, cfgResource.FileFilterExclude...,
+		)
 
-	if len(overrides) > 0 {
-		for languageCode, customPath := range overrides {
+		for languageCode, customPath := range cfgResource.Overrides {
 			// Add the Resource file filter overrides per lang
 			path := filepath.Join(curDir, customPath)
 			// In case of xliff/json add the extension
-			if args.Xliff {
-				path = fmt.Sprintf("%s.xlf", path)
-			}
+			path = setFileTypeExtensions(args.FileType, path)
 			allLocalLanguages[languageCode] = path
 		}
 	}
+	addConventionLanguageMappings(
+		*cfg, *cfgResource, getLocalLanguageCodes(allLocalLanguages),
+		localToRemoteLanguageMappings,
+		makeRemoteToLocalLanguageMappings(localToRemoteLanguageMappings),
+	)
 
 	for localLanguageCode, path := range allLocalLanguages {
+		if !isChangedPath(args.changedPaths, path) {
+			continue
+		}
 		remoteLanguageCode, exists := localToRemoteLanguageMappings[localLanguageCode]
 		if !exists {
 			remoteLanguageCode = localLanguageCode
@@ -897,7 +1148,9 @@ func pushTranslation(
 		Type: "languages",
 		Id:   fmt.Sprintf("l:%s", languageCode),
 	}
-	upload, err := txapi.UploadTranslation(api, resource, language, file, args.Xliff)
+	upload, err := txapi.UploadTranslation(
+		api, resource, language, file, args.FileType,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -937,3 +1190,154 @@ func shouldSkipPush(
 	// resource-language
 	return localTime.Before(remoteTime), nil
 }
+
+/*
+Keep only the resources that have at least one file, among the ones we are
+about to push, that is in 'args.changedPaths'. The source file counts when
+pushing sources and the files matched by the file filter (or the overrides)
+count when pushing translations.
+*/
+func filterChangedResources(
+	cfgResources []*config.Resource, args PushCommandArguments,
+) ([]*config.Resource, error) {
+	curDir, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+
+	var result []*config.Resource
+	for _, cfgResource := range cfgResources {
+		changed := false
+		if args.Source || !args.Translation {
+			sourceFile := cfgResource.SourceFile
+			if args.SourceFile != "" {
+				sourceFile = args.SourceFile
+			}
+			changed = isChangedPath(args.changedPaths, sourceFile)
+		}
+		if !changed && args.Translation && args.TranslationFile != "" {
+			changed = isChangedPath(args.changedPaths, args.TranslationFile)
+		} else if !changed && args.Translation && cfgResource.FileFilter != "" {
+			fileFilter := setFileTypeExtensions(args.FileType, cfgResource.FileFilter)
+			paths := searchFileFilter(
+				curDir, fileFilter, cfgResource.FileFilterExclude...,
+			)
+			for languageCode, customPath := range cfgResource.Overrides {
+				path := setFileTypeExtensions(
+					args.FileType, filepath.Join(curDir, customPath),
+				)
+				paths[languageCode] = path
+			}
+			for _, path := range paths {
+				if isChangedPath(args.changedPaths, path) {
+					changed = true
+					break
+				}
+			}
+		}
+		if changed {
+			result = append(result, cfgResource)
+		}
+	}
+	return result, nil
+}
+
+/*
+Return whether 'path' is one of the 'changedPaths'. A nil 'changedPaths' means
+that we are not filtering by changes, so every path counts as changed.
+*/
+func isChangedPath(changedPaths map[string]bool, path string) bool {
+	if changedPaths == nil {
+		return true
+	}
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+	return changedPaths[absPath]
+}
+
+/*
+Save everything that can be read from the standard input to a temporary file
+and return its path, so that it can be pushed like any other local file. The
+caller is responsible for removing the file.
+*/
+func copyStdinToTempFile() (string, error) {
+	file, err := os.CreateTemp("", "tx-push-")
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	_, err = io.Copy(file, os.Stdin)
+	if err != nil {
+		os.Remove(file.Name())
+		return "", err
+	}
+	return file.Name(), nil
+}
+
+/*
+Refuse to push to a branch resource that was created for another git branch.
+Different branches can end up with the same slug, eg 'feat/a_b' and
+'feat-a-b', or when long names are cut by 'branch_max_length'.
+*/
+func checkBranchResourceName(
+	resource *jsonapi.Resource, branch string, legacyBranch string,
+) error {
+	if branch == "" {
+		return nil
+	}
+	var resourceAttributes txapi.ResourceAttributes
+	err := resource.MapAttributes(&resourceAttributes)
+	if err != nil {
+		return err
+	}
+	createdFor, ok := parseBranchResourceName(resourceAttributes.Name)
+	if !ok || createdFor == branch {
+		return nil
+	}
+	if legacyBranch != "" && createdFor == legacyBranch {
+		// Created by an older version, see useLegacyBranchResource
+		return nil
+	}
+	return fmt.Errorf(
+		"resource '%s' was created for branch '%s', not '%s'; change "+
+			"'branch_template' in the config so that the branches get "+
+			"different slugs",
+		resourceAttributes.Slug,
+		createdFor,
+		branch,
+	)
+}
+
+/*
+Pick the base of a branch resource for '--base auto': the nearest of the git
+branches that HEAD was branched off that has a branch resource on Transifex, or
+the main resource ("") if none does.
+*/
+func findAutoBase(
+	api *jsonapi.Connection,
+	cfg *config.Config,
+	cfgResource *config.Resource,
+	mainResourceSlug string,
+	candidates []string,
+) (string, error) {
+	for _, candidate := range candidates {
+		resourceId := fmt.Sprintf(
+			"o:%s:p:%s:r:%s",
+			cfgResource.OrganizationSlug,
+			cfgResource.ProjectSlug,
+			getBranchResourceSlug(
+				cfg.Local.BranchNaming, mainResourceSlug, candidate,
+			),
+		)
+		resource, err := txapi.GetResourceById(api, resourceId)
+		if err != nil {
+			return "", err
+		}
+		if resource != nil {
+			return candidate, nil
+		}
+	}
+	return "", nil
+}
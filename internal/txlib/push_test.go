@@ -2,8 +2,11 @@ package txlib
 
 import (
 	"fmt"
+	"io"
 	"net/url"
 	"os"
+	"os/exec"
+	"runtime"
 	"strings"
 	"testing"
 	"time"
@@ -70,6 +73,189 @@ func TestPushSpecificResource(t *testing.T) {
 	testSimpleGet(t, mockData, "/resource_strings_async_uploads/upload_1")
 }
 
+func TestPushPrePushHook(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hook commands in this test need a POSIX shell")
+	}
+	afterTest := beforeTest(t, nil, nil)
+	defer afterTest()
+
+	mockData := jsonapi.MockData{
+		"/languages":           getLanguagesEndpoint([]string{"en", "fr", "el"}),
+		resourceUrl:            getResourceEndpoint(),
+		projectUrl:             getProjectEndpoint(),
+		statsUrlSourceLanguage: getStatsEndpointSourceLanguage(),
+		sourceUploadsUrl:       getSourceUploadPostEndpoint(),
+		sourceUploadUrl:        getSourceUploadGetEndpoint(),
+	}
+	api := jsonapi.GetTestConnection(mockData)
+
+	cfg := getStandardConfig()
+	cfg.Local.Hooks.PrePush = `echo "$TX_RESOURCE $TX_LANGUAGE $TX_FILE" > hook.log`
+	cfg.Local.Resources[0].SourceLanguage = "en"
+	err := PushCommand(cfg, api, PushCommandArguments{
+		Force: true, Branch: "-1", Workers: 1,
+	})
+	if err != nil {
+		t.Errorf("%s", err)
+	}
+
+	assertFileContent(t, "hook.log", "o:orgslug:p:projslug:r:resslug en aaa.json")
+	testSimpleUpload(t, mockData, sourceUploadsUrl)
+}
+
+func TestPushPrePushHookFails(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hook commands in this test need a POSIX shell")
+	}
+	afterTest := beforeTest(t, nil, nil)
+	defer afterTest()
+
+	mockData := jsonapi.MockData{
+		resourceUrl: getResourceEndpoint(),
+	}
+	api := jsonapi.GetTestConnection(mockData)
+
+	cfg := getStandardConfig()
+	cfg.Local.Resources[0].Hooks.PrePush = "echo 'something broke' && exit 3"
+	err := PushCommand(cfg, api, PushCommandArguments{
+		Force: true, Branch: "-1", Workers: 1, Silent: true,
+	})
+	if err == nil {
+		t.Error("Expected the push to fail")
+	}
+	if len(mockData[resourceUrl].Requests[0].Request.Method) != 0 {
+		t.Error("Resource was fetched even though the hook failed")
+	}
+}
+
+func TestPushCustomSourceFile(t *testing.T) {
+	afterTest := beforeTest(t, nil, []string{"generated.json"})
+	defer afterTest()
+
+	mockData := jsonapi.MockData{
+		resourceUrl:            getResourceEndpoint(),
+		projectUrl:             getProjectEndpoint(),
+		statsUrlSourceLanguage: getStatsEndpointSourceLanguage(),
+		sourceUploadsUrl:       getSourceUploadPostEndpoint(),
+		sourceUploadUrl:        getSourceUploadGetEndpoint(),
+	}
+	api := jsonapi.GetTestConnection(mockData)
+
+	err := PushCommand(getStandardConfig(), api, PushCommandArguments{
+		Force:       true,
+		ResourceIds: []string{"projslug.resslug"},
+		SourceFile:  "generated.json",
+		Branch:      "-1",
+		Workers:     1,
+	})
+	if err != nil {
+		t.Error(err)
+	}
+
+	testSimpleUpload(t, mockData, sourceUploadsUrl)
+	payload := string(mockData[sourceUploadsUrl].Requests[0].Request.Payload)
+	if !strings.Contains(payload, `{"hello": "world"}`) {
+		t.Errorf("Expected custom source file in upload, got '%s'", payload)
+	}
+}
+
+func TestPushSourceFileFromStdin(t *testing.T) {
+	afterTest := beforeTest(t, nil, nil)
+	defer afterTest()
+
+	rescueStdin := os.Stdin
+	defer func() { os.Stdin = rescueStdin }()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Stdin = r
+	_, err = w.WriteString(`{"from": "stdin"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+
+	mockData := jsonapi.MockData{
+		resourceUrl:            getResourceEndpoint(),
+		projectUrl:             getProjectEndpoint(),
+		statsUrlSourceLanguage: getStatsEndpointSourceLanguage(),
+		sourceUploadsUrl:       getSourceUploadPostEndpoint(),
+		sourceUploadUrl:        getSourceUploadGetEndpoint(),
+	}
+	api := jsonapi.GetTestConnection(mockData)
+
+	err = PushCommand(getStandardConfig(), api, PushCommandArguments{
+		Force:      true,
+		SourceFile: "-",
+		Branch:     "-1",
+		Workers:    1,
+	})
+	if err != nil {
+		t.Error(err)
+	}
+
+	testSimpleUpload(t, mockData, sourceUploadsUrl)
+	payload := string(mockData[sourceUploadsUrl].Requests[0].Request.Payload)
+	if !strings.Contains(payload, `{"from": "stdin"}`) {
+		t.Errorf("Expected standard input in upload, got '%s'", payload)
+	}
+}
+
+func TestPushCustomTranslationFile(t *testing.T) {
+	afterTest := beforeTest(t, nil, []string{"tmp-el.json"})
+	defer afterTest()
+
+	cfg := getStandardConfig()
+	cfg.Local.Resources[0].FileFilter = ""
+
+	mockData := jsonapi.MockData{
+		"/languages":          getLanguagesEndpoint([]string{"en", "fr", "el"}),
+		resourceUrl:           getResourceEndpoint(),
+		projectUrl:            getProjectEndpoint(),
+		statsUrlAllLanguages:  getStatsEndpointAllLanguages(),
+		translationUploadsUrl: getTranslationUploadPostEndpoint(),
+		translationUploadUrl:  getTranslationUploadGetEndpoint(),
+	}
+	api := jsonapi.GetTestConnection(mockData)
+
+	err := PushCommand(cfg, api, PushCommandArguments{
+		Translation:     true,
+		Force:           true,
+		Languages:       []string{"el"},
+		TranslationFile: "tmp-el.json",
+		Branch:          "-1",
+		Workers:         1,
+	})
+	if err != nil {
+		t.Error(err)
+	}
+
+	testSimpleUpload(t, mockData, translationUploadsUrl)
+	testSimpleGet(t, mockData, translationUploadUrl)
+}
+
+func TestPushCustomSourceFileMultipleResources(t *testing.T) {
+	afterTest := beforeTest(t, nil, nil)
+	defer afterTest()
+
+	cfg := getStandardConfig()
+	cfg.Local.Resources = append(cfg.Local.Resources, cfg.Local.Resources[0])
+	cfg.Local.Resources[1].ResourceSlug = "otherslug"
+
+	api := jsonapi.GetTestConnection(jsonapi.MockData{})
+	err := PushCommand(cfg, api, PushCommandArguments{
+		Force:      true,
+		SourceFile: "aaa.json",
+		Branch:     "-1",
+		Workers:    1,
+	})
+	if err == nil {
+		t.Error("Expected error")
+	}
+}
+
 func TestPushCommandResourceDoesNotExist(t *testing.T) {
 	afterTest := beforeTest(t, nil, nil)
 	defer afterTest()
@@ -226,7 +412,44 @@ func TestPushXliff(t *testing.T) {
 	err = PushCommand(getStandardConfig(), api, PushCommandArguments{
 		Translation: true,
 		Force:       true,
-		Xliff:       true,
+		FileType:    "xliff",
+		Branch:      "-1",
+		Workers:     1,
+	})
+	if err != nil {
+		t.Error(err)
+	}
+
+	testSimpleGet(t, mockData, resourceUrl)
+	testSimpleGet(t, mockData, projectUrl)
+	testSimpleGet(t, mockData, statsUrlAllLanguages)
+	testSimpleUpload(t, mockData, translationUploadsUrl)
+	testSimpleGet(t, mockData, translationUploadUrl)
+}
+
+func TestPushJsonWithOverrides(t *testing.T) {
+	afterTest := beforeTest(t, nil, []string{"custom.json.json"})
+	defer afterTest()
+
+	cfg := getStandardConfig()
+	cfg.Local.Resources[0].Overrides = map[string]string{
+		"el": "custom.json",
+	}
+
+	mockData := jsonapi.MockData{
+		"/languages":          getLanguagesEndpoint([]string{"en", "fr", "el"}),
+		resourceUrl:           getResourceEndpoint(),
+		projectUrl:            getProjectEndpoint(),
+		statsUrlAllLanguages:  getStatsEndpointAllLanguages(),
+		translationUploadsUrl: getTranslationUploadPostEndpoint(),
+		translationUploadUrl:  getTranslationUploadGetEndpoint(),
+	}
+	api := jsonapi.GetTestConnection(mockData)
+
+	err := PushCommand(cfg, api, PushCommandArguments{
+		Translation: true,
+		Force:       true,
+		FileType:    "json",
 		Branch:      "-1",
 		Workers:     1,
 	})
@@ -239,6 +462,11 @@ func TestPushXliff(t *testing.T) {
 	testSimpleGet(t, mockData, statsUrlAllLanguages)
 	testSimpleUpload(t, mockData, translationUploadsUrl)
 	testSimpleGet(t, mockData, translationUploadUrl)
+
+	payload := string(mockData[translationUploadsUrl].Requests[0].Request.Payload)
+	if !strings.Contains(payload, "name=\"file_type\"\r\n\r\njson") {
+		t.Errorf("Expected 'json' file type in upload, got '%s'", payload)
+	}
 }
 
 func TestPushTranslationWithLanguageMapping(t *testing.T) {
@@ -342,6 +570,47 @@ func TestPushTranslationRemoteLanguageDoesNotExist(t *testing.T) {
 	testSimpleGet(t, mockData, statsUrlAllLanguages)
 }
 
+func TestPushTranslationValidateLanguages(t *testing.T) {
+	afterTest := beforeTest(t, []string{"el", "node_modules"}, nil)
+	defer afterTest()
+
+	mockData := jsonapi.MockData{
+		"/languages":          getLanguagesEndpoint([]string{"en", "fr", "el"}),
+		resourceUrl:           getResourceEndpoint(),
+		projectUrl:            getProjectEndpoint(),
+		statsUrlAllLanguages:  getStatsEndpointAllLanguages(),
+		translationUploadsUrl: getTranslationUploadPostEndpoint(),
+		translationUploadUrl:  getTranslationUploadGetEndpoint(),
+	}
+	api := jsonapi.GetTestConnection(mockData)
+
+	err := PushCommand(getStandardConfig(), api, PushCommandArguments{
+		Translation: true,
+		All:         true,
+		Force:       true,
+		Branch:      "-1",
+		Workers:     1,
+		Silent:      true,
+	})
+	if err != nil {
+		t.Error(err)
+	}
+	testSimpleUpload(t, mockData, translationUploadsUrl)
+
+	err = PushCommand(getStandardConfig(), api, PushCommandArguments{
+		Translation:       true,
+		All:               true,
+		Force:             true,
+		Branch:            "-1",
+		Workers:           1,
+		Silent:            true,
+		ValidateLanguages: true,
+	})
+	if err == nil {
+		t.Error("Expected an error for an unknown language code")
+	}
+}
+
 func TestPushTranslationLocalFileIsOlderThanRemote(t *testing.T) {
 	afterTest := beforeTest(t, []string{"fr"}, nil)
 	defer afterTest()
@@ -703,6 +972,73 @@ func TestPushAll(t *testing.T) {
 	testSimpleGet(t, mockData, translationUploadUrl)
 }
 
+func TestPushChanged(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	afterTest := beforeTest(t, []string{"el", "fr"}, nil)
+	defer afterTest()
+
+	for _, gitArgs := range [][]string{
+		{"init", "-q"},
+		{"add", "."},
+		{"-c", "user.name=tx", "-c", "user.email=tx@example.com",
+			"commit", "-q", "-m", "initial"},
+	} {
+		err := exec.Command("git", gitArgs...).Run()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Nothing changed, nothing should be requested
+	mockData := jsonapi.MockData{}
+	api := jsonapi.GetTestConnection(mockData)
+	err := PushCommand(getStandardConfig(), api, PushCommandArguments{
+		Translation: true,
+		Force:       true,
+		All:         true,
+		Branch:      "-1",
+		Workers:     1,
+		Changed:     true,
+	})
+	if err != nil {
+		t.Error(err)
+	}
+
+	// Only 'el' changed, 'fr' should not be created remotely even with --all
+	err = os.WriteFile("aaa-el.json", []byte(`{"hello": "kosme"}`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mockData = jsonapi.MockData{
+		"/languages":          getLanguagesEndpoint([]string{"en", "fr", "el"}),
+		resourceUrl:           getResourceEndpoint(),
+		projectUrl:            getProjectEndpoint(),
+		statsUrlAllLanguages:  getStatsEndpointAllLanguages(),
+		translationUploadsUrl: getTranslationUploadPostEndpoint(),
+		translationUploadUrl:  getTranslationUploadGetEndpoint(),
+	}
+	api = jsonapi.GetTestConnection(mockData)
+	err = PushCommand(getStandardConfig(), api, PushCommandArguments{
+		Translation: true,
+		Force:       true,
+		All:         true,
+		Branch:      "-1",
+		Workers:     1,
+		Since:       "HEAD",
+	})
+	if err != nil {
+		t.Error(err)
+	}
+
+	testSimpleGet(t, mockData, resourceUrl)
+	testSimpleGet(t, mockData, projectUrl)
+	testSimpleGet(t, mockData, statsUrlAllLanguages)
+	testSimpleUpload(t, mockData, translationUploadsUrl)
+	testSimpleGet(t, mockData, translationUploadUrl)
+}
+
 func getEmptyEndpoint() *jsonapi.MockEndpoint {
 	return &jsonapi.MockEndpoint{
 		Requests: []jsonapi.MockRequest{{
@@ -809,3 +1145,262 @@ func testSimpleUpload(t *testing.T, mockData jsonapi.MockData, path string) {
 		t.Errorf("Something was wrong with the request '%+v'", actual)
 	}
 }
+
+func TestPushCommandBranchResourceCollision(t *testing.T) {
+	afterTest := beforeTest(t, nil, nil)
+	defer afterTest()
+
+	// 'Branch' and 'branch' have the same slug
+	branchResourceUrl := "/resources/o:orgslug:p:projslug:r:branch--resslug"
+	mockData := jsonapi.MockData{
+		"/languages": getLanguagesEndpoint([]string{"en", "fr", "el"}),
+		branchResourceUrl: jsonapi.GetMockTextResponse(
+			`{"data": {
+				"type": "resources",
+				"id": "o:orgslug:p:projslug:r:branch--resslug",
+				"attributes": {
+					"name": "aaa.json (branch Branch)", "slug": "branch--resslug"
+				}
+			}}`,
+		),
+	}
+	api := jsonapi.GetTestConnection(mockData)
+
+	err := PushCommand(getStandardConfig(), api, PushCommandArguments{
+		Force:   true,
+		Branch:  "branch",
+		Base:    "-1",
+		Workers: 1,
+		Silent:  true,
+	})
+	if err == nil {
+		t.Error("Expected the push to be refused")
+	}
+	testSimpleGet(t, mockData, branchResourceUrl)
+}
+
+func TestPushCommandLegacyBranchResource(t *testing.T) {
+	afterTest := beforeTest(t, nil, nil)
+	defer afterTest()
+	defer clearBranchVariables()()
+	os.Setenv("TX_BRANCH", "feature/x")
+
+	// An older version created 'x--resslug' for the branch, which it knew as
+	// 'x'
+	resourceId := "o:orgslug:p:projslug:r:x--resslug"
+	resourceUrl := fmt.Sprintf("/resources/%s", resourceId)
+	resourceResponse := jsonapi.MockRequest{Response: jsonapi.MockResponse{
+		Text: fmt.Sprintf(
+			`{"data": {
+				"type": "resources",
+				"id": "%s",
+				"attributes": {
+					"name": "aaa.json (branch x)", "slug": "x--resslug"
+				},
+				"relationships": {"project": {"data": {"type": "projects",
+				                                       "id": "%s"}}}
+			}}`,
+			resourceId,
+			projectId,
+		),
+	}}
+	statsUrl := fmt.Sprintf(
+		"/resource_language_stats?%s=%s&%s=%s&%s=%s",
+		url.QueryEscape("filter[language]"),
+		url.QueryEscape("l:en"),
+		url.QueryEscape("filter[project]"),
+		url.QueryEscape(projectId),
+		url.QueryEscape("filter[resource]"),
+		url.QueryEscape(resourceId),
+	)
+	mockData := jsonapi.MockData{
+		"/languages": getLanguagesEndpoint([]string{"en", "fr", "el"}),
+		"/resources/o:orgslug:p:projslug:r:feature-x--resslug": getEmptyEndpoint(),
+		resourceUrl: &jsonapi.MockEndpoint{
+			Requests: []jsonapi.MockRequest{resourceResponse, resourceResponse},
+		},
+		projectUrl:       getProjectEndpoint(),
+		statsUrl:         getStatsEndpointSourceLanguage(),
+		sourceUploadsUrl: getSourceUploadPostEndpoint(),
+		sourceUploadUrl:  getSourceUploadGetEndpoint(),
+	}
+	api := jsonapi.GetTestConnection(mockData)
+
+	err := PushCommand(getStandardConfig(), api, PushCommandArguments{
+		Force:   true,
+		Branch:  "",
+		Base:    "-1",
+		Workers: 1,
+		Silent:  true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testMultipleRequests(
+		t, mockData, resourceUrl, []string{"GET", "GET"}, []string{"", ""},
+	)
+	testSimpleUpload(t, mockData, sourceUploadsUrl)
+}
+
+func TestPushCommandBranchAutoBase(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	afterTest := beforeTest(t, nil, nil)
+	defer afterTest()
+	defer clearBranchVariables()()
+	initStackedGitBranches(t)
+
+	branchResourceUrl := "/resources/o:orgslug:p:projslug:r:feature-b--resslug"
+	baseResourceId := "o:orgslug:p:projslug:r:feature-a--resslug"
+	baseResourceResponse := jsonapi.MockResponse{Text: fmt.Sprintf(
+		`{"data": {"type": "resources", "id": "%s",
+		  "attributes": {"slug": "feature-a--resslug"}}}`,
+		baseResourceId,
+	)}
+	mockData := jsonapi.MockData{
+		"/languages":      getLanguagesEndpoint([]string{"en", "fr", "el"}),
+		branchResourceUrl: getEmptyEndpoint(),
+		// Once to pick it as the base and once to check it exists
+		"/resources/" + baseResourceId: &jsonapi.MockEndpoint{
+			Requests: []jsonapi.MockRequest{
+				{Response: baseResourceResponse},
+				{Response: baseResourceResponse},
+			},
+		},
+		projectUrl:             getProjectEndpoint(),
+		statsUrlSourceLanguage: getStatsEndpointSourceLanguage(),
+		resourcesUrl:           getResourceCreatedEndpoint(),
+		sourceUploadsUrl:       getSourceUploadPostEndpoint(),
+		sourceUploadUrl:        getSourceUploadGetEndpoint(),
+	}
+	api := jsonapi.GetTestConnection(mockData)
+
+	err := PushCommand(getStandardConfig(), api, PushCommandArguments{
+		Force:   true,
+		Branch:  "",
+		Base:    "auto",
+		Workers: 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testSimplePost(
+		t,
+		mockData,
+		resourcesUrl,
+		fmt.Sprintf(`{"data": {
+			"type": "resources",
+			"attributes": {
+				"name": "aaa.json (branch feature-b)", "slug": "feature-b--resslug"
+			},
+			"relationships": {
+				"base": {"data": {"type": "resources", "id": "%s"}},
+				"project": {"data": {"type": "projects", "id": "o:orgslug:p:projslug"}},
+				"i18n_format": {"data": {"type": "i18n_formats", "id": "I18N_TYPE"}}
+			}
+		}}`, baseResourceId),
+	)
+}
+
+func TestPushBomlessUtf16File(t *testing.T) {
+	afterTest := beforeTest(t, nil, nil)
+	defer afterTest()
+
+	cfg := getStandardConfig()
+	cfgResource := &cfg.Local.Resources[0]
+	bom := false
+	cfgResource.Encoding = "utf-16"
+	cfgResource.Bom = &bom
+
+	// Store the source file the way pull does
+	err := os.WriteFile("aaa.json", []byte(`{"hello": "world"}`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = normalizeFile(cfgResource, "aaa.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile("aaa.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) < 2 || data[1] != 0 {
+		t.Fatalf("Expected a UTF-16LE file without a BOM, got %q", data)
+	}
+
+	mockData := jsonapi.MockData{
+		"/languages":           getLanguagesEndpoint([]string{"en", "fr", "el"}),
+		resourceUrl:            getResourceEndpoint(),
+		projectUrl:             getProjectEndpoint(),
+		statsUrlSourceLanguage: getStatsEndpointSourceLanguage(),
+		sourceUploadsUrl:       getSourceUploadPostEndpoint(),
+		sourceUploadUrl:        getSourceUploadGetEndpoint(),
+	}
+	api := jsonapi.GetTestConnection(mockData)
+
+	rescueStderr := os.Stderr
+	r, w, _ := os.Pipe()
+	os.Stderr = w
+	err = PushCommand(cfg, api, PushCommandArguments{
+		Force: true, Branch: "-1", Workers: 1,
+	})
+	w.Close()
+	out, _ := io.ReadAll(r)
+	os.Stderr = rescueStderr
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(out), "does not follow the configured format") {
+		t.Errorf("Unexpected warning: %s", out)
+	}
+}
+
+func TestPushWarnsOnlyAboutPushedFiles(t *testing.T) {
+	afterTest := beforeTest(t, []string{"el", "fr"}, nil)
+	defer afterTest()
+
+	cfg := getStandardConfig()
+	cfg.Local.Resources[0].Eol = "lf"
+	for _, path := range []string{"aaa-el.json", "aaa-fr.json"} {
+		err := os.WriteFile(path, []byte("{\r\n}\r\n"), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	mockData := jsonapi.MockData{
+		"/languages":          getLanguagesEndpoint([]string{"en", "fr", "el"}),
+		resourceUrl:           getResourceEndpoint(),
+		projectUrl:            getProjectEndpoint(),
+		statsUrlAllLanguages:  getStatsEndpointAllLanguages(),
+		translationUploadsUrl: getTranslationUploadPostEndpoint(),
+		translationUploadUrl:  getTranslationUploadGetEndpoint(),
+	}
+	api := jsonapi.GetTestConnection(mockData)
+
+	rescueStderr := os.Stderr
+	r, w, _ := os.Pipe()
+	os.Stderr = w
+	err := PushCommand(cfg, api, PushCommandArguments{
+		Translation: true,
+		Languages:   []string{"el"},
+		Force:       true,
+		Branch:      "-1",
+		Workers:     1,
+	})
+	w.Close()
+	out, _ := io.ReadAll(r)
+	os.Stderr = rescueStderr
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(out), "'aaa-el.json' does not follow") {
+		t.Errorf("Expected a warning about 'aaa-el.json', got: %s", out)
+	}
+	if strings.Contains(string(out), "aaa-fr.json") {
+		t.Errorf("Unexpected warning about a file that wasn't pushed: %s", out)
+	}
+}
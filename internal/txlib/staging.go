@@ -0,0 +1,193 @@
+package txlib
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/transifex/cli/pkg/txapi"
+)
+
+const stagedSuffix = ".tx-staged"
+const backupSuffix = ".tx-backup"
+
+/*
+Keep track of files that are downloaded by 'tx pull' but must not replace the
+local files until every download has succeeded.
+
+Every FilePullTask asks for a staged path with 'stage' and downloads there
+instead of the real destination. When the pull is over, 'commit' moves all the
+staged files into place, or 'rollback' removes them and leaves the working tree
+untouched.
+
+	staging := newPullStaging()
+	path := staging.stage("locale/fr.po")  // "locale/fr.po.tx-staged"
+	// Download into 'path'
+	err := staging.commit()  // "locale/fr.po.tx-staged" -> "locale/fr.po"
+*/
+type pullStaging struct {
+	mutex sync.Mutex
+	// If set, files are staged in this directory instead of next to their
+	// destination
+	dir string
+	// Destination path -> staged path
+	files map[string]string
+	// Destination path -> information about the downloaded file, used for the
+	// manifest of 'tx pull --archive'
+	info map[string]stagedFileInfo
+}
+
+type stagedFileInfo struct {
+	ResourceId   string
+	LanguageCode string
+	Mode         string
+	Stats        *txapi.ResourceLanguageStatsAttributes
+}
+
+func newPullStaging() *pullStaging {
+	return &pullStaging{
+		files: make(map[string]string),
+		info:  make(map[string]stagedFileInfo),
+	}
+}
+
+/*
+Create a staging area that keeps the downloaded files in 'dir', so that the
+working tree is not touched at all until 'commit' is called.
+*/
+func newPullStagingInDir(dir string) *pullStaging {
+	return &pullStaging{
+		dir:   dir,
+		files: make(map[string]string),
+		info:  make(map[string]stagedFileInfo),
+	}
+}
+
+/*
+Return the path a file destined for 'filePath' should be downloaded to. Unless
+a staging directory was given, staged files are placed in the same directory as
+their destination so that moving them into place is a rename within the same
+filesystem.
+*/
+func (staging *pullStaging) stage(filePath string) string {
+	staging.mutex.Lock()
+	defer staging.mutex.Unlock()
+
+	var stagedPath string
+	if staging.dir == "" {
+		stagedPath = filePath + stagedSuffix
+	} else {
+		stagedPath = filepath.Join(
+			staging.dir,
+			fmt.Sprintf("%d-%s", len(staging.files), filepath.Base(filePath)),
+		)
+	}
+	staging.files[filePath] = stagedPath
+	return stagedPath
+}
+
+/*
+Record information about the file staged for 'filePath'.
+*/
+func (staging *pullStaging) describe(filePath string, info stagedFileInfo) {
+	staging.mutex.Lock()
+	defer staging.mutex.Unlock()
+
+	staging.info[filePath] = info
+}
+
+/*
+Move every staged file into its destination. If any of the moves fails, the
+files that were already moved are reverted, so that the working tree ends up
+either with all the new files or with all the original ones.
+*/
+func (staging *pullStaging) commit() error {
+	staging.mutex.Lock()
+	defer staging.mutex.Unlock()
+
+	var committed []string
+	backups := make(map[string]string)
+
+	revert := func() {
+		for _, filePath := range committed {
+			backupPath, exists := backups[filePath]
+			if exists {
+				os.Rename(backupPath, filePath)
+			} else {
+				os.Remove(filePath)
+			}
+		}
+	}
+
+	for filePath, stagedPath := range staging.files {
+		_, err := os.Stat(stagedPath)
+		if os.IsNotExist(err) {
+			// Nothing was downloaded for this file, eg the task skipped it
+			continue
+		}
+		_, err = os.Stat(filePath)
+		if err == nil {
+			backupPath := filePath + backupSuffix
+			err = os.Rename(filePath, backupPath)
+			if err != nil {
+				revert()
+				staging.removeStagedFiles()
+				return fmt.Errorf("could not back up '%s': %w", filePath, err)
+			}
+			backups[filePath] = backupPath
+		}
+		err = os.Rename(stagedPath, filePath)
+		if err != nil {
+			backupPath, exists := backups[filePath]
+			if exists {
+				os.Rename(backupPath, filePath)
+			}
+			revert()
+			staging.removeStagedFiles()
+			return fmt.Errorf("could not move '%s' into place: %w", filePath, err)
+		}
+		committed = append(committed, filePath)
+	}
+
+	for _, backupPath := range backups {
+		os.Remove(backupPath)
+	}
+	staging.files = make(map[string]string)
+	return nil
+}
+
+/*
+Discard every staged file, leaving the destinations untouched.
+*/
+func (staging *pullStaging) rollback() {
+	staging.mutex.Lock()
+	defer staging.mutex.Unlock()
+
+	staging.removeStagedFiles()
+}
+
+func (staging *pullStaging) removeStagedFiles() {
+	for _, stagedPath := range staging.files {
+		os.Remove(stagedPath)
+	}
+	staging.files = make(map[string]string)
+}
+
+/*
+Return the files that have actually been downloaded into the staging area so
+far, keyed by their destination.
+*/
+func (staging *pullStaging) stagedFiles() map[string]string {
+	staging.mutex.Lock()
+	defer staging.mutex.Unlock()
+
+	result := make(map[string]string)
+	for filePath, stagedPath := range staging.files {
+		_, err := os.Stat(stagedPath)
+		if err == nil {
+			result[filePath] = stagedPath
+		}
+	}
+	return result
+}
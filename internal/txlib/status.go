@@ -1,16 +1,59 @@
 package txlib
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/fatih/color"
 	"github.com/transifex/cli/internal/txlib/config"
 	"github.com/transifex/cli/pkg/jsonapi"
 	"github.com/transifex/cli/pkg/txapi"
+	"github.com/transifex/cli/pkg/worker_pool"
 )
 
 type StatusCommandArguments struct {
 	ResourceIds []string
+	Workers     int
+	Json        bool
+}
+
+// What is known about a resource's languages, locally and on Transifex
+type statusReport struct {
+	Resource       string           `json:"resource"`
+	Id             string           `json:"id"`
+	SourceLanguage string           `json:"source_language"`
+	Languages      []statusLanguage `json:"languages"`
+	// Set if the remote information could not be fetched; the languages are
+	// then only the local ones
+	Error string `json:"error,omitempty"`
+}
+
+type statusLanguage struct {
+	Code      string `json:"code"`
+	LocalCode string `json:"local_code,omitempty"`
+	Path      string `json:"path,omitempty"`
+	Source    bool   `json:"source,omitempty"`
+	// Only set when the language exists on Transifex
+	Stats *statusStats `json:"stats,omitempty"`
+	// "up to date", "behind remote", "missing locally", "not in project" or
+	// "" if unknown
+	State string `json:"state,omitempty"`
+}
+
+type statusStats struct {
+	TotalStrings         int    `json:"total_strings"`
+	TranslatedStrings    int    `json:"translated_strings"`
+	ReviewedStrings      int    `json:"reviewed_strings"`
+	ProofreadStrings     int    `json:"proofread_strings"`
+	TranslatedPercentage int    `json:"translated_perc"`
+	ReviewedPercentage   int    `json:"reviewed_perc"`
+	ProofreadPercentage  int    `json:"proofread_perc"`
+	LastUpdate           string `json:"last_update,omitempty"`
 }
 
 func StatusCommand(
@@ -20,7 +63,9 @@ func StatusCommand(
 ) error {
 	var cfgResources []config.Resource
 
-	fmt.Print("# Gathering data for resources\n")
+	if !arguments.Json {
+		fmt.Print("# Gathering data for resources\n")
+	}
 
 	for _, resourceId := range arguments.ResourceIds {
 		// Find Resources for delete in config
@@ -46,38 +91,249 @@ func StatusCommand(
 		color.Red("Given resources not found in config file.")
 		return nil
 	}
+
+	workers := arguments.Workers
+	if workers < 1 {
+		workers = 1
+	}
+	reports := make([]statusReport, cfgResourcesLen)
+	pool := worker_pool.New(workers, cfgResourcesLen, true)
+	for i := range cfgResources {
+		pool.Add(&StatusResourceTask{
+			cfg, &cfgResources[i], &api, &reports[i],
+		})
+	}
+	pool.Start()
+	<-pool.Wait()
+
+	if arguments.Json {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(reports)
+	}
+
 	cyan := color.New(color.FgCyan).SprintFunc()
 	for i, cfgResource := range cfgResources {
-		sourceLang, err := getSourceLanguage(cfg, &api, &cfgResource)
-		if err != nil {
-			fmt.Print(err)
-		}
-
+		report := reports[i]
 		fmt.Printf("\n%s -> %s (%d of %d)\n",
 			cfgResource.ProjectSlug,
 			cfgResource.ResourceSlug,
 			i+1,
 			cfgResourcesLen,
 		)
-		localLanguages := searchFileFilter(".", cfgResource.FileFilter)
-		overrides := cfgResource.Overrides
-		if len(overrides) > 0 {
-			for langOverride := range overrides {
-				localLanguages[langOverride] = overrides[langOverride]
-			}
+		if report.Error != "" {
+			fmt.Printf(
+				"%s\n",
+				color.RedString(
+					"Could not get information from Transifex: %s",
+					report.Error,
+				),
+			)
 		}
-		for language := range localLanguages {
+		for _, language := range report.Languages {
+			code := language.LocalCode
+			path := language.Path
+			if code == "" {
+				code = language.Code
+			}
 			source := ""
-			if sourceLang == language {
+			if language.Source {
 				source = " (source)"
 			}
-			fmt.Printf("- %s: %s %s\n", cyan(language),
-				localLanguages[language], source)
+			var details []string
+			if language.Stats != nil {
+				details = append(details, fmt.Sprintf(
+					"%d%% translated, %d%% reviewed, %d%% proofread",
+					language.Stats.TranslatedPercentage,
+					language.Stats.ReviewedPercentage,
+					language.Stats.ProofreadPercentage,
+				))
+			}
+			if language.State != "" {
+				details = append(details, language.State)
+			}
+			line := fmt.Sprintf("- %s: %s %s", cyan(code), path, source)
+			if len(details) > 0 {
+				line += " [" + strings.Join(details, ", ") + "]"
+			}
+			fmt.Println(line)
 		}
 	}
 	return nil
 }
 
+type StatusResourceTask struct {
+	cfg         *config.Config
+	cfgResource *config.Resource
+	api         *jsonapi.Connection
+	report      *statusReport
+}
+
+func (task *StatusResourceTask) Run(send func(string), abort func()) {
+	cfg := task.cfg
+	cfgResource := task.cfgResource
+	api := task.api
+	report := task.report
+
+	report.Resource = fmt.Sprintf(
+		"%s.%s", cfgResource.ProjectSlug, cfgResource.ResourceSlug,
+	)
+	report.Id = cfgResource.GetAPv3Id()
+	report.Languages = []statusLanguage{}
+
+	sourceLanguage, err := getSourceLanguage(cfg, api, cfgResource)
+	if err != nil {
+		report.Error = err.Error()
+	}
+	report.SourceLanguage = sourceLanguage
+
+	localFiles := searchFileFilter(
+		".", cfgResource.FileFilter, cfgResource.FileFilterExclude...,
+	)
+	for localLanguageCode, path := range cfgResource.Overrides {
+		localFiles[localLanguageCode] = path
+	}
+
+	var stats map[string]*jsonapi.Resource
+	if report.Error == "" {
+		stats, err = getStatusStats(api, cfgResource)
+		if err != nil {
+			report.Error = err.Error()
+		}
+	}
+
+	localToRemote := makeLocalToRemoteLanguageMappings(*cfg, *cfgResource)
+	remoteToLocal := makeRemoteToLocalLanguageMappings(localToRemote)
+	addConventionLanguageMappings(
+		*cfg, *cfgResource, getLocalLanguageCodes(localFiles),
+		localToRemote, remoteToLocal,
+	)
+	var remoteLanguageCodes []string
+	for languageId := range stats {
+		remoteLanguageCodes = append(
+			remoteLanguageCodes, strings.TrimPrefix(languageId, "l:"),
+		)
+	}
+	sort.Strings(remoteLanguageCodes)
+	addConventionRemoteLanguageMappings(
+		*cfg, *cfgResource, remoteLanguageCodes, localToRemote, remoteToLocal,
+	)
+
+	seen := make(map[string]bool)
+	for localLanguageCode, path := range localFiles {
+		remoteLanguageCode, exists := localToRemote[localLanguageCode]
+		if !exists {
+			remoteLanguageCode = localLanguageCode
+		}
+		seen[remoteLanguageCode] = true
+		language := statusLanguage{
+			Code:      remoteLanguageCode,
+			LocalCode: localLanguageCode,
+			Path:      path,
+			Source:    localLanguageCode == sourceLanguage,
+		}
+		if stats != nil {
+			stat, exists := stats[fmt.Sprintf("l:%s", remoteLanguageCode)]
+			if exists {
+				language.Stats = makeStatusStats(stat)
+				language.State = getStatusState(path, language.Stats)
+			} else if !language.Source {
+				language.State = "not in project"
+			}
+		}
+		report.Languages = append(report.Languages, language)
+	}
+	for _, remoteLanguageCode := range remoteLanguageCodes {
+		if seen[remoteLanguageCode] || remoteLanguageCode == sourceLanguage {
+			continue
+		}
+		localLanguageCode, exists := remoteToLocal[remoteLanguageCode]
+		if !exists {
+			localLanguageCode = remoteLanguageCode
+		}
+		report.Languages = append(report.Languages, statusLanguage{
+			Code:      remoteLanguageCode,
+			LocalCode: localLanguageCode,
+			Stats:     makeStatusStats(stats["l:"+remoteLanguageCode]),
+			State:     "missing locally",
+		})
+	}
+	sort.Slice(report.Languages, func(i, j int) bool {
+		return report.Languages[i].Code < report.Languages[j].Code
+	})
+}
+
+func getStatusStats(
+	api *jsonapi.Connection, cfgResource *config.Resource,
+) (map[string]*jsonapi.Resource, error) {
+	var stats map[string]*jsonapi.Resource
+	err := handleRetry(
+		func() error {
+			resource, err := txapi.GetResourceById(api, cfgResource.GetAPv3Id())
+			if err != nil {
+				return err
+			}
+			if resource == nil {
+				return errors.New("resource does not exist on Transifex")
+			}
+			if _, exists := resource.Relationships["project"]; !exists {
+				return errors.New(
+					"resource does not have a 'project' relationship",
+				)
+			}
+			stats, err = txapi.GetResourceStats(api, resource, nil)
+			return err
+		},
+		"",
+		func(string) {},
+	)
+	return stats, err
+}
+
+func makeStatusStats(stat *jsonapi.Resource) *statusStats {
+	var attributes txapi.ResourceLanguageStatsAttributes
+	err := stat.MapAttributes(&attributes)
+	if err != nil {
+		return nil
+	}
+	percentage := func(count int) int {
+		if attributes.TotalStrings == 0 {
+			return 0
+		}
+		return count * 100 / attributes.TotalStrings
+	}
+	return &statusStats{
+		TotalStrings:         attributes.TotalStrings,
+		TranslatedStrings:    attributes.TranslatedStrings,
+		ReviewedStrings:      attributes.ReviewedStrings,
+		ProofreadStrings:     attributes.ProofreadStrings,
+		TranslatedPercentage: percentage(attributes.TranslatedStrings),
+		ReviewedPercentage:   percentage(attributes.ReviewedStrings),
+		ProofreadPercentage:  percentage(attributes.ProofreadStrings),
+		LastUpdate:           attributes.LastUpdate,
+	}
+}
+
+// Compare the modification time of the local file with the time the language
+// was last updated on Transifex
+func getStatusState(path string, stats *statusStats) string {
+	if stats == nil || stats.LastUpdate == "" {
+		return ""
+	}
+	remoteTime, err := time.Parse(time.RFC3339, stats.LastUpdate)
+	if err != nil {
+		return ""
+	}
+	fileInfo, err := os.Stat(path)
+	if err != nil {
+		return "missing locally"
+	}
+	if remoteTime.After(fileInfo.ModTime()) {
+		return "behind remote"
+	}
+	return "up to date"
+}
+
 func getSourceLanguage(
 	cfg *config.Config,
 	api *jsonapi.Connection,
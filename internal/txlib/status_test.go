@@ -1,6 +1,7 @@
 package txlib
 
 import (
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"log"
@@ -359,3 +360,100 @@ func getMockedDataForResourceStatus() jsonapi.MockData {
 		languagesUrlElStatusCommand: statusGetLanguagesEndpointEl(),
 	}
 }
+
+func TestStatusWithRemoteStats(t *testing.T) {
+	afterTest := beforeTest(t, []string{"el", "fr"}, nil)
+	defer afterTest()
+
+	cfg := getStandardConfig()
+	cfg.Local.Resources[0].SourceLanguage = "en"
+
+	api := jsonapi.GetTestConnection(getMockedDataForStatusStats())
+
+	rescueStdout := os.Stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+
+	err := StatusCommand(cfg, api, &StatusCommandArguments{Workers: 1})
+
+	w.Close()
+	out, _ := ioutil.ReadAll(r)
+	os.Stdout = rescueStdout
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result := string(out)
+	assert.True(t, strings.Contains(result,
+		"aaa-el.json  [50% translated, 20% reviewed, 10% proofread, up to date]"))
+	assert.True(t, strings.Contains(result,
+		"aaa-fr.json  [not in project]"))
+	assert.True(t, strings.Contains(result,
+		"[100% translated, 0% reviewed, 0% proofread, missing locally]"))
+
+	// Mocked responses are only served once
+	api = jsonapi.GetTestConnection(getMockedDataForStatusStats())
+	r, w, _ = os.Pipe()
+	os.Stdout = w
+
+	err = StatusCommand(
+		cfg, api, &StatusCommandArguments{Workers: 1, Json: true},
+	)
+
+	w.Close()
+	out, _ = ioutil.ReadAll(r)
+	os.Stdout = rescueStdout
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var reports []statusReport
+	err = json.Unmarshal(out, &reports)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, len(reports), 1)
+	assert.Equal(t, reports[0].Resource, "projslug.resslug")
+	assert.Equal(t, reports[0].SourceLanguage, "en")
+	var states []string
+	for _, language := range reports[0].Languages {
+		states = append(states, language.Code+": "+language.State)
+	}
+	assert.Equal(
+		t,
+		strings.Join(states, ", "),
+		"de: missing locally, el: up to date, fr: not in project",
+	)
+	assert.Equal(t, reports[0].Languages[1].Stats.TranslatedPercentage, 50)
+}
+
+func getMockedDataForStatusStats() jsonapi.MockData {
+	return jsonapi.MockData{
+		resourceUrl: getResourceEndpoint(),
+		statsUrlAllLanguages: jsonapi.GetMockTextResponse(fmt.Sprintf(
+			`{"data": [{"type": "resource_language_stats",
+			            "id": "%[1]s:l:en",
+			            "attributes": {"total_strings": 10,
+			                           "translated_strings": 10},
+			            "relationships": {"language": {"data": {
+			              "type": "languages", "id": "l:en"}}}},
+			           {"type": "resource_language_stats",
+			            "id": "%[1]s:l:el",
+			            "attributes": {"total_strings": 10,
+			                           "translated_strings": 5,
+			                           "reviewed_strings": 2,
+			                           "proofread_strings": 1,
+			                           "last_update": "2000-01-01T00:00:00Z"},
+			            "relationships": {"language": {"data": {
+			              "type": "languages", "id": "l:el"}}}},
+			           {"type": "resource_language_stats",
+			            "id": "%[1]s:l:de",
+			            "attributes": {"total_strings": 10,
+			                           "translated_strings": 10,
+			                           "last_update": "2100-01-01T00:00:00Z"},
+			            "relationships": {"language": {"data": {
+			              "type": "languages", "id": "l:de"}}}}]}`,
+			resourceId,
+		)),
+	}
+}
@@ -1,6 +1,7 @@
 package txlib
 
 import (
+	"crypto/sha1"
 	"errors"
 	"fmt"
 	"os"
@@ -9,19 +10,34 @@ import (
 	"time"
 
 	"github.com/gosimple/slug"
+	"github.com/manifoldco/promptui"
 	"github.com/mattn/go-isatty"
 	"github.com/transifex/cli/internal/txlib/config"
 	"github.com/transifex/cli/pkg/jsonapi"
+	"github.com/transifex/cli/pkg/txapi"
 	"golang.org/x/term"
 )
 
-func figureOutBranch(branch string) string {
+/*
+Resolve the '--branch' option: "-1" means no branch and an empty string means
+the current branch, which must then be found, because silently falling back to
+the main resource would push to or pull from the wrong place.
+*/
+func figureOutBranch(branch string) (string, error) {
 	if branch == "-1" {
-		return ""
+		return "", nil
 	} else if branch == "" {
-		return getGitBranch()
+		result := getCurrentBranch()
+		if result == "" {
+			return "", errors.New(
+				"could not determine the current branch; pass it with " +
+					"'--branch <name>' or set the TX_BRANCH environment " +
+					"variable",
+			)
+		}
+		return result, nil
 	} else {
-		return branch
+		return branch, nil
 	}
 }
 
@@ -38,6 +54,7 @@ func figureOutResources(
 			resourceId := fmt.Sprintf("%s.%s", resource.ProjectSlug, resource.ResourceSlug)
 			existingResourceIds[resourceId] = resource
 		}
+		added := make(map[string]bool)
 
 		for _, resourceId := range resourceIds {
 			pattern, err := regexp.Compile(
@@ -49,7 +66,11 @@ func figureOutResources(
 			atLeastOne := false
 			for existingResourceId := range existingResourceIds {
 				if pattern.MatchString(existingResourceId) {
-					result = append(result, existingResourceIds[existingResourceId])
+					// Patterns may overlap; don't return a resource twice
+					if !added[existingResourceId] {
+						added[existingResourceId] = true
+						result = append(result, existingResourceIds[existingResourceId])
+					}
 					atLeastOne = true
 				}
 			}
@@ -69,50 +90,156 @@ func figureOutResources(
 	return result, nil
 }
 
-func applyBranchToResources(cfgResources []*config.Resource, branch string) {
+func applyBranchToResources(
+	api *jsonapi.Connection,
+	cfg *config.Config,
+	cfgResources []*config.Resource,
+	branch string,
+	legacyBranch string,
+) {
 	for i := range cfgResources {
 		cfgResource := cfgResources[i]
-		if branch != "" {
-			cfgResource.ResourceSlug = getBranchResourceSlug(cfgResource, branch)
-		}
+		mainSlug := cfgResource.ResourceSlug
+		cfgResource.ResourceSlug = getBranchResourceSlug(
+			cfg.Local.BranchNaming, mainSlug, branch,
+		)
+		useLegacyBranchResource(api, cfg, cfgResource, mainSlug, legacyBranch)
 	}
 }
 
-func getBaseResourceSlug(cfgResource *config.Resource, branch string, base string) string {
-	if branch != "" {
-		branchPrefix := fmt.Sprintf(
-			"%s--",
-			slug.Make(branch),
-		)
-		mainResourceSlug := cfgResource.ResourceSlug[len(branchPrefix):]
-		baseBranch := base
-		if base == "-1" {
-			baseBranch = ""
-		}
-		if baseBranch == "" {
-			return mainResourceSlug
-		} else {
-			return fmt.Sprintf(
-				"%s--%s",
-				slug.Make(baseBranch),
-				mainResourceSlug,
-			)
-		}
-	} else {
-		return cfgResource.ResourceSlug
+/*
+Older versions only used the part of git branches after the last '/', eg 'x'
+for 'feature/x'. Return that name if 'branch' was detected rather than passed
+with '--branch' ('requested' is empty) and has slashes, otherwise an empty
+string.
+*/
+func getLegacyBranch(requested, branch string) string {
+	if requested != "" || !strings.Contains(branch, "/") {
+		return ""
 	}
+	return branch[strings.LastIndex(branch, "/")+1:]
 }
 
-func getBranchResourceSlug(cfgResource *config.Resource, branch string) string {
-	if branch != "" {
-		return fmt.Sprintf(
-			"%s--%s",
-			slug.Make(branch),
-			cfgResource.ResourceSlug,
-		)
-	} else {
-		return cfgResource.ResourceSlug
+/*
+Older versions named branch resources after 'legacyBranch', eg 'x--res' instead
+of 'feature-x--res' for 'feature/x'. If the resource named after the whole
+branch doesn't exist but one that was created for 'legacyBranch' does, keep
+using the old one so that its translations aren't left behind.
+*/
+func useLegacyBranchResource(
+	api *jsonapi.Connection,
+	cfg *config.Config,
+	cfgResource *config.Resource,
+	mainSlug string,
+	legacyBranch string,
+) {
+	if api == nil || legacyBranch == "" {
+		return
+	}
+	legacySlug := getBranchResourceSlug(
+		cfg.Local.BranchNaming, mainSlug, legacyBranch,
+	)
+	if legacySlug == cfgResource.ResourceSlug {
+		return
+	}
+	resource, err := txapi.GetResourceById(api, cfgResource.GetAPv3Id())
+	if err != nil || resource != nil {
+		return
+	}
+	newSlug := cfgResource.ResourceSlug
+	cfgResource.ResourceSlug = legacySlug
+	resource, err = txapi.GetResourceById(api, cfgResource.GetAPv3Id())
+	if err != nil || resource == nil {
+		cfgResource.ResourceSlug = newSlug
+		return
+	}
+	var resourceAttributes txapi.ResourceAttributes
+	err = resource.MapAttributes(&resourceAttributes)
+	createdFor, ok := parseBranchResourceName(resourceAttributes.Name)
+	if err != nil || !ok || createdFor != legacyBranch {
+		// Another branch's resource
+		cfgResource.ResourceSlug = newSlug
+		return
 	}
+	fmt.Fprintf(
+		os.Stderr,
+		"Using '%s', created by an older version, instead of '%s'\n",
+		legacySlug, newSlug,
+	)
+}
+
+/*
+The slug of the resource that a branch resource is based on: the main resource
+if 'base' is empty or "-1", otherwise the resource of the 'base' branch.
+*/
+func getBaseResourceSlug(
+	naming config.BranchNaming, mainResourceSlug string, base string,
+) string {
+	if base == "-1" {
+		base = ""
+	}
+	return getBranchResourceSlug(naming, mainResourceSlug, base)
+}
+
+// The slug of 'resourceSlug' on 'branch', according to the 'branch_template'
+// settings of the config
+func getBranchResourceSlug(
+	naming config.BranchNaming, resourceSlug string, branch string,
+) string {
+	if branch == "" {
+		return resourceSlug
+	}
+	template := naming.Template
+	if template == "" {
+		template = config.DefaultBranchTemplate
+	}
+	branchSlug := slug.Make(branch)
+	if naming.MaxLength > 0 && len(branchSlug) > naming.MaxLength {
+		branchSlug = strings.TrimRight(branchSlug[:naming.MaxLength], "-")
+	}
+	hashLength := naming.HashLength
+	if hashLength <= 0 {
+		hashLength = 8
+	}
+	branchHash := fmt.Sprintf("%x", sha1.Sum([]byte(branch)))[:hashLength]
+	return strings.NewReplacer(
+		"<branch>", branchSlug,
+		"<branch_hash>", branchHash,
+		"<resource_slug>", resourceSlug,
+	).Replace(template)
+}
+
+// Match the slugs that getBranchResourceSlug makes for 'resourceSlug'
+func getBranchResourceSlugPattern(
+	naming config.BranchNaming, resourceSlug string,
+) *regexp.Regexp {
+	template := naming.Template
+	if template == "" {
+		template = config.DefaultBranchTemplate
+	}
+	return regexp.MustCompile("^" + strings.NewReplacer(
+		"<branch>", ".+",
+		"<branch_hash>", "[0-9a-f]+",
+		"<resource_slug>", regexp.QuoteMeta(resourceSlug),
+	).Replace(regexp.QuoteMeta(template)) + "$")
+}
+
+// The name given to a branch resource when it's created; it records the git
+// branch, which the slug may not
+func getBranchResourceName(name string, branch string) string {
+	return fmt.Sprintf("%s (branch %s)", name, branch)
+}
+
+// The git branch a branch resource was created for, if its name says
+func parseBranchResourceName(name string) (string, bool) {
+	if !strings.HasSuffix(name, ")") {
+		return "", false
+	}
+	idx := strings.LastIndex(name, " (branch ")
+	if idx == -1 {
+		return "", false
+	}
+	return name[idx+len(" (branch ") : len(name)-1], true
 }
 
 func stringSliceContains(haystack []string, needle string) bool {
@@ -191,6 +318,26 @@ func handleRetry(do func() error, initialMsg string, send func(string)) error {
 	}
 }
 
+/*
+Ask a yes/no question, failing if there is no terminal to answer it on, so that
+commands that ask don't wait forever in CI; they take '--yes' instead. Replaced
+in tests.
+*/
+var confirm = func(label string) (bool, error) {
+	if !isatty.IsTerminal(os.Stdin.Fd()) {
+		return false, errors.New(
+			"not running interactively; use '--yes' to proceed without " +
+				"confirmation",
+		)
+	}
+	prompt := promptui.Prompt{
+		Label:     label,
+		IsConfirm: true,
+	}
+	_, err := prompt.Run()
+	return err == nil, nil
+}
+
 func checkFileFilter(fileFilter string) error {
 	if fileFilter == "" {
 		return errors.New("file filter is empty")
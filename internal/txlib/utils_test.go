@@ -1,13 +1,18 @@
 package txlib
 
 import (
+	"fmt"
+	"os"
+	"os/exec"
 	"reflect"
 	"sort"
+	"strings"
 	"testing"
 
 	"github.com/transifex/cli/pkg/assert"
 
 	"github.com/transifex/cli/internal/txlib/config"
+	"github.com/transifex/cli/pkg/jsonapi"
 )
 
 func TestFigureOutResources(t *testing.T) {
@@ -99,3 +104,244 @@ func TestTruncateMessage(t *testing.T) {
 		result,
 	)
 }
+
+// Unset the variables that branch detection looks at, returning a function
+// that restores them
+func clearBranchVariables() func() {
+	saved := make(map[string]string)
+	for _, variable := range append([]string{"TX_BRANCH"}, ciBranchVariables...) {
+		if value, exists := os.LookupEnv(variable); exists {
+			saved[variable] = value
+			os.Unsetenv(variable)
+		}
+	}
+	return func() {
+		for _, variable := range append([]string{"TX_BRANCH"}, ciBranchVariables...) {
+			os.Unsetenv(variable)
+		}
+		for variable, value := range saved {
+			os.Setenv(variable, value)
+		}
+	}
+}
+
+func TestGetBranchResourceSlug(t *testing.T) {
+	naming := config.BranchNaming{}
+	assert.Equal(t, getBranchResourceSlug(naming, "res", ""), "res")
+	assert.Equal(
+		t, getBranchResourceSlug(naming, "res", "feature/login"),
+		"feature-login--res",
+	)
+
+	naming = config.BranchNaming{
+		Template:   "<branch>-<branch_hash>--<resource_slug>",
+		MaxLength:  8,
+		HashLength: 4,
+	}
+	first := getBranchResourceSlug(naming, "res", "feature/a_b")
+	second := getBranchResourceSlug(naming, "res", "feature-a-b")
+	assert.True(t, first != second)
+	assert.True(t, strings.HasPrefix(first, "feature-"))
+	assert.Equal(t, len(first), len("feature-")+4+len("--res"))
+	assert.True(t, !strings.Contains(first, "--res--"))
+
+	pattern := getBranchResourceSlugPattern(naming, "res")
+	assert.True(t, pattern.MatchString(first))
+	assert.True(t, pattern.MatchString(second))
+	assert.True(t, !pattern.MatchString("res"))
+	assert.True(t, !pattern.MatchString("feature--other"))
+
+	branch, ok := parseBranchResourceName("aaa.json (branch feature/a_b)")
+	assert.True(t, ok)
+	assert.Equal(t, branch, "feature/a_b")
+	_, ok = parseBranchResourceName("aaa.json")
+	assert.True(t, !ok)
+}
+
+func TestApplyBranchToResourcesLegacySlug(t *testing.T) {
+	cfg := config.Config{Local: &config.LocalConfig{}}
+	resourceUrl := func(slug string) string {
+		return "/resources/o:orgslug:p:projslug:r:" + slug
+	}
+	legacyResourceEndpoint := func(name string) *jsonapi.MockEndpoint {
+		return jsonapi.GetMockTextResponse(fmt.Sprintf(
+			`{"data": {"type": "resources",
+			           "id": "o:orgslug:p:projslug:r:x--resslug",
+			           "attributes": {"slug": "x--resslug", "name": "%s"}}}`,
+			name,
+		))
+	}
+	apply := func(mockData jsonapi.MockData, requested string) string {
+		api := jsonapi.GetTestConnection(mockData)
+		cfgResources := []*config.Resource{{
+			OrganizationSlug: "orgslug",
+			ProjectSlug:      "projslug",
+			ResourceSlug:     "resslug",
+		}}
+		applyBranchToResources(
+			&api, &cfg, cfgResources, "feature/x",
+			getLegacyBranch(requested, "feature/x"),
+		)
+		return cfgResources[0].ResourceSlug
+	}
+
+	// Only a resource created for the old name exists
+	slug := apply(jsonapi.MockData{
+		resourceUrl("feature-x--resslug"): getEmptyEndpoint(),
+		resourceUrl("x--resslug"): legacyResourceEndpoint(
+			"aaa.json (branch x)",
+		),
+	}, "")
+	assert.Equal(t, slug, "x--resslug")
+
+	// The resource with the old name belongs to branch 'x'
+	slug = apply(jsonapi.MockData{
+		resourceUrl("feature-x--resslug"): getEmptyEndpoint(),
+		resourceUrl("x--resslug"): legacyResourceEndpoint(
+			"aaa.json (branch other/x)",
+		),
+	}, "")
+	assert.Equal(t, slug, "feature-x--resslug")
+
+	// Neither exists, the new name is used
+	slug = apply(jsonapi.MockData{
+		resourceUrl("feature-x--resslug"): getEmptyEndpoint(),
+		resourceUrl("x--resslug"):         getEmptyEndpoint(),
+	}, "")
+	assert.Equal(t, slug, "feature-x--resslug")
+
+	// Branches passed with '--branch' were never cut, nothing is looked up
+	slug = apply(jsonapi.MockData{
+		resourceUrl("feature-x--resslug"): getEmptyEndpoint(),
+		resourceUrl("x--resslug"): legacyResourceEndpoint(
+			"aaa.json (branch x)",
+		),
+	}, "feature/x")
+	assert.Equal(t, slug, "feature-x--resslug")
+	assert.Equal(t, getLegacyBranch("", "x"), "")
+}
+
+func TestFigureOutBranch(t *testing.T) {
+	afterTest := beforeTest(t, nil, nil)
+	defer afterTest()
+	restore := clearBranchVariables()
+	defer restore()
+
+	branch, err := figureOutBranch("-1")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, branch, "")
+
+	branch, err = figureOutBranch("foo")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, branch, "foo")
+
+	// Not a git repository and no variables
+	_, err = figureOutBranch("")
+	if err == nil {
+		t.Error("Expected an error when the branch can't be determined")
+	}
+
+	// Tags are not branches
+	os.Setenv("GITHUB_REF", "refs/tags/v1.0")
+	_, err = figureOutBranch("")
+	if err == nil {
+		t.Error("Expected a tag to be ignored")
+	}
+
+	os.Setenv("BUILD_SOURCEBRANCH", "refs/heads/feature/login")
+	branch, err = figureOutBranch("")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, branch, "feature/login")
+
+	os.Setenv("GITHUB_HEAD_REF", "pull-request-branch")
+	branch, _ = figureOutBranch("")
+	assert.Equal(t, branch, "pull-request-branch")
+
+	os.Setenv("TX_BRANCH", "override")
+	branch, _ = figureOutBranch("")
+	assert.Equal(t, branch, "override")
+}
+
+func TestGetGitBranchDetachedHead(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	afterTest := beforeTest(t, nil, nil)
+	defer afterTest()
+
+	for _, gitArgs := range [][]string{
+		{"init", "-q"},
+		{"checkout", "-q", "-b", "feature/login"},
+		{"add", "."},
+		{"-c", "user.name=tx", "-c", "user.email=tx@example.com",
+			"commit", "-q", "-m", "initial"},
+	} {
+		err := exec.Command("git", gitArgs...).Run()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	assert.Equal(t, getGitBranch(), "feature/login")
+
+	err := exec.Command("git", "checkout", "-q", "--detach").Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, getGitBranch(), "")
+}
+
+// Make a git repository where 'feature-b' was branched off 'feature-a', which
+// was branched off 'main', and 'feature-c' off 'feature-b'; 'feature-b' is
+// checked out
+func initStackedGitBranches(t *testing.T) {
+	commit := []string{
+		"-c", "user.name=tx", "-c", "user.email=tx@example.com",
+		"commit", "-q", "--allow-empty", "-m",
+	}
+	for _, gitArgs := range [][]string{
+		{"init", "-q"},
+		{"checkout", "-q", "-b", "main"},
+		{"add", "."},
+		append(commit, "initial"),
+		{"checkout", "-q", "-b", "feature-a"},
+		append(commit, "a"),
+		{"checkout", "-q", "-b", "feature-b"},
+		append(commit, "b"),
+		{"checkout", "-q", "-b", "feature-c"},
+		append(commit, "c"),
+		{"checkout", "-q", "feature-b"},
+	} {
+		err := exec.Command("git", gitArgs...).Run()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestGetGitAncestorBranches(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	afterTest := beforeTest(t, nil, nil)
+	defer afterTest()
+	initStackedGitBranches(t)
+
+	branches, err := getGitAncestorBranches("feature-b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, strings.Join(branches, ","), "feature-a,main")
+
+	// A branch at the same commit is the nearest
+	err = exec.Command("git", "branch", "feature-b-copy").Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	branches, err = getGitAncestorBranches("feature-b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(
+		t, strings.Join(branches, ","), "feature-b-copy,feature-a,main",
+	)
+}
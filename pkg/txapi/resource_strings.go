@@ -0,0 +1,53 @@
+package txapi
+
+import (
+	"github.com/transifex/cli/pkg/jsonapi"
+)
+
+type ResourceStringAttributes struct {
+	Key        string `json:"key"`
+	Context    string `json:"context"`
+	Pluralized bool   `json:"pluralized"`
+	// Plural form ("one", "other", etc) -> text; non-pluralized strings only
+	// have "other"
+	Strings    map[string]string `json:"strings"`
+	StringHash string            `json:"string_hash"`
+}
+
+/* Get all the source strings of a resource */
+func GetResourceStrings(
+	api *jsonapi.Connection, resource *jsonapi.Resource,
+) ([]*jsonapi.Resource, error) {
+	query := jsonapi.Query{
+		Filters: map[string]string{"resource": resource.Id},
+	}
+	page, err := api.List("resource_strings", query.Encode())
+	if err != nil {
+		return nil, err
+	}
+	var result []*jsonapi.Resource
+	for {
+		for i := range page.Data {
+			result = append(result, &page.Data[i])
+		}
+		if page.Next == "" {
+			break
+		}
+		page, err = page.GetNext()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+/* Replace the text of a source string */
+func UpdateResourceString(
+	resourceString *jsonapi.Resource, strings map[string]string,
+) error {
+	if resourceString.Attributes == nil {
+		resourceString.Attributes = make(map[string]interface{})
+	}
+	resourceString.Attributes["strings"] = strings
+	return resourceString.Save([]string{"strings"})
+}
@@ -5,8 +5,6 @@ import (
 	"fmt"
 	"io"
 	"net/http"
-	"os"
-	"path/filepath"
 	"time"
 
 	"github.com/transifex/cli/pkg/jsonapi"
@@ -51,22 +49,12 @@ func PollResourceStringsDownload(download *jsonapi.Resource, filePath string) er
 				return errors.New("file download error")
 			}
 			bodyBytes, err := io.ReadAll(resp.Body)
+			resp.Body.Close()
 			if err != nil {
 				return err
 			}
 
-			dir := filepath.Dir(filePath)
-			err = os.MkdirAll(dir, os.ModePerm)
-			if err != nil {
-				return err
-			}
-
-			err = os.WriteFile(filePath, bodyBytes, 0644)
-			if err != nil {
-				return err
-			}
-			resp.Body.Close()
-			return nil
+			return WriteFileAtomically(filePath, bodyBytes)
 		} else if download.Attributes["status"] == "failed" {
 			return fmt.Errorf(
 				"failed to download translation '%s'",
@@ -5,8 +5,6 @@ import (
 	"fmt"
 	"io"
 	"net/http"
-	"os"
-	"path/filepath"
 	"time"
 
 	"github.com/transifex/cli/pkg/jsonapi"
@@ -68,14 +66,5 @@ func PollTranslationDownload(download *jsonapi.Resource, filePath string) error
 	if err != nil {
 		return err
 	}
-	languageDirectory := filepath.Dir(filePath)
-	err = os.MkdirAll(languageDirectory, os.ModePerm)
-	if err != nil {
-		return err
-	}
-	err = os.WriteFile(filePath, bodyBytes, 0644)
-	if err != nil {
-		return err
-	}
-	return nil
+	return WriteFileAtomically(filePath, bodyBytes)
 }
@@ -14,17 +14,14 @@ func UploadTranslation(
 	resource,
 	language *jsonapi.Resource,
 	file io.Reader,
-	xliff bool,
+	fileType string,
 ) (*jsonapi.Resource, error) {
 	data, err := io.ReadAll(file)
 	if err != nil {
 		return nil, err
 	}
 
-	var fileType string
-	if xliff {
-		fileType = "xliff"
-	} else {
+	if fileType == "" {
 		fileType = "default"
 	}
 
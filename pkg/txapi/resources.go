@@ -2,6 +2,8 @@ package txapi
 
 import (
 	"errors"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/transifex/cli/pkg/jsonapi"
@@ -180,18 +182,69 @@ func GetResourceById(api *jsonapi.Connection, id string) (*jsonapi.Resource, err
 	return &resource, nil
 }
 
+type ResourceMergeAttributes struct {
+	Status             string `json:"status"`
+	ConflictResolution string `json:"conflict_resolution"`
+	Errors             []struct {
+		Code   string `json:"code"`
+		Detail string `json:"detail"`
+	} `json:"errors"`
+}
+
+func (err *ResourceMergeAttributes) Error() string {
+	parts := make([]string, 0, len(err.Errors))
+	for _, item := range err.Errors {
+		parts = append(parts,
+			fmt.Sprintf("%s: %s", item.Code, item.Detail))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Whether the merge failed because the branch and its base have conflicting
+// changes, as opposed to some other error
+func (err *ResourceMergeAttributes) IsConflict() bool {
+	for _, item := range err.Errors {
+		text := strings.ToLower(item.Code + " " + item.Detail)
+		if strings.Contains(text, "conflict") ||
+			strings.Contains(text, "diverge") {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+Wait for a merge to complete, checking its status every 'duration'. Returns an
+error wrapping the merge's attributes if it fails, or if it hasn't completed
+after 'timeout' (0 means wait forever).
+*/
 func PollResourceMerge(
 	merge *jsonapi.Resource,
 	duration time.Duration,
+	timeout time.Duration,
 ) error {
+	start := time.Now()
 	for {
 		err := merge.Reload()
 		if err != nil {
 			return err
 		}
 
-		if merge.Attributes["status"] == "COMPLETED" {
+		var mergeAttributes ResourceMergeAttributes
+		err = merge.MapAttributes(&mergeAttributes)
+		if err != nil {
+			return err
+		}
+
+		if strings.EqualFold(mergeAttributes.Status, "completed") {
 			return nil
+		} else if strings.EqualFold(mergeAttributes.Status, "failed") {
+			return fmt.Errorf("merge '%s' failed - %w", merge.Id, &mergeAttributes)
+		}
+		if timeout > 0 && time.Since(start) >= timeout {
+			return fmt.Errorf(
+				"merge '%s' did not complete within %s", merge.Id, timeout,
+			)
 		}
 		time.Sleep(duration)
 	}
@@ -1,7 +1,10 @@
 package txapi
 
 import (
+	"errors"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/transifex/cli/pkg/jsonapi"
 )
@@ -392,3 +395,60 @@ func TestDeleteResource(t *testing.T) {
 		t.Errorf("Got error while deleting resource: %s", err)
 	}
 }
+
+func getMergeResponse(status, errors string) string {
+	return `{"data": {"type": "resource_async_merges",
+	                  "id": "some_uuid",
+	                  "attributes": {"status": "` + status + `",
+	                                 "errors": ` + errors + `}}}`
+}
+
+func TestPollResourceMergeFailed(t *testing.T) {
+	api := jsonapi.GetTestConnection(jsonapi.MockData{
+		"/resource_async_merges/some_uuid": &jsonapi.MockEndpoint{
+			Requests: []jsonapi.MockRequest{
+				{Response: jsonapi.MockResponse{
+					Text: getMergeResponse("PROCESSING", "[]"),
+				}},
+				{Response: jsonapi.MockResponse{
+					Text: getMergeResponse(
+						"FAILED",
+						`[{"code": "merge_conflict",
+						   "detail": "Source strings have diverged"}]`,
+					),
+				}},
+			},
+		},
+	})
+	merge := &jsonapi.Resource{
+		API: &api, Type: "resource_async_merges", Id: "some_uuid",
+	}
+
+	err := PollResourceMerge(merge, time.Millisecond, 0)
+	if err == nil {
+		t.Fatal("Expected an error for a failed merge")
+	}
+	var mergeAttributes *ResourceMergeAttributes
+	if !errors.As(err, &mergeAttributes) {
+		t.Fatalf("Expected the merge's attributes in the error, got %s", err)
+	}
+	if !mergeAttributes.IsConflict() {
+		t.Errorf("Expected %s to be a conflict", mergeAttributes)
+	}
+}
+
+func TestPollResourceMergeTimeout(t *testing.T) {
+	api := jsonapi.GetTestConnection(jsonapi.MockData{
+		"/resource_async_merges/some_uuid": jsonapi.GetMockTextResponse(
+			getMergeResponse("PENDING", "[]"),
+		),
+	})
+	merge := &jsonapi.Resource{
+		API: &api, Type: "resource_async_merges", Id: "some_uuid",
+	}
+
+	err := PollResourceMerge(merge, time.Millisecond, time.Nanosecond)
+	if err == nil || !strings.Contains(err.Error(), "did not complete") {
+		t.Errorf("Expected a timeout error, got %v", err)
+	}
+}
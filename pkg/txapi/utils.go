@@ -1,5 +1,10 @@
 package txapi
 
+import (
+	"os"
+	"path/filepath"
+)
+
 /*
 Return a function that returns the next item from 'pool' every time. When 'pool' runs
 out, keep returning the last item forever.
@@ -31,3 +36,42 @@ func getBackoff(pool []int) func() int {
 		}
 	}
 }
+
+/*
+Write 'data' to 'filePath' so that readers will either see the old or the new
+contents of the file, never a partially written one. The data is first written
+to a temporary file in the same directory, which is then renamed into place.
+Missing parent directories are created.
+*/
+func WriteFileAtomically(filePath string, data []byte) error {
+	dir := filepath.Dir(filePath)
+	err := os.MkdirAll(dir, os.ModePerm)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.CreateTemp(dir, "."+filepath.Base(filePath)+".tx-*")
+	if err != nil {
+		return err
+	}
+	tempPath := file.Name()
+	_, err = file.Write(data)
+	if err == nil {
+		err = file.Sync()
+	}
+	closeErr := file.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tempPath, 0644)
+	}
+	if err == nil {
+		err = os.Rename(tempPath, filePath)
+	}
+	if err != nil {
+		os.Remove(tempPath)
+		return err
+	}
+	return nil
+}